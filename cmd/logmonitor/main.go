@@ -1,12 +1,19 @@
 package main
 
 import (
+	"fmt"
+	"os"
+	"strings"
+
 	"github.com/Juli3nnicolas/http_log_monitor/pkg/app"
 	"github.com/spf13/cobra"
 )
 
 var conf *app.Config = &app.Config{}
 
+// controlSocket is the socket of the monitor controlled by the control command
+var controlSocket string
+
 func main() {
 
 	var rootCmd = &cobra.Command{Use: "logmonitor",
@@ -23,8 +30,62 @@ present to be notified when traffic gets awry.`,
 	}
 
 	rootCmd.Flags().StringVarP(&conf.LogFilePath, "path", "p", app.DefaultLogFilePath, "path to the log file to monitor traffic from")
+	rootCmd.Flags().StringVarP(&conf.LogFormat, "log-format", "f", app.DefaultLogFormat, "log file's format - auto (detected from the file's first lines), common, combined, w3c (iis), cloudfront, alb (elb), json[:field=key,...], caddy, traefik or a custom nginx log_format / Apache LogFormat string such as '%h %l %u %t \"%r\" %>s %b'")
+	rootCmd.Flags().StringArrayVar(&conf.DetectFormats, "detect-format", nil, "custom nginx log_format / Apache LogFormat string considered by --log-format=auto (can be repeated)")
+	rootCmd.Flags().StringArrayVar(&conf.TrustedProxies, "trusted-proxy", nil, "CIDR or IP address of a proxy whose X-Forwarded-For header gives the real client, such as 10.0.0.0/8 (can be repeated)")
+	rootCmd.Flags().IntVar(&conf.SectionDepth, "section-depth", app.DefaultSectionDepth, "number of directories making a section in the most hits, latency and bandwidth panels - /api for 1, /api/v1 for 2")
+	rootCmd.Flags().StringArrayVar(&conf.RoutePatterns, "route-pattern", nil, "route template such as /api/v1/orders/:id/items, matching routes are aggregated in the top routes (can be repeated)")
 	rootCmd.Flags().DurationVarP(&conf.UpdateFrameDuration, "update", "u", app.DefaultUpdateFrameDuration, "app's refresh rate - rate at which data are going to be fetched and displayed")
+	rootCmd.Flags().BoolVar(&conf.EventTime, "event-time", false, "measure the rates on the logs' timestamps instead of the time they are read (for buffered writes or lagging files)")
+	rootCmd.Flags().DurationVar(&conf.AllowedLateness, "allowed-lateness", app.DefaultAllowedLateness, "how late a log can be read with --event-time to be counted in its frame")
 	rootCmd.Flags().DurationVarP(&conf.AlertFrameDuration, "alert-period", "T", app.DefaultAlertFrameDuration, "configure alerts' monitoring interval - if the request-rate is above it fro -T, an alert is given")
-	rootCmd.Flags().Uint64VarP(&conf.AlertThreshold, "alert-threshold", "t", app.DefaultAlertThreshold, "threshold value, if the request rate is above for -T time, an alert is switched on")
+	rootCmd.Flags().Float64VarP(&conf.AlertThreshold, "alert-threshold", "t", app.DefaultAlertThreshold, "threshold value, if the alert metric is above for -T time, an alert is switched on")
+	rootCmd.Flags().StringVarP(&conf.AlertMetric, "alert-metric", "m", app.DefaultAlertMetric, "metric monitored by the alert - req_rate, req_rate_1s, req_rate_10s, req_rate_1m, req_rate_5m, req_rate_15m (req/s), latency_p50, latency_p90, latency_p99, latency_max (ms), bandwidth (B/s), error_rate (% of 5xx), status:<code> (req), section:<section> (req/s) or parse_error_rate (%)")
+	rootCmd.Flags().StringVar(&conf.AlertComparison, "alert-op", app.DefaultAlertComparison, "how the alert metric is compared to the threshold - > (above), < (below) or change (changed by threshold % compared to the previous -T period)")
+	rootCmd.Flags().Float64Var(&conf.AlertResolveThreshold, "alert-resolve-threshold", 0, "value the alert metric must go back past for the alert to resolve (below for > and change, above for <) - defaults to the threshold")
+	rootCmd.Flags().Uint64Var(&conf.AlertPendingWindows, "alert-pending-windows", app.DefaultAlertPendingWindows, "number of consecutive -T periods the threshold must be exceeded on before the alert fires, it is pending meanwhile")
+	rootCmd.Flags().DurationVar(&conf.AlertMinFiring, "alert-min-firing", 0, "minimum time the alert fires before it can resolve")
+	rootCmd.Flags().StringArrayVar(&conf.AlertRules, "alert-rule", nil, "additional alert such as name=slow,metric=latency_p99,threshold=250,duration=5m,op=>,resolve=200,pending=2,min_firing=10m - metric, duration and op default to req_rate, -T and > (can be repeated)")
+	rootCmd.Flags().StringArrayVar(&conf.AlertExprs, "alert-expr", nil, "alert written as an expression such as 'errors: rate(status >= 500, 5m) / rate(all, 5m) > 0.05 for 2m' (can be repeated)")
+	rootCmd.Flags().StringArrayVar(&conf.Silences, "silence", nil, "alert silenced from startup for a duration, written as name=duration such as traffic=2h - its notifications aren't sent (can be repeated)")
+	rootCmd.Flags().StringVar(&conf.ControlSocket, "control-socket", "", "unix socket the alerts are silenced and acknowledged through while the monitor runs (see the control command)")
+	rootCmd.Flags().StringVar(&conf.AlertLogPath, "alert-log", "", "file the alerts' transitions are appended to (JSON lines), the alert history is loaded from it on startup - the history is only kept in memory by default")
+	rootCmd.Flags().StringVar(&conf.WebhookURL, "webhook-url", "", "URL the alerts' notifications are POSTed to whenever an alert is switched on or off")
+	rootCmd.Flags().StringVar(&conf.WebhookTemplate, "webhook-template", app.DefaultWebhookTemplate, "payload of the notifications - json, slack, mattermost or a Go template such as '{\"text\": {{json .Message}}}'")
+	rootCmd.Flags().IntVar(&conf.WebhookRetries, "webhook-retries", app.DefaultWebhookRetries, "number of retries after a failed notification (network error, 5xx or 429 status)")
+	rootCmd.Flags().DurationVar(&conf.WebhookBackoff, "webhook-backoff", app.DefaultWebhookBackoff, "delay before the first retry of a notification, it doubles on every retry")
+	rootCmd.Flags().DurationVar(&conf.WebhookTimeout, "webhook-timeout", app.DefaultWebhookTimeout, "timeout of each notification request")
+	rootCmd.Flags().StringVar(&conf.NotifyCommand, "notify-command", "", "shell command run whenever an alert is switched on or off - it reads the alert in JSON on its input and in ALERT_* environment variables")
+	rootCmd.Flags().DurationVar(&conf.NotifyCommandTimeout, "notify-command-timeout", app.DefaultNotifyCommandTimeout, "time the notification command is killed after")
+	rootCmd.Flags().StringVar(&conf.SMTPAddr, "smtp-addr", "", "SMTP server (host:port) the alerts are emailed with whenever they are switched on or off")
+	rootCmd.Flags().StringVar(&conf.SMTPFrom, "smtp-from", "", "sender of the alerts' emails")
+	rootCmd.Flags().StringArrayVar(&conf.SMTPTo, "smtp-to", nil, "recipient of the alerts' emails (can be repeated)")
+	rootCmd.Flags().StringVar(&conf.SMTPUsername, "smtp-username", "", "username authenticating to the SMTP server, the password is read from the LOGMONITOR_SMTP_PASSWORD environment variable if --smtp-password isn't set")
+	rootCmd.Flags().StringVar(&conf.SMTPPassword, "smtp-password", "", "password authenticating to the SMTP server")
+	rootCmd.Flags().DurationVar(&conf.SMTPTimeout, "smtp-timeout", app.DefaultSMTPTimeout, "timeout of each email")
+	rootCmd.Flags().IntVar(&conf.NotifyRateLimit, "notify-rate-limit", app.DefaultNotifyRateLimit, "maximum number of notifications per alert sent during --notify-rate-period, the latest state of a flapping alert is notified once the limit allows it - 0 for no limit")
+	rootCmd.Flags().DurationVar(&conf.NotifyRatePeriod, "notify-rate-period", app.DefaultNotifyRatePeriod, "period the notifications are limited over")
+	rootCmd.Flags().Float64Var(&conf.MaxParseErrorRate, "max-parse-error-rate", app.DefaultMaxParseErrorRate, "percentage of unparseable lines triggering an alert if exceeded for -T time (the log format doesn't match), 0 disables it")
+
+	var controlCmd = &cobra.Command{Use: "control <command>",
+		Short: "Silences or acknowledges an alert of a running monitor",
+		Long: `Silences or acknowledges an alert of a monitor started with --control-socket
+The command is one of :
+  silence <alert> <duration>  mute the alert's notifications, such as silence traffic 2h
+  unsilence <alert>           lift the alert's silence
+  ack <alert>                 acknowledge the firing alert until it resolves`,
+		Args: cobra.MinimumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			outcome, err := app.SendControl(controlSocket, strings.Join(args, " "))
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			fmt.Println(outcome)
+		},
+	}
+	controlCmd.Flags().StringVar(&controlSocket, "control-socket", "", "unix socket the monitor listens to")
+	rootCmd.AddCommand(controlCmd)
+
 	rootCmd.Execute()
 }
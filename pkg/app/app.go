@@ -1,16 +1,65 @@
 package app
 
 import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Juli3nnicolas/http_log_monitor/pkg/expr"
+	"github.com/Juli3nnicolas/http_log_monitor/pkg/log"
 	"github.com/Juli3nnicolas/http_log_monitor/pkg/logger"
+	"github.com/Juli3nnicolas/http_log_monitor/pkg/notify"
 	"github.com/Juli3nnicolas/http_log_monitor/pkg/reader"
+	"github.com/Juli3nnicolas/http_log_monitor/pkg/task"
 )
 
 // Run executes the entire application (both frontend and backend)
 func Run(conf *Config) error {
 	l := logger.Get()
 
-	// Init backend
-	b := Backend{}
+	parser, detector, err := newParser(conf)
+	if err != nil {
+		l.Fatalf(err.Error())
+		return err
+	}
+
+	if len(conf.TrustedProxies) > 0 {
+		trusted, err := log.ParseTrustedProxies(conf.TrustedProxies)
+		if err != nil {
+			l.Fatalf(err.Error())
+			return err
+		}
+		parser = reader.ProxyAwareParser(parser, trusted)
+	}
+
+	rules, err := alertRules(conf)
+	if err != nil {
+		l.Fatalf(err.Error())
+		return err
+	}
+
+	exprAlerts, err := expressionAlerts(conf)
+	if err != nil {
+		l.Fatalf(err.Error())
+		return err
+	}
+
+	notifiers, err := newNotifiers(conf)
+	if err != nil {
+		l.Fatalf(err.Error())
+		return err
+	}
+
+	silences, err := alertSilences(conf)
+	if err != nil {
+		l.Fatalf(err.Error())
+		return err
+	}
+
+	// Init backend, the alerts are controlled from the dashboard and the control socket through controls
+	controls := make(chan alertControl, controlQueueSize)
+	b := Backend{logFormat: conf.LogFormat, detector: detector, controls: controls}
 
 	// Add your tasks to the backend so that it can execute them
 	// HACK : the tasks passed to add are already part of the backend
@@ -25,28 +74,97 @@ func Run(conf *Config) error {
 	b.add(
 		Taskenv{
 			Task:       &b.fetchLogs,
-			InitParams: []interface{}{conf.LogFilePath, reader.CommonLogFormatParser(), conf.UpdateFrameDuration},
+			InitParams: []interface{}{conf.LogFilePath, parser, conf.UpdateFrameDuration},
+		},
+		Taskenv{
+			Task:       &b.mostHits,
+			InitParams: []interface{}{conf.SectionDepth},
+		},
+		Taskenv{
+			Task:       &b.routes,
+			InitParams: []interface{}{conf.RoutePatterns},
 		},
 		Taskenv{
-			Task: &b.mostHits,
+			Task: &b.clients,
 		},
 		Taskenv{
-			Task: &b.rates,
+			Task:       &b.rates,
+			InitParams: []interface{}{conf.EventTime, conf.AllowedLateness},
+		},
+		Taskenv{
+			Task:       &b.latency,
+			InitParams: []interface{}{conf.SectionDepth},
+		},
+		Taskenv{
+			Task:       &b.bandwidth,
+			InitParams: []interface{}{conf.SectionDepth},
 		},
 		Taskenv{
 			Task: &b.countCodes,
 		},
 		Taskenv{
-			Task:       &b.alert,
-			InitParams: []interface{}{conf.AlertFrameDuration, conf.AlertThreshold},
+			Task:       &b.alerts,
+			InitParams: []interface{}{rules, exprAlerts},
+		},
+		Taskenv{
+			Task:       &b.history,
+			InitParams: []interface{}{conf.AlertLogPath},
+		},
+		Taskenv{
+			Task:       &b.notifier,
+			InitParams: []interface{}{notifiers, notify.RateLimit{Max: conf.NotifyRateLimit, Period: conf.NotifyRatePeriod}},
 		},
 	)
 
-	err := b.init(conf)
+	err = b.init(conf)
 	if err != nil {
 		l.Fatalf(err.Error())
 		return err
 	}
+
+	for _, c := range silences {
+		if err := b.alerts.Apply(c, time.Now()); err != nil {
+			l.Fatalf(err.Error())
+			return err
+		}
+	}
+
+	if conf.ControlSocket != "" {
+		listener, err := serveControls(conf.ControlSocket, controls)
+		if err != nil {
+			l.Fatalf(err.Error())
+			return err
+		}
+		defer listener.Close()
+	}
+
+	// The log file exists once the backend is initialised, sample its
+	// content to detect its format
+	if detector != nil {
+		if err := detector.SampleFile(conf.LogFilePath); err != nil {
+			l.Warn(err)
+		}
+
+		d := detector.Result()
+		if d.NbLines == 0 {
+			l.Infof("no line to detect the log format from in %s, it will be detected from the first lines written", conf.LogFilePath)
+		} else {
+			l.Infof("detected log format %s with a %.0f%% confidence (%d sampled lines)", d.Name, d.Confidence*100, d.NbLines)
+		}
+	}
+
+	// The file is read from its end, stateful parsers must know the fields
+	// declared by the directives written so far. The whole file is scanned
+	// only because #Fields can change mid-file, hence only for such formats.
+	format := conf.LogFormat
+	if detector != nil {
+		format = detector.Result().Name
+	}
+	if reader.HasDirectives(format) {
+		if err := reader.ReadDirectives(conf.LogFilePath, parser); err != nil {
+			l.Warn(err)
+		}
+	}
 	defer func() {
 		if err := b.shutdown(); err != nil {
 			logger.Get().Fatalf(err.Error())
@@ -54,7 +172,7 @@ func Run(conf *Config) error {
 	}()
 
 	// Init view
-	r := renderer{}
+	r := renderer{controls: controls}
 	ctx, err := r.init()
 	if err != nil {
 		logger.Get().Fatalf(err.Error())
@@ -74,3 +192,149 @@ func Run(conf *Config) error {
 
 	return nil
 }
+
+// newParser returns the parser reading conf.LogFormat. When the format has
+// to be detected, the detector used as a parser is returned as well.
+func newParser(conf *Config) (reader.Parser, *reader.Detector, error) {
+	if conf.LogFormat != reader.AutoFormat {
+		parser, err := reader.FormatParser(conf.LogFormat)
+		return parser, nil, err
+	}
+
+	candidates, err := reader.KnownFormats(conf.DetectFormats...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	detector := reader.NewDetector(candidates, reader.DefaultSampleSize)
+	return detector.Parse, detector, nil
+}
+
+// alertRules lists the rules of the alerts: the one configured by the alert flags,
+// named after its metric, the parse-error alert if it is enabled and the custom rules
+func alertRules(conf *Config) ([]task.AlertRule, error) {
+	metric, err := task.ParseMetric(conf.AlertMetric)
+	if err != nil {
+		return nil, err
+	}
+
+	comparison, err := task.ParseComparison(conf.AlertComparison)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := []task.AlertRule{{
+		Name:       metric.Name,
+		Metric:     metric,
+		Duration:   conf.AlertFrameDuration,
+		Threshold:  conf.AlertThreshold,
+		Comparison: comparison,
+		Hysteresis: task.Hysteresis{
+			ResolveThreshold: conf.AlertResolveThreshold,
+			PendingWindows:   conf.AlertPendingWindows,
+			MinFiring:        conf.AlertMinFiring,
+		},
+	}}
+
+	// The parse-error alert warns that the log format doesn't match the file
+	if conf.MaxParseErrorRate > 0 {
+		rules = append(rules, task.AlertRule{
+			Name:      parseErrorAlertName,
+			Metric:    task.ParseErrorRateMetric(),
+			Duration:  conf.AlertFrameDuration,
+			Threshold: conf.MaxParseErrorRate,
+		})
+	}
+
+	for _, spec := range conf.AlertRules {
+		rule, err := task.ParseAlertRule(spec, conf.AlertFrameDuration)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+// expressionAlerts compiles the alerts written in the expression language (see package expr)
+func expressionAlerts(conf *Config) ([]task.Alerter, error) {
+	alerts := make([]task.Alerter, 0, len(conf.AlertExprs))
+	for _, spec := range conf.AlertExprs {
+		alert, err := expr.ParseAlert(spec)
+		if err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, alert)
+	}
+
+	return alerts, nil
+}
+
+// alertSilences reads the silences set from startup, written as name=duration
+func alertSilences(conf *Config) ([]task.AlertControl, error) {
+	silences := make([]task.AlertControl, 0, len(conf.Silences))
+	for _, spec := range conf.Silences {
+		kv := strings.SplitN(spec, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid silence %q - it must be written as name=duration such as traffic=2h", spec)
+		}
+
+		c, err := task.ParseAlertControl("silence " + strings.TrimSpace(kv[0]) + " " + strings.TrimSpace(kv[1]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid silence %q - %v", spec, err)
+		}
+		silences = append(silences, c)
+	}
+
+	return silences, nil
+}
+
+// newNotifiers returns where the alerts' notifications are sent
+func newNotifiers(conf *Config) ([]notify.Notifier, error) {
+	var notifiers []notify.Notifier
+
+	if conf.WebhookURL != "" {
+		hook, err := notify.NewWebhook(notify.WebhookConfig{
+			URL:      conf.WebhookURL,
+			Template: conf.WebhookTemplate,
+			Retries:  conf.WebhookRetries,
+			Backoff:  conf.WebhookBackoff,
+			Timeout:  conf.WebhookTimeout,
+		})
+		if err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, hook)
+	}
+
+	if conf.NotifyCommand != "" {
+		cmd, err := notify.NewCommand(conf.NotifyCommand, conf.NotifyCommandTimeout)
+		if err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, cmd)
+	}
+
+	if conf.SMTPAddr != "" {
+		password := conf.SMTPPassword
+		if password == "" {
+			password = os.Getenv(smtpPasswordEnv)
+		}
+
+		mail, err := notify.NewSMTP(notify.SMTPConfig{
+			Addr:     conf.SMTPAddr,
+			From:     conf.SMTPFrom,
+			To:       conf.SMTPTo,
+			Username: conf.SMTPUsername,
+			Password: password,
+			Timeout:  conf.SMTPTimeout,
+		})
+		if err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, mail)
+	}
+
+	return notifiers, nil
+}
@@ -4,7 +4,10 @@ import (
 	"os"
 	"time"
 
+	"github.com/Juli3nnicolas/http_log_monitor/pkg/history"
 	"github.com/Juli3nnicolas/http_log_monitor/pkg/logger"
+	"github.com/Juli3nnicolas/http_log_monitor/pkg/notify"
+	"github.com/Juli3nnicolas/http_log_monitor/pkg/reader"
 	"github.com/Juli3nnicolas/http_log_monitor/pkg/task"
 	"github.com/Juli3nnicolas/http_log_monitor/pkg/timer"
 )
@@ -13,10 +16,20 @@ import (
 type Backend struct {
 	fetchLogs  task.FetchLogs
 	mostHits   task.FindMostHitSections
+	routes     task.FindMostHitRoutes
+	clients    task.TopClients
 	rates      task.MeasureRates
+	latency    task.MeasureLatency
+	bandwidth  task.MeasureBandwidth
 	countCodes task.CountHTTPCodes
-	alert      task.Alert
+	alerts     task.AlertManager
+	history    history.AlertHistory
+	notifier   notify.Dispatcher
 	tasks      []Taskenv
+	logFormat  string            // configured log format
+	detector   *reader.Detector  // detects the log format when it's configured to "auto", nil otherwise
+	controls   chan alertControl // alert silences and acknowledgements sent from the dashboard or the control socket
+	control    string            // outcome of the last alert control
 }
 
 // Taskenv is a task and all its necessary environment to be executed
@@ -82,6 +95,11 @@ func (b *Backend) runTasks(t *timer.Time, start time.Time, frame time.Duration,
 	resultSent := false
 
 	for t.Now().Sub(start) < frame {
+		// The controls are displayed straight away if the frame's view has been sent
+		if b.applyControls(t) && resultSent {
+			outputChan <- b.view()
+		}
+
 		if !b.fetchLogs.IsDone() {
 			if err = b.fetchLogs.Run(); err != nil {
 				return err
@@ -95,8 +113,32 @@ func (b *Backend) runTasks(t *timer.Time, start time.Time, frame time.Duration,
 			}
 		}
 
+		if !b.routes.IsDone() {
+			if err = b.routes.Run(logs); err != nil {
+				return err
+			}
+		}
+
+		if !b.clients.IsDone() {
+			if err = b.clients.Run(logs); err != nil {
+				return err
+			}
+		}
+
 		if !b.rates.IsDone() {
-			if err = b.rates.Run(logs, uint64(frame.Seconds())); err != nil {
+			if err = b.rates.Run(logs, uint64(frame.Seconds()), t); err != nil {
+				return err
+			}
+		}
+
+		if !b.bandwidth.IsDone() {
+			if err = b.bandwidth.Run(logs, uint64(frame.Seconds())); err != nil {
+				return err
+			}
+		}
+
+		if !b.latency.IsDone() {
+			if err = b.latency.Run(logs); err != nil {
 				return err
 			}
 		}
@@ -107,21 +149,28 @@ func (b *Backend) runTasks(t *timer.Time, start time.Time, frame time.Duration,
 			}
 		}
 
-		if !b.alert.IsDone() && b.rates.IsDone() {
-			if err = b.alert.Run(b.rates.Result(), t); err != nil {
+		if !b.alerts.IsDone() && b.rates.IsDone() && b.latency.IsDone() && b.bandwidth.IsDone() &&
+			b.mostHits.IsDone() && b.countCodes.IsDone() {
+			if err = b.alerts.Run(b.metrics(), t); err != nil {
+				return err
+			}
+		}
+
+		if !b.notifier.IsDone() && b.alerts.IsDone() {
+			if err = b.notifier.Run(b.alerts.Result(), t); err != nil {
+				return err
+			}
+		}
+
+		if !b.history.IsDone() && b.alerts.IsDone() {
+			if err = b.history.Run(b.alerts.Result()); err != nil {
 				return err
 			}
 			allDone = true
 		}
 
 		if allDone && !resultSent {
-			view := ViewFrame{
-				Hits:  b.mostHits.Result(),
-				Rates: b.rates.Result(),
-				Codes: b.countCodes.Result(),
-				Alert: b.alert.Result(),
-			}
-			outputChan <- view
+			outputChan <- b.view()
 			resultSent = true
 		}
 	}
@@ -129,6 +178,69 @@ func (b *Backend) runTasks(t *timer.Time, start time.Time, frame time.Duration,
 	return nil
 }
 
+// view gathers the tasks' results to be displayed
+func (b *Backend) view() ViewFrame {
+	return ViewFrame{
+		Format:      b.formatDescription(),
+		Hits:        b.mostHits.Result(),
+		Routes:      b.routes.Result(),
+		Clients:     b.clients.Result(),
+		Rates:       b.rates.Result(),
+		Latency:     b.latency.Result(),
+		Bandwidth:   b.bandwidth.Result(),
+		Codes:       b.countCodes.Result(),
+		ParseErrors: b.fetchLogs.ParseErrors(),
+		Alerts:      b.alerts.Result(),
+		Incidents:   b.history.Result(),
+		Control:     b.control,
+	}
+}
+
+// applyControls applies the alert controls sent from the dashboard or the control
+// socket, it returns whether any has been applied
+func (b *Backend) applyControls(t *timer.Time) bool {
+	applied := false
+	for {
+		select {
+		case req := <-b.controls:
+			now := t.Now()
+			err := b.alerts.Apply(req.control, now)
+			if err == nil {
+				logger.Get().Infof("alert control applied: %s", req.control)
+			}
+			if req.reply != nil {
+				req.reply <- err
+			}
+			b.control = formatControlOutcome(req.control, now, err)
+			applied = true
+
+		default:
+			return applied
+		}
+	}
+}
+
+// formatDescription describes the log format being read
+func (b *Backend) formatDescription() string {
+	if b.detector == nil {
+		return formatLogFormat(b.logFormat)
+	}
+
+	return formatDetection(b.detector.Result())
+}
+
+// metrics gathers the frame's measures alerts are evaluated against
+func (b *Backend) metrics() task.Metrics {
+	return task.Metrics{
+		Rates:       b.rates.Result(),
+		Latency:     b.latency.Result(),
+		Bandwidth:   b.bandwidth.Result(),
+		ParseErrors: b.fetchLogs.ParseErrors(),
+		Codes:       b.countCodes.Result(),
+		Hits:        b.mostHits.Result(),
+	}
+}
+
 func (b *Backend) shutdown() error {
 	for _, t := range b.tasks {
 		if err := t.Task.Close(); err != nil {
@@ -3,6 +3,8 @@ package app
 import "time"
 
 const (
+	// DefaultLogFormat is the default format of the monitored log file, it is detected automatically
+	DefaultLogFormat string = "auto"
 	// DefaultLogFilePath refers to the first file the app will try to read the logs from
 	DefaultLogFilePath string = "/tmp/access.log"
 	// DefaultUpdateFrameDuration refers to the default time the app will carry out all its measures
@@ -12,19 +14,140 @@ const (
 	// It's an alert refreshing rate
 	DefaultAlertFrameDuration time.Duration = 2 * time.Minute
 	// DefaultAlertThreshold is the default threshold (in req/s) triggering an alert
-	DefaultAlertThreshold uint64 = 10
+	DefaultAlertThreshold float64 = 10
+	// DefaultAlertMetric is the name of the metric monitored by default by the alert (see task.ParseMetric)
+	DefaultAlertMetric string = "req_rate"
+	// DefaultAlertComparison is the default comparison of the alert metric to the threshold (see task.ParseComparison)
+	DefaultAlertComparison string = ">"
+	// DefaultWebhookTemplate is the default payload of the alerts' notifications (see notify.Presets)
+	DefaultWebhookTemplate string = "json"
+	// DefaultWebhookRetries is the default number of retries after a failed notification
+	DefaultWebhookRetries int = 3
+	// DefaultWebhookBackoff is the default delay before the first retry of a notification
+	DefaultWebhookBackoff time.Duration = time.Second
+	// DefaultWebhookTimeout is the default timeout of a notification request
+	DefaultWebhookTimeout time.Duration = 5 * time.Second
+	// DefaultNotifyCommandTimeout is the default time the notification command is killed after
+	DefaultNotifyCommandTimeout time.Duration = 10 * time.Second
+	// DefaultSMTPTimeout is the default timeout of an email notification
+	DefaultSMTPTimeout time.Duration = 10 * time.Second
+	// smtpPasswordEnv is the environment variable the SMTP password is read from if it isn't configured
+	smtpPasswordEnv string = "LOGMONITOR_SMTP_PASSWORD"
+	// DefaultNotifyRateLimit is the default maximum number of notifications per alert and DefaultNotifyRatePeriod
+	DefaultNotifyRateLimit int = 6
+	// DefaultNotifyRatePeriod is the default period the notifications are limited over
+	DefaultNotifyRatePeriod time.Duration = 10 * time.Minute
+	// DefaultAlertPendingWindows is the default number of consecutive -T periods the threshold must be exceeded on to fire
+	DefaultAlertPendingWindows uint64 = 1
+	// controlQueueSize is the number of alert controls sent from the dashboard waiting to be applied
+	controlQueueSize int = 16
+	// controlTimeout is how long the control socket waits for a command to be applied
+	controlTimeout time.Duration = 5 * time.Second
+	// DefaultSectionDepth is the default number of directories making a section (/api for a depth of 1, /api/v1 for 2)
+	DefaultSectionDepth int = 1
+	// parseErrorAlertName is the name of the alert enabled by MaxParseErrorRate
+	parseErrorAlertName string = "parse_errors"
+	// DefaultAllowedLateness is how late a log can be read in event-time mode to be counted in its frame
+	DefaultAllowedLateness time.Duration = 5 * time.Second
+	// DefaultMaxParseErrorRate is the default percentage of unparseable lines triggering an alert, 0 disables the alert
+	DefaultMaxParseErrorRate float64 = 0
 )
 
 // Config is a struct to initialise the application
 type Config struct {
 	// logFilePath refers to the first file the app will try to read the logs from
 	LogFilePath string
+	// LogFormat is the log file's format. It is either the name of a predefined format
+	// or a custom nginx log_format or Apache LogFormat string (see reader.FormatParser)
+	LogFormat string
+	// DetectFormats are custom nginx log_format or Apache LogFormat strings
+	// considered when the log format is detected automatically
+	DetectFormats []string
+	// TrustedProxies are the CIDRs (or IP addresses) of the proxies whose X-Forwarded-For
+	// header gives the real client's address (see log.TrustedProxies)
+	TrustedProxies []string
+	// SectionDepth is the number of directories making a section in the most hits,
+	// latency and bandwidth panels
+	SectionDepth int
+	// RoutePatterns are route templates such as /api/v1/orders/:id/items, routes
+	// matching them are aggregated together (see task.RouteNormalizer)
+	RoutePatterns []string
 	// updateFrameDuration refers to the default time the app will carry out all its measures
 	// Said diferently, this value defines the app's backend refresh rate
 	UpdateFrameDuration time.Duration
+	// EventTime makes the rates be measured on the logs' timestamps instead of the time they are read
+	EventTime bool
+	// AllowedLateness is how late a log can be read in event-time mode, a frame is
+	// measured once a log timestamped AllowedLateness after its end has been read
+	// (or as much time has passed when no log is read)
+	AllowedLateness time.Duration
 	// alertFrameDuration corresponds to a time-frame during which work related to a single alert will be carried to compute its state
 	// It's an alert refreshing rate
 	AlertFrameDuration time.Duration
-	// alertThreshold is the default threshold (in req/s) triggering an alert
-	AlertThreshold uint64
+	// alertThreshold is the threshold (in the alert metric's unit) triggering an alert
+	AlertThreshold float64
+	// AlertMetric is the name of the metric monitored by the alert (see task.ParseMetric)
+	AlertMetric string
+	// AlertComparison is how the alert metric is compared to the threshold, >, < or change (see task.ParseComparison)
+	AlertComparison string
+	// AlertResolveThreshold is the value the alert metric must go back past for the alert
+	// to resolve, the threshold is used if it is 0 (see task.Hysteresis)
+	AlertResolveThreshold float64
+	// AlertPendingWindows is the number of consecutive alert periods the threshold
+	// must be exceeded on before the alert fires
+	AlertPendingWindows uint64
+	// AlertMinFiring is the minimum time the alert fires before it can resolve
+	AlertMinFiring time.Duration
+	// MaxParseErrorRate is the percentage of unparseable lines triggering an alert
+	// (the log format doesn't match the file), 0 disables the alert
+	MaxParseErrorRate float64
+	// AlertRules are additional alerts such as name=slow,metric=latency_p99,threshold=250,duration=5m
+	// (see task.ParseAlertRule), they are evaluated along with the alert configured above
+	AlertRules []string
+	// AlertLogPath is the file the alerts' transitions are appended to, so that the
+	// history survives restarts. Nothing is persisted if it is empty (the default),
+	// the history is only kept in memory.
+	AlertLogPath string
+	// WebhookURL is the URL the alerts' notifications are POSTed to, none are sent if it is empty
+	WebhookURL string
+	// WebhookTemplate is the notifications' payload, a preset (json, slack or mattermost)
+	// or a text/template (see notify.WebhookConfig)
+	WebhookTemplate string
+	// WebhookRetries is the number of retries after a failed notification
+	WebhookRetries int
+	// WebhookBackoff is the delay before the first retry, it doubles on every retry
+	WebhookBackoff time.Duration
+	// WebhookTimeout is the timeout of each notification request
+	WebhookTimeout time.Duration
+	// NotifyCommand is a shell command run whenever an alert is switched on or off, it reads
+	// the alert in JSON on its input and in ALERT_* environment variables (see notify.Command)
+	NotifyCommand string
+	// NotifyCommandTimeout is the time the notification command is killed after
+	NotifyCommandTimeout time.Duration
+	// SMTPAddr is the address (host:port) of the SMTP server the alerts are emailed with,
+	// none are sent if it is empty
+	SMTPAddr string
+	// SMTPFrom is the sender of the alerts' emails
+	SMTPFrom string
+	// SMTPTo are the recipients of the alerts' emails
+	SMTPTo []string
+	// SMTPUsername and SMTPPassword authenticate to the SMTP server if the username is set
+	SMTPUsername string
+	SMTPPassword string
+	// SMTPTimeout is the timeout of each email
+	SMTPTimeout time.Duration
+	// NotifyRateLimit is the maximum number of notifications per alert sent during
+	// NotifyRatePeriod, 0 for no limit (see notify.RateLimit)
+	NotifyRateLimit int
+	// NotifyRatePeriod is the period the notifications are limited over
+	NotifyRatePeriod time.Duration
+	// AlertExprs are alerts written in the expression language such as
+	// "errors: rate(status >= 500, 5m) / rate(all, 5m) > 0.05 for 2m" (see package expr)
+	AlertExprs []string
+	// Silences mute alerts' notifications from startup, they are written as name=duration
+	// such as traffic=2h (see task.AlertControl)
+	Silences []string
+	// ControlSocket is the unix socket alerts are silenced and acknowledged through
+	// while the monitor runs (see SendControl), it is disabled if it is empty
+	ControlSocket string
 }
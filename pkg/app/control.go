@@ -0,0 +1,137 @@
+package app
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Juli3nnicolas/http_log_monitor/pkg/logger"
+	"github.com/Juli3nnicolas/http_log_monitor/pkg/task"
+)
+
+// Replies of the control socket, followed by the control's outcome
+const (
+	controlReplyOK    string = "ok"
+	controlReplyError string = "error"
+)
+
+// alertControl is an alert control sent to the backend. Its outcome is sent to
+// reply if it isn't nil (it must be buffered).
+type alertControl struct {
+	control task.AlertControl
+	reply   chan error
+}
+
+// serveControls listens to the alert controls sent to the unix socket at path
+// (see SendControl) and forwards them to controls. Close the listener to stop.
+func serveControls(path string, controls chan<- alertControl) (net.Listener, error) {
+	// A socket left by a previous run would make Listen fail
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		l.Close()
+		return nil, err
+	}
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				// The listener has been closed
+				return
+			}
+			go handleControl(conn, controls)
+		}
+	}()
+
+	return l, nil
+}
+
+// handleControl reads a command from conn, has the backend apply it and writes its outcome
+func handleControl(conn net.Conn, controls chan<- alertControl) {
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(controlTimeout)); err != nil {
+		logger.Get().Errorln(err)
+		return
+	}
+
+	cmd, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		logger.Get().Errorln(err)
+		return
+	}
+
+	c, err := task.ParseAlertControl(strings.TrimSpace(cmd))
+	if err != nil {
+		fmt.Fprintf(conn, "%s %v\n", controlReplyError, err)
+		return
+	}
+
+	req := alertControl{control: c, reply: make(chan error, 1)}
+	select {
+	case controls <- req:
+	default:
+		fmt.Fprintf(conn, "%s %s\n", controlReplyError, alertControlBusyMsg)
+		return
+	}
+
+	select {
+	case err = <-req.reply:
+	case <-time.After(controlTimeout):
+		fmt.Fprintf(conn, "%s %s - no reply from the monitor\n", controlReplyError, c)
+		return
+	}
+
+	reply := controlReplyOK
+	if err != nil {
+		reply = controlReplyError
+	}
+	fmt.Fprintf(conn, "%s %s\n", reply, formatControlOutcome(c, time.Now(), err))
+}
+
+// SendControl sends an alert control command (silence <alert> <duration>, unsilence <alert>
+// or ack <alert>) to the monitor listening to the unix socket at path. It returns the
+// control's outcome, an error if it couldn't be applied.
+func SendControl(path, cmd string) (string, error) {
+	if path == "" {
+		return "", fmt.Errorf("no control socket - set it with --control-socket")
+	}
+
+	conn, err := net.DialTimeout("unix", path, controlTimeout)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(2 * controlTimeout)); err != nil {
+		return "", err
+	}
+
+	if _, err := fmt.Fprintf(conn, "%s\n", strings.Join(strings.Fields(cmd), " ")); err != nil {
+		return "", err
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+
+	fields := strings.SplitN(strings.TrimSpace(reply), " ", 2)
+	if len(fields) != 2 {
+		return "", fmt.Errorf("invalid reply %q", reply)
+	}
+	if fields[0] != controlReplyOK {
+		return "", fmt.Errorf("%s", fields[1])
+	}
+	return fields[1], nil
+}
@@ -2,8 +2,11 @@ package app
 
 import (
 	"context"
+	"fmt"
 	"time"
 
+	"github.com/Juli3nnicolas/http_log_monitor/pkg/reader"
+	"github.com/Juli3nnicolas/http_log_monitor/pkg/task"
 	"github.com/mum4k/termdash/cell"
 	"github.com/mum4k/termdash/container"
 	"github.com/mum4k/termdash/container/grid"
@@ -19,18 +22,28 @@ const redrawInterval = 250 * time.Millisecond
 // widgets holds the widgets used by this demo.
 type widgets struct {
 	alertMessage *text.Text
+	alertControl *text.Text
+	controlInput *textinput.TextInput
+	alertHistory *text.Text
 	ratesMsg     *text.Text
 	mostHits     *text.Text
+	routes       *text.Text
+	clients      *text.Text
+	latency      *text.Text
+	bandwidth    *text.Text
+	parseErrors  *text.Text
 	httpCodes100 *text.Text
 	httpCodes200 *text.Text
 	httpCodes300 *text.Text
 	httpCodes400 *text.Text
 	httpCodes500 *text.Text
 	reqPerSec    *barchart.BarChart
+	respSizes    *barchart.BarChart
 }
 
-// newWidgets creates all widgets used by this demo.
-func newWidgets(ctx context.Context, c *container.Container) (*widgets, error) {
+// newWidgets creates all widgets used by this demo. The alert controls typed
+// in the dashboard are submitted to onControl.
+func newWidgets(ctx context.Context, c *container.Container, onControl func(input string) error) (*widgets, error) {
 	reqPerSec, err := newBarChart(ctx)
 	if err != nil {
 		return nil, err
@@ -41,7 +54,23 @@ func newWidgets(ctx context.Context, c *container.Container) (*widgets, error) {
 		return nil, err
 	}
 
+	alertControl, err := newScrollableText(alertControlHelp)
+	if err != nil {
+		return nil, err
+	}
+
+	controlInput, err := newTextInput(alertControlLabel, alertControlPlaceHolder, onControl)
+	if err != nil {
+		return nil, err
+	}
+
+	alertHistory, err := newScrollableText(alertHistoryNoIncident)
+	if err != nil {
+		return nil, err
+	}
+
 	ratesMsg, err := newTextLabel(formatRateMsg(rateMsgContent{
+		format:        formatDetection(reader.Detection{}),
 		frameDuration: 1,
 		maxReqPSec:    0,
 		avgReqPSec:    0,
@@ -57,6 +86,36 @@ func newWidgets(ctx context.Context, c *container.Container) (*widgets, error) {
 		return nil, err
 	}
 
+	routes, err := newTextLabel(mostHitsNoTraffic)
+	if err != nil {
+		return nil, err
+	}
+
+	clients, err := newTextLabel(mostHitsNoTraffic)
+	if err != nil {
+		return nil, err
+	}
+
+	latency, err := newTextLabel(latencyNoData)
+	if err != nil {
+		return nil, err
+	}
+
+	bandwidth, err := newTextLabel(fmt.Sprintf(bandwidthMsgFormat, formatBytes(0), formatBytes(0), formatBytes(0), formatBytes(0)))
+	if err != nil {
+		return nil, err
+	}
+
+	respSizes, err := newHistogram(formatSizeBuckets())
+	if err != nil {
+		return nil, err
+	}
+
+	parseErrors, err := newTextLabel(formatParseErrorsMsg(&task.ParseErrors{}))
+	if err != nil {
+		return nil, err
+	}
+
 	httpCodes100, err := newTextLabel(httpCodes100Header)
 	if err != nil {
 		return nil, err
@@ -84,14 +143,23 @@ func newWidgets(ctx context.Context, c *container.Container) (*widgets, error) {
 
 	return &widgets{
 		alertMessage: alertMessage,
+		alertControl: alertControl,
+		controlInput: controlInput,
+		alertHistory: alertHistory,
 		ratesMsg:     ratesMsg,
 		mostHits:     mostHits,
+		routes:       routes,
+		clients:      clients,
+		latency:      latency,
+		bandwidth:    bandwidth,
+		parseErrors:  parseErrors,
 		httpCodes100: httpCodes100,
 		httpCodes200: httpCodes200,
 		httpCodes300: httpCodes300,
 		httpCodes400: httpCodes400,
 		httpCodes500: httpCodes500,
 		reqPerSec:    reqPerSec,
+		respSizes:    respSizes,
 	}, nil
 }
 
@@ -102,14 +170,30 @@ func newWidgets(ctx context.Context, c *container.Container) (*widgets, error) {
 func gridLayout(w *widgets) ([]container.Option, error) {
 	builder := grid.New()
 	builder.Add(
-		grid.RowHeightPerc(8,
-			grid.Widget(w.alertMessage,
-				container.Border(linestyle.Light),
-				container.BorderTitle("Alert:"),
-				container.BorderTitleAlignLeft(),
+		grid.RowHeightPerc(12,
+			grid.ColWidthPerc(65,
+				grid.Widget(w.alertMessage,
+					container.Border(linestyle.Light),
+					container.BorderTitle("Alerts:"),
+					container.BorderTitleAlignLeft(),
+				),
+			),
+			// Alert control - click the input field to type a command
+			grid.ColWidthPercWithOpts(35,
+				[]container.Option{
+					container.Border(linestyle.Light),
+					container.BorderTitle("Alert control"),
+					container.BorderTitleAlignLeft(),
+				},
+				grid.RowHeightPerc(40,
+					grid.Widget(w.controlInput),
+				),
+				grid.RowHeightPerc(60,
+					grid.Widget(w.alertControl),
+				),
 			),
 		),
-		grid.RowHeightPerc(92,
+		grid.RowHeightPerc(88,
 			grid.ColWidthPerc(70,
 				grid.RowHeightPerc(10,
 					grid.Widget(w.ratesMsg,
@@ -118,24 +202,77 @@ func gridLayout(w *widgets) ([]container.Option, error) {
 						container.BorderTitleAlignLeft(),
 					),
 				),
-				grid.RowHeightPerc(80,
+				grid.RowHeightPerc(40,
 					grid.Widget(w.reqPerSec,
 						container.Border(linestyle.Light),
 						container.BorderTitle("Req/s"),
 						container.BorderTitleAlignLeft(),
 					),
 				),
+				grid.RowHeightPerc(20,
+					grid.ColWidthPerc(60,
+						grid.Widget(w.bandwidth,
+							container.Border(linestyle.Light),
+							container.BorderTitle("Bandwidth"),
+							container.BorderTitleAlignLeft(),
+						),
+					),
+					grid.ColWidthPerc(40,
+						grid.Widget(w.respSizes,
+							container.Border(linestyle.Light),
+							container.BorderTitle("Response sizes"),
+							container.BorderTitleAlignLeft(),
+						),
+					),
+				),
+				grid.RowHeightPerc(18,
+					grid.Widget(w.latency,
+						container.Border(linestyle.Light),
+						container.BorderTitle("Latency"),
+						container.BorderTitleAlignLeft(),
+					),
+				),
+				grid.RowHeightPerc(12,
+					grid.Widget(w.parseErrors,
+						container.Border(linestyle.Light),
+						container.BorderTitle("Parse errors"),
+						container.BorderTitleAlignLeft(),
+					),
+				),
 			),
 			grid.ColWidthPerc(30,
-				grid.RowHeightPerc(50,
-					grid.Widget(w.mostHits,
+				grid.RowHeightPerc(25,
+					grid.ColWidthPerc(50,
+						grid.Widget(w.mostHits,
+							container.Border(linestyle.Light),
+							container.BorderTitle("Most hits"),
+							container.BorderTitleAlignLeft(),
+						),
+					),
+					grid.ColWidthPerc(50,
+						grid.Widget(w.clients,
+							container.Border(linestyle.Light),
+							container.BorderTitle("Top clients"),
+							container.BorderTitleAlignLeft(),
+						),
+					),
+				),
+				grid.RowHeightPerc(25,
+					grid.Widget(w.routes,
+						container.Border(linestyle.Light),
+						container.BorderTitle("Top routes"),
+						container.BorderTitleAlignLeft(),
+					),
+				),
+				grid.RowHeightPerc(20,
+					grid.Widget(w.alertHistory,
 						container.Border(linestyle.Light),
-						container.BorderTitle("Most hits"),
+						container.BorderTitle("Alert history"),
 						container.BorderTitleAlignLeft(),
 					),
 				),
 				// HTTP error codes - add a container
-				grid.RowHeightPercWithOpts(50,
+				grid.RowHeightPercWithOpts(30,
 					[]container.Option{
 						container.Border(linestyle.Light),
 						container.BorderTitle("HTTP codes"),
@@ -218,14 +355,36 @@ func newBarChart(ctx context.Context) (*barchart.BarChart, error) {
 	return bc, nil
 }
 
-// newTextInput creates a new TextInput field that changes the text on the
-// SegmentDisplay.
+// newHistogram returns a BarChart with a labelled bar per bucket
+func newHistogram(labels []string) (*barchart.BarChart, error) {
+	barColors := make([]cell.Color, len(labels))
+	valueColors := make([]cell.Color, len(labels))
+	for i := range labels {
+		barColors[i] = cell.ColorNumber(33)
+		valueColors[i] = cell.ColorYellow
+	}
+
+	bc, err := barchart.New(
+		barchart.BarColors(barColors),
+		barchart.ValueColors(valueColors),
+		barchart.Labels(labels),
+		barchart.ShowValues(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return bc, nil
+}
+
+// newTextInput creates a new TextInput field whose text is submitted to onSubmit
+// on Enter, the field is cleared afterwards. Click it to type.
 func newTextInput(text, inputPlaceHolder string, onSubmit func(input string) error) (*textinput.TextInput, error) {
 	input, err := textinput.New(
 		textinput.Label(text, cell.FgColor(cell.ColorWhite)),
-		textinput.MaxWidthCells(20),
 		textinput.PlaceHolder(inputPlaceHolder),
 		textinput.OnSubmit(onSubmit),
+		textinput.ClearOnSubmit(),
 	)
 	if err != nil {
 		return nil, err
@@ -233,6 +392,19 @@ func newTextInput(text, inputPlaceHolder string, onSubmit func(input string) err
 	return input, err
 }
 
+// newScrollableText adds a text scrolled with the mouse wheel (or the arrow keys once
+// clicked), its lines are wrapped
+func newScrollableText(msg string) (*text.Text, error) {
+	txt, err := text.New(text.WrapAtWords())
+	if err != nil {
+		return nil, err
+	}
+	if err := txt.Write(msg, text.WriteCellOpts(cell.FgColor(cell.ColorWhite))); err != nil {
+		return nil, err
+	}
+	return txt, nil
+}
+
 // Adds a label (raw text)
 func newTextLabel(msg string) (*text.Text, error) {
 	txt, err := text.New()
@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 	"strconv"
+	"strings"
 
+	"github.com/Juli3nnicolas/http_log_monitor/pkg/history"
 	"github.com/Juli3nnicolas/http_log_monitor/pkg/logger"
 	"github.com/Juli3nnicolas/http_log_monitor/pkg/task"
 	"github.com/mum4k/termdash"
@@ -21,14 +23,36 @@ type renderer struct {
 	container *container.Container
 	cancel    context.CancelFunc
 	gridOpts  []container.Option
-	alert     *task.AlertState
+	// alerts keeps track of the alerts' states by rule name
+	alerts map[string]*alertView
+	// incidentsMsg is the alert history displayed, the panel is only rewritten
+	// when it changes so that it keeps its scrolling position
+	incidentsMsg string
+	// controls sends the alert controls typed in the dashboard to the backend
+	controls chan<- alertControl
+	// controlMsg is the outcome of the last alert control applied by the backend
+	controlMsg string
 }
 
 type ViewFrame struct {
-	Hits  []task.Hit
-	Rates task.Rates
-	Codes map[uint32]uint64
-	Alert task.AlertState
+	Format      string
+	Hits        []task.Hit
+	Routes      []task.RouteHit
+	Clients     task.ClientRankings
+	Rates       task.Rates
+	Latency     task.Latency
+	Bandwidth   task.Bandwidth
+	Codes       map[uint32]uint64
+	ParseErrors task.ParseErrors
+	Alerts      []task.AlertState  // state of every alert rule
+	Incidents   []history.Incident // alert history, the latest first
+	Control     string             // outcome of the last alert control
+}
+
+// alertView keeps track of an alert's states to display it
+type alertView struct {
+	last *task.AlertState // last state the alert has been switched on or off, nil if never
+	msg  string
 }
 
 // rootID is the ID assigned to the root container.
@@ -49,7 +73,7 @@ func (r *renderer) init() (context.Context, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 	r.cancel = cancel
 
-	w, err := newWidgets(ctx, r.container)
+	w, err := newWidgets(ctx, r.container, r.submitControl)
 	if err != nil {
 		return nil, err
 	}
@@ -104,7 +128,15 @@ func (r *renderer) update(viewChan chan ViewFrame, errorHandle func(error)) {
 			errorHandle(err)
 		}
 
-		if err := updateRates(w, &view.Rates); err != nil {
+		if err := updateRoutes(w, view.Routes); err != nil {
+			errorHandle(err)
+		}
+
+		if err := updateClients(w, &view.Clients); err != nil {
+			errorHandle(err)
+		}
+
+		if err := updateRates(w, view.Format, &view.Rates); err != nil {
 			errorHandle(err)
 		}
 
@@ -112,16 +144,60 @@ func (r *renderer) update(viewChan chan ViewFrame, errorHandle func(error)) {
 			errorHandle(err)
 		}
 
+		if err := updateLatency(w, &view.Latency); err != nil {
+			errorHandle(err)
+		}
+
+		if err := updateBandwidth(w, &view.Bandwidth); err != nil {
+			errorHandle(err)
+		}
+
 		if err := updateCodes(w, view.Codes); err != nil {
 			errorHandle(err)
 		}
 
-		if err := r.updateAlerts(&view.Alert); err != nil {
+		if err := updateParseErrors(w, &view.ParseErrors); err != nil {
+			errorHandle(err)
+		}
+
+		if err := r.updateAlerts(&view); err != nil {
+			errorHandle(err)
+		}
+
+		if err := r.updateIncidents(view.Incidents); err != nil {
+			errorHandle(err)
+		}
+
+		if err := r.updateControl(view.Control); err != nil {
 			errorHandle(err)
 		}
 	}
 }
 
+// submitControl sends the alert control typed in the dashboard to the backend. It always
+// returns nil, errors are displayed below the input field.
+func (r *renderer) submitControl(input string) error {
+	msg := alertControlHelp
+	if strings.TrimSpace(input) != "" {
+		c, err := task.ParseAlertControl(input)
+		if err != nil {
+			msg = err.Error()
+		} else {
+			select {
+			case r.controls <- alertControl{control: c}:
+				msg = fmt.Sprintf(alertControlSentMsgFormat, c)
+			default:
+				msg = alertControlBusyMsg
+			}
+		}
+	}
+
+	if err := updateTextWidget(r.widgets.alertControl, msg); err != nil {
+		LogUpdateError()(err)
+	}
+	return nil
+}
+
 func updateHit(w *widgets, hits []task.Hit) error {
 	var msg string
 
@@ -138,6 +214,18 @@ func updateHit(w *widgets, hits []task.Hit) error {
 			msg += method + ": " + strconv.Itoa(int(count)) + sep
 		}
 		msg += ")\n"
+
+		// Drill down to the section's busiest subsections
+		nbSubsections := 0
+		for _, sub := range hits[i].Subsections {
+			if nbSubsections == maxDisplayedSubsections {
+				break
+			}
+			if sub.Section != hits[i].Section {
+				msg += formatSubsectionLine(&sub)
+				nbSubsections++
+			}
+		}
 	}
 
 	if msg == "" {
@@ -147,17 +235,62 @@ func updateHit(w *widgets, hits []task.Hit) error {
 	return updateTextWidget(w.mostHits, msg)
 }
 
-func updateRates(w *widgets, r *task.Rates) error {
+func updateRoutes(w *widgets, routes []task.RouteHit) error {
+	var msg string
+	for i := range routes {
+		msg += formatRouteLine(&routes[i])
+	}
+
+	if msg == "" {
+		msg = mostHitsNoTraffic
+	}
+
+	return updateTextWidget(w.routes, msg)
+}
+
+func updateClients(w *widgets, c *task.ClientRankings) error {
+	if len(c.ByRequests) == 0 {
+		return updateTextWidget(w.clients, mostHitsNoTraffic)
+	}
+
+	msg := clientsRequestsHeader
+	for i := range c.ByRequests {
+		msg += fmt.Sprintf(clientMsgFormat, c.ByRequests[i].Host, c.ByRequests[i].Requests)
+	}
+
+	if len(c.ByBytes) > 0 {
+		msg += clientsBytesHeader
+		for i := range c.ByBytes {
+			msg += fmt.Sprintf(clientBytesMsgFormat, c.ByBytes[i].Host, formatBytes(c.ByBytes[i].Bytes))
+		}
+	}
+
+	if len(c.ByErrors) > 0 {
+		msg += clientsErrorsHeader
+		for i := range c.ByErrors {
+			msg += fmt.Sprintf(clientMsgFormat, c.ByErrors[i].Host, c.ByErrors[i].Errors)
+		}
+	}
+
+	return updateTextWidget(w.clients, msg)
+}
+
+func updateRates(w *widgets, format string, r *task.Rates) error {
 	f := &r.Frame
 	g := &r.Global
 
 	msg := formatRateMsg(rateMsgContent{
+		format:        format,
 		frameDuration: f.Duration,
 		maxReqPSec:    g.MaxReqPerS,
 		avgReqPSec:    g.AvgReqPerS,
 		nbSuccesses:   f.NbSuccess,
 		nbFailures:    f.NbFailures,
 	})
+	if !f.Start.IsZero() {
+		msg += fmt.Sprintf(eventTimeMsgFormat, f.Start.Format(eventTimeLayout), f.NbLate)
+	}
+	msg += formatWindowRates(r.Windows)
 
 	return updateTextWidget(w.ratesMsg, msg)
 }
@@ -184,6 +317,49 @@ func createUpdateReqPerSeconds() func(w *widgets, r *task.Rates) error {
 	return update
 }
 
+func updateLatency(w *widgets, l *task.Latency) error {
+	if l.Frame.NbSamples == 0 {
+		return updateTextWidget(w.latency, latencyNoData)
+	}
+
+	msg := formatLatencyLine(latencyAllRequests, &l.Frame)
+	for i := range l.Sections {
+		msg += formatLatencyLine(l.Sections[i].Section, &l.Sections[i].LatencyStats)
+	}
+
+	return updateTextWidget(w.latency, msg)
+}
+
+func updateBandwidth(w *widgets, b *task.Bandwidth) error {
+	f := &b.Frame
+	g := &b.Global
+
+	msg := fmt.Sprintf(bandwidthMsgFormat, formatBytes(f.BytesPerS), formatBytes(g.AvgBytesPerS), formatBytes(g.MaxBytesPerS), formatBytes(f.NbBytes))
+	for i := range b.Sections {
+		if i == maxBandwidthSections {
+			break
+		}
+		msg += fmt.Sprintf(bandwidthSectionMsgFormat, b.Sections[i].Section, formatBytes(b.Sections[i].NbBytes))
+	}
+	if err := updateTextWidget(w.bandwidth, msg); err != nil {
+		return err
+	}
+
+	sizes := make([]int, len(b.Sizes))
+	max := 1 // Values cannot equal to 0
+	for i, count := range b.Sizes {
+		sizes[i] = int(count)
+		if sizes[i] > max {
+			max = sizes[i]
+		}
+	}
+	if len(sizes) == 0 {
+		sizes = make([]int, len(task.SizeBuckets)+1)
+	}
+
+	return w.respSizes.Values(sizes, max)
+}
+
 func updateCodes(w *widgets, codes map[uint32]uint64) error {
 	var (
 		msg100 string = "100:\n"
@@ -230,27 +406,88 @@ func updateCodes(w *widgets, codes map[uint32]uint64) error {
 	return updateTextWidget(w.httpCodes500, msg500)
 }
 
-func (r *renderer) updateAlerts(alert *task.AlertState) error {
-	// The alarm is has been activated so log it
-	if alert.IsOn {
-		r.alert = alert
-		msg := formatAlertOnMsg(r.alert)
-		return updateTextWidget(r.widgets.alertMessage, msg)
+func updateParseErrors(w *widgets, p *task.ParseErrors) error {
+	msg := formatParseErrorsMsg(p)
+	for i := len(p.Samples) - 1; i >= 0; i-- {
+		msg += formatParseErrorSample(&p.Samples[i])
 	}
 
-	// The alarm has just been desactivated, log it
-	if r.alert != nil && r.alert.IsOn && !alert.IsOn {
-		r.alert = alert
-		msg := formatAlertOffMsg(r.alert)
-		return updateTextWidget(r.widgets.alertMessage, msg)
+	return updateTextWidget(w.parseErrors, msg)
+}
+
+func (r *renderer) updateAlerts(view *ViewFrame) error {
+	if r.alerts == nil {
+		r.alerts = make(map[string]*alertView)
 	}
 
-	if r.alert == nil {
-		msg := formatAlertInfoMsg(alert)
-		return updateTextWidget(r.widgets.alertMessage, msg)
+	var msg string
+	for i := range view.Alerts {
+		alert := &view.Alerts[i]
+		v, ok := r.alerts[alert.Name]
+		if !ok {
+			v = &alertView{}
+			r.alerts[alert.Name] = v
+		}
+		msg += fmt.Sprintf(alertNameMsgFormat, alert.Name) + v.update(alert) + formatAlertControl(alert) + "\n"
 	}
 
-	return nil
+	return updateTextWidget(r.widgets.alertMessage, msg)
+}
+
+func (r *renderer) updateIncidents(incidents []history.Incident) error {
+	var msg string
+	for i := range incidents {
+		msg += formatIncidentLine(&incidents[i])
+	}
+
+	if msg == "" {
+		msg = alertHistoryNoIncident
+	}
+
+	if msg == r.incidentsMsg {
+		return nil
+	}
+	r.incidentsMsg = msg
+
+	return updateTextWidget(r.widgets.alertHistory, msg)
+}
+
+// updateControl displays the outcome of the last alert control once it has been applied
+func (r *renderer) updateControl(msg string) error {
+	if msg == "" || msg == r.controlMsg {
+		return nil
+	}
+	r.controlMsg = msg
+
+	return updateTextWidget(r.widgets.alertControl, msg)
+}
+
+// update returns the message describing the alert's state
+func (v *alertView) update(alert *task.AlertState) string {
+	switch {
+	// The alarm is has been activated so log it
+	case alert.IsOn:
+		v.last = alert
+		v.msg = formatAlertOnMsg(alert)
+
+	// The threshold is exceeded but the alarm hasn't been activated yet
+	case alert.Status == task.Pending:
+		v.msg = formatAlertPendingMsg(alert)
+
+	// The alarm has just been desactivated, log it
+	case v.last != nil && v.last.IsOn:
+		v.last = alert
+		v.msg = formatAlertOffMsg(alert)
+
+	case v.last == nil:
+		v.msg = formatAlertInfoMsg(alert)
+
+	// The alarm is no longer pending, show its last transition again
+	default:
+		v.msg = formatAlertOffMsg(v.last)
+	}
+
+	return v.msg
 }
 
 func httpReturnCodeLine(code uint32, count uint64) string {
@@ -2,19 +2,75 @@ package app
 
 import (
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/Juli3nnicolas/http_log_monitor/pkg/history"
+	"github.com/Juli3nnicolas/http_log_monitor/pkg/reader"
 	"github.com/Juli3nnicolas/http_log_monitor/pkg/task"
 )
 
 const (
-	alertThresholdMessageFormat string = "Threshold: %d req/s"
+	alertMetricMessageFormat    string = "Metric: %s"
+	alertThresholdMessageFormat string = "Threshold: %s %g %s"
 	alertDurationMessageFormat  string = "Duration: %s"
+	alertResolveMessageFormat   string = "Resolve: %s %g %s"
+	alertPendingWindowsFormat   string = "Pending: %d periods"
+	alertMinFiringMessageFormat string = "Min firing: %s"
+	alertPendingMessageFormat   string = "Pending - the threshold has been exceeded on %d/%d periods"
+	alertExprPendingMsgFormat   string = "Pending - the expression holds, the alert fires if it holds for %s"
 	alertMessageHeader          string = "Message:"
+	alertNameMsgFormat          string = "[%s] "
 	alertOnMessageFormat        string = "High traffic generated an alert - hits = %d, triggered at %v"
+	alertMetricOnMessageFormat  string = "High %s generated an alert - value = %.2f %s, triggered at %v"
+	alertBelowOnMessageFormat   string = "Low %s generated an alert - value = %.2f %s, triggered at %v"
+	alertChangeOnMessageFormat  string = "%s changed by %.1f%% - triggered at %v"
+	alertExprMessageFormat      string = "Expr: %s"
+	alertExprOnMessageFormat    string = "The expression holds - value = %.4g, triggered at %v"
 	alertOffMessageFormat       string = "Traffic is back to normal - recovery time is %v"
+	alertHistoryNoIncident      string = "No incident"
+	alertSilencedMsgFormat      string = " (silenced until %s)"
+	alertAcknowledgedMsg        string = " (acknowledged)"
+	alertControlLabel           string = "> "
+	alertControlPlaceHolder     string = "silence traffic 2h"
+	alertControlHelp            string = "silence <alert> <duration>, unsilence <alert> or ack <alert>"
+	alertControlSentMsgFormat   string = "%s - sent"
+	alertControlBusyMsg         string = "Too many commands waiting to be applied, try again"
+	controlSilenceMsgFormat     string = "%s [%s] silenced until %s"
+	controlUnsilenceMsgFormat   string = "%s [%s] no longer silenced"
+	controlAckMsgFormat         string = "%s [%s] acknowledged until it resolves"
+	controlErrorMsgFormat       string = "%s %s failed - %v"
+	incidentTimeFormat          string = "Jan 2 15:04:05"
+	incidentMsgFormat           string = "[%s] %s - %s (%s) peak %.2f %s\n"
+	incidentOngoingMsgFormat    string = "[%s] %s - firing, triggered at %.2f %s\n"
+	incidentUnresolvedMsgFormat string = "[%s] %s - no resolution recorded\n"
 	rateMsgHeader               string = "Frame: "
-	rateMsgFormat               string = rateMsgHeader + "%ds Max: %d req/s Avg: %d req/s Success: %d Failure: %d"
+	rateMsgFormat               string = "Format: %s " + rateMsgHeader + "%ds Max: %d req/s Avg: %d req/s Success: %d Failure: %d"
+	eventTimeMsgFormat          string = " Event time: %s Late: %d"
+	eventTimeLayout             string = "15:04:05"
+	windowRatesHeader           string = "\nReq/s"
+	windowRateMsgFormat         string = " %s: %.1f"
+	formatDetectingMsg          string = "auto (detecting)"
+	formatDetectedMsgFormat     string = "auto %s (%.0f%% of %d lines)"
+	formatCustomMsg             string = "custom"
 	mostHitsNoTraffic           string = "No traffic"
+	subsectionMsgFormat         string = "  %s: %d\n"
+	maxDisplayedSubsections     int    = 3
+	routeMsgFormat              string = "%s: %d (errors: %d%s)\n"
+	clientsRequestsHeader       string = "Requests:\n"
+	clientsBytesHeader          string = "Bytes:\n"
+	clientsErrorsHeader         string = "Errors:\n"
+	clientMsgFormat             string = "  %s: %d\n"
+	clientBytesMsgFormat        string = "  %s: %s\n"
+	bandwidthMsgFormat          string = "Frame: %s/s Avg: %s/s Max: %s/s Total: %s\n"
+	bandwidthSectionMsgFormat   string = "%s: %s\n"
+	maxBandwidthSections        int    = 3
+	latencyNoData               string = "No request duration in the logs"
+	latencyMsgFormat            string = "%s: p50 %v p90 %v p99 %v max %v (%d req)\n"
+	latencyAllRequests          string = "All"
+	parseErrorsMsgFormat        string = "Frame: %d of %d lines (%.1f%%) Total: %d\n"
+	parseErrorSampleFormat      string = "%s (%v)\n"
 	httpCodes100Header          string = "100:\n"
 	httpCodes200Header          string = "200:\n"
 	httpCodes300Header          string = "300:\n"
@@ -23,6 +79,7 @@ const (
 )
 
 type rateMsgContent struct {
+	format        string
 	frameDuration uint64
 	maxReqPSec    uint64
 	avgReqPSec    uint64
@@ -31,10 +88,74 @@ type rateMsgContent struct {
 }
 
 func formatRateMsg(r rateMsgContent) string {
-	return fmt.Sprintf(rateMsgFormat, r.frameDuration, r.maxReqPSec, r.avgReqPSec, r.nbSuccesses, r.nbFailures)
+	return fmt.Sprintf(rateMsgFormat, r.format, r.frameDuration, r.maxReqPSec, r.avgReqPSec, r.nbSuccesses, r.nbFailures)
+}
+
+// formatWindowRates lists the request-rates over the trailing windows (1s: 0.9 10s: 1.2 1m: 1.0...)
+func formatWindowRates(windows []task.WindowRate) string {
+	if len(windows) == 0 {
+		return ""
+	}
+
+	msg := windowRatesHeader
+	for _, w := range windows {
+		// 1m0s is displayed as 1m
+		window := w.Window.String()
+		if strings.HasSuffix(window, "m0s") {
+			window = strings.TrimSuffix(window, "0s")
+		}
+		msg += fmt.Sprintf(windowRateMsgFormat, window, w.ReqPerS)
+	}
+	return msg
+}
+
+// formatLogFormat shortens custom formats (nginx or Apache format strings)
+func formatLogFormat(format string) string {
+	if strings.ContainsAny(format, "$% ") {
+		return formatCustomMsg
+	}
+	return format
+}
+
+func formatDetection(d reader.Detection) string {
+	if d.NbLines == 0 {
+		return formatDetectingMsg
+	}
+	return fmt.Sprintf(formatDetectedMsgFormat, formatLogFormat(d.Name), d.Confidence*100, d.NbLines)
 }
 
 func formatAlertOnMsg(alert *task.AlertState) string {
+	if alert.Expr != "" {
+		return formatAlertInfoMsg(alert) +
+			fmt.Sprintf(
+				alertMessageHeader+" "+
+					alertExprOnMessageFormat,
+				alert.Value, alert.Date.Local())
+	}
+
+	switch alert.Comparison {
+	case task.Below:
+		return formatAlertInfoMsg(alert) +
+			fmt.Sprintf(
+				alertMessageHeader+" "+
+					alertBelowOnMessageFormat,
+				alert.Metric, alert.Value, alert.Unit, alert.Date.Local())
+	case task.ChangeBy:
+		return formatAlertInfoMsg(alert) +
+			fmt.Sprintf(
+				alertMessageHeader+" "+
+					alertChangeOnMessageFormat,
+				alert.Metric, alert.Value, alert.Date.Local())
+	}
+
+	if alert.Metric != task.RequestRateMetricName {
+		return formatAlertInfoMsg(alert) +
+			fmt.Sprintf(
+				alertMessageHeader+" "+
+					alertMetricOnMessageFormat,
+				alert.Metric, alert.Value, alert.Unit, alert.Date.Local())
+	}
+
 	return formatAlertInfoMsg(alert) +
 		fmt.Sprintf(
 			alertMessageHeader+" "+
@@ -50,10 +171,170 @@ func formatAlertOffMsg(alert *task.AlertState) string {
 			alert.Date.Local())
 }
 
+func formatAlertPendingMsg(alert *task.AlertState) string {
+	if alert.Expr != "" {
+		return formatAlertInfoMsg(alert) +
+			fmt.Sprintf(
+				alertMessageHeader+" "+
+					alertExprPendingMsgFormat,
+				alert.Duration.String())
+	}
+
+	return formatAlertInfoMsg(alert) +
+		fmt.Sprintf(
+			alertMessageHeader+" "+
+				alertPendingMessageFormat,
+			alert.NbPending, alert.PendingWindows)
+}
+
 func formatAlertInfoMsg(alert *task.AlertState) string {
+	if alert.Expr != "" {
+		return fmt.Sprintf(alertExprMessageFormat+" ", alert.Expr)
+	}
+
+	// A change is a percentage whatever the metric's unit
+	unit := alert.Unit
+	if alert.Comparison == task.ChangeBy {
+		unit = "%"
+	}
+
 	return fmt.Sprintf(
-		alertThresholdMessageFormat+" "+
+		alertMetricMessageFormat+" "+
+			alertThresholdMessageFormat+" "+
 			alertDurationMessageFormat+" ",
+		alert.Metric,
+		alert.Comparison,
 		alert.Threshold,
-		alert.Duration.String())
+		unit,
+		alert.Duration.String()) +
+		formatAlertHysteresis(alert, unit)
+}
+
+// formatAlertHysteresis describes the hysteresis settings differing from the defaults
+func formatAlertHysteresis(alert *task.AlertState, unit string) string {
+	var msg string
+	if alert.ResolveThreshold != alert.Threshold {
+		op := "<"
+		if alert.Comparison == task.Below {
+			op = ">="
+		}
+		msg += fmt.Sprintf(alertResolveMessageFormat+" ", op, alert.ResolveThreshold, unit)
+	}
+	if alert.PendingWindows > 1 {
+		msg += fmt.Sprintf(alertPendingWindowsFormat+" ", alert.PendingWindows)
+	}
+	if alert.MinFiring > 0 {
+		msg += fmt.Sprintf(alertMinFiringMessageFormat+" ", alert.MinFiring.String())
+	}
+	return msg
+}
+
+func formatSubsectionLine(h *task.Hit) string {
+	return fmt.Sprintf(subsectionMsgFormat, h.Section, h.Total)
+}
+
+// formatRouteLine describes a route's hits, methods are sorted by name
+func formatRouteLine(r *task.RouteHit) string {
+	methods := make([]string, 0, len(r.Methods))
+	for method := range r.Methods {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+
+	var counts string
+	for _, method := range methods {
+		counts += fmt.Sprintf(", %s: %d", method, r.Methods[method])
+	}
+
+	return fmt.Sprintf(routeMsgFormat, r.Route, r.Total, r.Errors, counts)
+}
+
+// formatBytes displays a size using the largest fitting unit (1.5 KiB, 12.0 MiB)
+func formatBytes(size uint64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	div, exp := uint64(unit), 0
+	for n := size / unit; n >= unit && exp < 4; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTP"[exp])
+}
+
+// formatSizeBuckets labels the response-size histogram's buckets (<1.0 KiB, ..., >=10.0 MiB)
+func formatSizeBuckets() []string {
+	labels := make([]string, 0, len(task.SizeBuckets)+1)
+	for _, bound := range task.SizeBuckets {
+		labels = append(labels, "<"+formatBytes(bound))
+	}
+	return append(labels, ">="+formatBytes(task.SizeBuckets[len(task.SizeBuckets)-1]))
+}
+
+func formatParseErrorsMsg(p *task.ParseErrors) string {
+	return fmt.Sprintf(parseErrorsMsgFormat, p.Frame, p.NbLines, p.Rate(), p.Total)
+}
+
+func formatParseErrorSample(e *reader.ParseError) string {
+	return fmt.Sprintf(parseErrorSampleFormat, e.Line, e.Err)
+}
+
+func formatLatencyLine(name string, l *task.LatencyStats) string {
+	return fmt.Sprintf(latencyMsgFormat, name, roundDuration(l.P50), roundDuration(l.P90), roundDuration(l.P99), roundDuration(l.Max), l.NbSamples)
+}
+
+// roundDuration keeps durations readable (12.345678ms becomes 12.35ms)
+func roundDuration(d time.Duration) time.Duration {
+	switch {
+	case d >= time.Second:
+		return d.Round(time.Millisecond)
+	case d >= time.Millisecond:
+		return d.Round(10 * time.Microsecond)
+	}
+	return d.Round(time.Microsecond)
+}
+
+// formatIncidentLine describes an incident, values are displayed in the alert's unit
+func formatIncidentLine(inc *history.Incident) string {
+	start := inc.Start.Local().Format(incidentTimeFormat)
+
+	switch {
+	case inc.Ongoing:
+		return fmt.Sprintf(incidentOngoingMsgFormat, inc.Alert, start, inc.Value, inc.Unit)
+	case !inc.IsResolved():
+		return fmt.Sprintf(incidentUnresolvedMsgFormat, inc.Alert, start)
+	}
+
+	end := inc.End.Local().Format(incidentTimeFormat)
+	return fmt.Sprintf(incidentMsgFormat, inc.Alert, start, end, inc.Duration.String(), inc.Peak, inc.Unit)
+}
+
+// formatAlertControl tags an alert line with its silence and acknowledgement
+func formatAlertControl(alert *task.AlertState) string {
+	var msg string
+	if alert.Silenced {
+		msg += fmt.Sprintf(alertSilencedMsgFormat, alert.SilencedUntil.Local().Format(incidentTimeFormat))
+	}
+	if alert.Acknowledged {
+		msg += alertAcknowledgedMsg
+	}
+	return msg
+}
+
+// formatControlOutcome describes the outcome of c, applied at now
+func formatControlOutcome(c task.AlertControl, now time.Time, err error) string {
+	applied := now.Local().Format(eventTimeLayout)
+	if err != nil {
+		return fmt.Sprintf(controlErrorMsgFormat, applied, c, err)
+	}
+
+	switch c.Action {
+	case task.Silence:
+		return fmt.Sprintf(controlSilenceMsgFormat, applied, c.Name, now.Add(c.Duration).Local().Format(incidentTimeFormat))
+	case task.Unsilence:
+		return fmt.Sprintf(controlUnsilenceMsgFormat, applied, c.Name)
+	}
+	return fmt.Sprintf(controlAckMsgFormat, applied, c.Name)
 }
@@ -0,0 +1,163 @@
+package expr
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Juli3nnicolas/http_log_monitor/pkg/task"
+	"github.com/Juli3nnicolas/http_log_monitor/pkg/timer"
+)
+
+// Alert is an alert triggered when an expression's condition has held for its
+// For duration (immediately if it has none), it is pending meanwhile. It recovers
+// as soon as the condition doesn't hold anymore. It implements task.Alerter.
+type Alert struct {
+	name string
+	src  string
+	expr *Expr
+	// pendingSince is when the condition started to hold, zero if it doesn't
+	pendingSince time.Time
+	done         bool
+	state        task.AlertState
+}
+
+// NewAlert compiles src into an alert called name
+func NewAlert(name, src string) (*Alert, error) {
+	if name == "" {
+		return nil, fmt.Errorf("invalid alert %q - its name is empty", src)
+	}
+
+	expr, err := Compile(src)
+	if err != nil {
+		return nil, err
+	}
+	return &Alert{name: name, src: src, expr: expr}, nil
+}
+
+// ParseAlert reads an alert written as "name: expression", for instance
+// "errors: rate(status >= 500, 5m) / rate(all, 5m) > 0.05 for 2m"
+func ParseAlert(spec string) (*Alert, error) {
+	i := strings.IndexByte(spec, ':')
+	if i < 0 {
+		return nil, fmt.Errorf("invalid alert %q - it must be written as name: expression", spec)
+	}
+
+	name := strings.TrimSpace(spec[:i])
+	if strings.ContainsAny(name, " \t(") {
+		return nil, fmt.Errorf("invalid alert %q - it must be written as name: expression", spec)
+	}
+	return NewAlert(name, strings.TrimSpace(spec[i+1:]))
+}
+
+// Init resets the alert, the expression's windows are emptied
+func (o *Alert) Init(args ...interface{}) error {
+	if len(args) != 0 {
+		return fmt.Errorf("wrong parameters - an expression alert takes no parameter")
+	}
+
+	expr, err := Compile(o.src)
+	if err != nil {
+		return err
+	}
+
+	o.expr = expr
+	o.pendingSince = time.Time{}
+	o.state = task.AlertState{
+		Name:     o.name,
+		Expr:     o.src,
+		Duration: expr.For,
+	}
+	return nil
+}
+
+// BeforeRun flags the task as not done
+func (o *Alert) BeforeRun(...interface{}) error {
+	o.done = false
+	return nil
+}
+
+// Run evaluates the expression, triggers or recovers the alert
+// Parameters :
+// - metrics task.Metrics : frame measures the expression is evaluated on
+// - timer : a Timer interface, only uses Timer.Now()
+func (o *Alert) Run(args ...interface{}) error {
+	if len(args) != 2 {
+		return fmt.Errorf("wrong parameters - this function expects a task.Metrics parameter and a Timer struct")
+	}
+
+	metrics, ok := args[0].(task.Metrics)
+	if !ok {
+		return fmt.Errorf("type error - got %T instead of %T", args[0], metrics)
+	}
+
+	t, ok := args[1].(timer.Timer)
+	if !ok {
+		return fmt.Errorf("type error - got %T instead of %T", args[1], t)
+	}
+
+	if o.expr == nil {
+		if err := o.Init(); err != nil {
+			return err
+		}
+	}
+
+	now := t.Now()
+	holds, value := o.expr.Eval(&metrics, now)
+	o.state.Current = value
+
+	switch {
+	case holds:
+		if o.pendingSince.IsZero() {
+			o.pendingSince = now
+		}
+		if !o.state.IsOn && now.Sub(o.pendingSince) >= o.expr.For {
+			o.state.IsOn = true
+			o.state.Status = task.Firing
+			o.state.Date = now
+			o.state.Value = value
+			o.state.Avg = uint64(value)
+		} else if !o.state.IsOn {
+			o.state.Status = task.Pending
+		}
+
+	case o.state.IsOn:
+		o.pendingSince = time.Time{}
+		o.state.IsOn = false
+		o.state.Status = task.Inactive
+		o.state.Date = now
+		o.state.Value = 0
+		o.state.Avg = 0
+
+	default:
+		o.pendingSince = time.Time{}
+		o.state.Status = task.Inactive
+	}
+	o.done = true
+
+	return nil
+}
+
+// AfterRun does nothing, implements the Task interface
+func (o *Alert) AfterRun() error {
+	return nil
+}
+
+// Result returns a copy of the current alert state
+func (o *Alert) Result() task.AlertState {
+	return o.state
+}
+
+// IsDone is true once the alert has been evaluated for the current frame
+func (o *Alert) IsDone() bool {
+	return o.done
+}
+
+// Close wipes the alert's state. Call Init to use it again.
+func (o *Alert) Close() error {
+	o.expr = nil
+	o.pendingSince = time.Time{}
+	o.done = false
+	o.state = task.AlertState{}
+	return nil
+}
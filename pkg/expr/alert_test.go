@@ -0,0 +1,121 @@
+package expr
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Juli3nnicolas/http_log_monitor/pkg/task"
+	"github.com/Juli3nnicolas/http_log_monitor/pkg/timer"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAlertIsTriggeredOnceTheConditionHasHeldForItsDuration(t *testing.T) {
+	// Setup stage - 1 minute frames
+	const frameDuration time.Duration = time.Minute
+
+	alert, err := ParseAlert("errors: rate(status >= 500, 5m) / rate(all, 5m) > 0.05 for 2m")
+	if err != nil {
+		panic(err)
+	}
+	if err := alert.Init(); err != nil {
+		panic(err)
+	}
+
+	frame := func(ok, errors uint64) task.Metrics {
+		return task.Metrics{
+			Rates: task.Rates{Frame: task.FrameRates{Duration: uint64(frameDuration.Seconds())}},
+			Codes: map[uint32]uint64{200: ok, 503: errors},
+		}
+	}
+
+	now := time.Date(2020, 2, 9, 16, 27, 0, 0, time.UTC)
+	t1 := &timer.TimeStub{
+		NowStub: func() time.Time {
+			now = now.Add(frameDuration)
+			return now
+		},
+	}
+
+	// Exercise & validation stages
+
+	// 10% of errors, the condition starts holding
+	assert.Nil(t, alert.BeforeRun())
+	assert.Nil(t, alert.Run(frame(90, 10), t1))
+	assert.True(t, alert.IsDone())
+	res := alert.Result()
+	assert.Equal(t, "errors", res.Name)
+	assert.Equal(t, "rate(status >= 500, 5m) / rate(all, 5m) > 0.05 for 2m", res.Expr)
+	assert.Equal(t, 2*time.Minute, res.Duration)
+	assert.False(t, res.IsOn)
+	assert.Equal(t, task.Pending, res.Status)
+
+	// It has held for a minute
+	assert.Nil(t, alert.Run(frame(90, 10), t1))
+	assert.False(t, alert.Result().IsOn)
+
+	// It has held for 2 minutes so the alert is switched on
+	assert.Nil(t, alert.Run(frame(90, 10), t1))
+	res = alert.Result()
+	assert.True(t, res.IsOn)
+	assert.Equal(t, task.Firing, res.Status)
+	assert.Equal(t, now, res.Date)
+	assert.InDelta(t, 0.1, res.Value, 1e-9)
+
+	// Errors stop but the 5 minute window still holds 30 errors out of 400
+	// requests (7.5%)
+	assert.Nil(t, alert.Run(frame(100, 0), t1))
+	assert.True(t, alert.Result().IsOn)
+
+	// 30 errors out of 900 requests, the alert is switched off
+	assert.Nil(t, alert.Run(frame(500, 0), t1))
+	res = alert.Result()
+	assert.False(t, res.IsOn)
+	assert.Equal(t, task.Inactive, res.Status)
+	assert.Equal(t, now, res.Date)
+	assert.Equal(t, float64(0), res.Value)
+}
+
+func TestAlertWithoutDurationIsTriggeredImmediately(t *testing.T) {
+	alert, err := NewAlert("outage", "rate(all, 1m) < 1")
+	assert.Nil(t, err)
+	assert.Nil(t, alert.Init())
+
+	now := time.Now()
+	t1 := &timer.TimeStub{NowStub: func() time.Time { return now }}
+	idle := task.Metrics{Rates: task.Rates{Frame: task.FrameRates{Duration: 60}}}
+
+	assert.Nil(t, alert.Run(idle, t1))
+	assert.True(t, alert.Result().IsOn)
+	assert.NotNil(t, alert.Run(task.Rates{}, t1))
+	assert.NotNil(t, alert.Init(time.Second))
+}
+
+func TestParseAlertRequiresAName(t *testing.T) {
+	for _, spec := range []string{"error_rate > 5", ": error_rate > 5", "rate(status >= 500: 5m) > 1", "errors: error_rate >"} {
+		_, err := ParseAlert(spec)
+		assert.NotNil(t, err, spec)
+	}
+}
+
+func TestAlertManagerEvaluatesExpressionAlerts(t *testing.T) {
+	errors, err := ParseAlert("errors: error_rate > 5")
+	assert.Nil(t, err)
+	traffic, err := ParseAlert("req_rate: req_rate > 100")
+	assert.Nil(t, err)
+
+	manager := task.AlertManager{}
+	rules := []task.AlertRule{{Name: "req_rate", Metric: task.RequestRateMetric(), Duration: time.Minute, Threshold: 10}}
+
+	assert.NotNil(t, manager.Init(rules, []task.Alerter{errors, traffic}))
+	assert.Nil(t, manager.Init(rules, []task.Alerter{errors}))
+
+	now := time.Now()
+	t1 := &timer.TimeStub{NowStub: func() time.Time { return now }}
+	assert.Nil(t, manager.Run(task.Metrics{Codes: map[uint32]uint64{500: 1}}, t1))
+
+	res := manager.Result()
+	assert.Len(t, res, 2)
+	assert.Equal(t, "errors", res[1].Name)
+	assert.True(t, res[1].IsOn)
+}
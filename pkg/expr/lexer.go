@@ -0,0 +1,121 @@
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+	"unicode"
+)
+
+// tokenKind identifies a lexical token
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokDuration
+	tokIdent
+	tokString
+	tokOperator // > >= < <= == != + - * /
+	tokLParen
+	tokRParen
+	tokComma
+)
+
+// token is a lexical token, pos is its column in the expression (starting at 1)
+type token struct {
+	kind     tokenKind
+	text     string
+	number   float64
+	duration time.Duration
+	pos      int
+}
+
+func (t token) String() string {
+	if t.kind == tokEOF {
+		return "end of expression"
+	}
+	return strconv.Quote(t.text)
+}
+
+// lex splits src into tokens
+func lex(src string) ([]token, error) {
+	var tokens []token
+	runes := []rune(src)
+
+	for i := 0; i < len(runes); {
+		c := runes[i]
+		start := i
+
+		switch {
+		case unicode.IsSpace(c):
+			i++
+			continue
+
+		case c == '(':
+			tokens = append(tokens, token{kind: tokLParen, text: "(", pos: start + 1})
+			i++
+
+		case c == ')':
+			tokens = append(tokens, token{kind: tokRParen, text: ")", pos: start + 1})
+			i++
+
+		case c == ',':
+			tokens = append(tokens, token{kind: tokComma, text: ",", pos: start + 1})
+			i++
+
+		case c == '"':
+			i++
+			for i < len(runes) && runes[i] != '"' {
+				i++
+			}
+			if i == len(runes) {
+				return nil, fmt.Errorf("unterminated string at column %d", start+1)
+			}
+			i++
+			tokens = append(tokens, token{kind: tokString, text: string(runes[start+1 : i-1]), pos: start + 1})
+
+		case c == '>' || c == '<' || c == '=' || c == '!':
+			i++
+			if i < len(runes) && runes[i] == '=' {
+				i++
+			}
+			op := string(runes[start:i])
+			if op == "=" || op == "!" {
+				return nil, fmt.Errorf("unknown operator %q at column %d", op, start+1)
+			}
+			tokens = append(tokens, token{kind: tokOperator, text: op, pos: start + 1})
+
+		case c == '+' || c == '-' || c == '*' || c == '/':
+			tokens = append(tokens, token{kind: tokOperator, text: string(c), pos: start + 1})
+			i++
+
+		case unicode.IsDigit(c) || c == '.':
+			// Numbers (0.05) and durations (5m, 1h30m) are read as a whole
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || unicode.IsLetter(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			text := string(runes[start:i])
+			if n, err := strconv.ParseFloat(text, 64); err == nil {
+				tokens = append(tokens, token{kind: tokNumber, text: text, number: n, pos: start + 1})
+				break
+			}
+			d, err := time.ParseDuration(text)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number or duration %q at column %d", text, start+1)
+			}
+			tokens = append(tokens, token{kind: tokDuration, text: text, duration: d, pos: start + 1})
+
+		case unicode.IsLetter(c) || c == '_':
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: string(runes[start:i]), pos: start + 1})
+
+		default:
+			return nil, fmt.Errorf("unexpected character %q at column %d", c, start+1)
+		}
+	}
+
+	return append(tokens, token{kind: tokEOF, pos: len(runes) + 1}), nil
+}
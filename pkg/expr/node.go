@@ -0,0 +1,215 @@
+package expr
+
+import (
+	"math"
+	"time"
+
+	"github.com/Juli3nnicolas/http_log_monitor/pkg/task"
+)
+
+// frame is what an expression is evaluated against: a frame's measures and the
+// time they have been taken at
+type frame struct {
+	metrics *task.Metrics
+	now     time.Time
+}
+
+// seconds returns the frame's duration in seconds
+func (f *frame) seconds() float64 {
+	return float64(f.metrics.Rates.Frame.Duration)
+}
+
+// value is a numeric expression. Windowed functions record a sample each time
+// they are evaluated, every node is evaluated exactly once per frame.
+type value interface {
+	value(f *frame) float64
+}
+
+// condition is a boolean expression
+type condition interface {
+	holds(f *frame) bool
+}
+
+type number float64
+
+func (n number) value(*frame) float64 {
+	return float64(n)
+}
+
+// metricRef is a task.Metric's value for the frame (see task.ParseMetric)
+type metricRef struct {
+	metric task.Metric
+}
+
+func (m *metricRef) value(f *frame) float64 {
+	return m.metric.Value(f.metrics)
+}
+
+type negation struct {
+	operand value
+}
+
+func (n *negation) value(f *frame) float64 {
+	return -n.operand.value(f)
+}
+
+// arithmetic is a binary operation (+ - * /), divisions by 0 follow IEEE 754
+// (x/0 is infinite, 0/0 is NaN and never compares true)
+type arithmetic struct {
+	op          string
+	left, right value
+}
+
+func (a *arithmetic) value(f *frame) float64 {
+	l, r := a.left.value(f), a.right.value(f)
+	switch a.op {
+	case "+":
+		return l + r
+	case "-":
+		return l - r
+	case "*":
+		return l * r
+	}
+	return l / r
+}
+
+// selector counts the frame's requests matching a predicate
+type selector func(m *task.Metrics) uint64
+
+// sample is a value recorded for a frame
+type sample struct {
+	date    time.Time
+	value   float64
+	seconds float64 // duration of the frame the value has been measured on
+}
+
+// window keeps the samples recorded during the last d
+type window struct {
+	d       time.Duration
+	samples []sample
+}
+
+func (w *window) add(s sample) {
+	w.samples = append(w.samples, s)
+
+	// Samples are recorded in chronological order, drop the ones out of the window
+	limit := s.date.Add(-w.d)
+	i := 0
+	for i < len(w.samples) && !w.samples[i].date.After(limit) {
+		i++
+	}
+	w.samples = w.samples[i:]
+}
+
+// rate is the number of requests per second matching a selector over a window
+// (rate(status >= 500, 5m)). Until the window is full, the rate is measured on
+// the frames recorded so far.
+type rate struct {
+	selector selector
+	window   window
+}
+
+func (r *rate) value(f *frame) float64 {
+	r.window.add(sample{date: f.now, value: float64(r.selector(f.metrics)), seconds: f.seconds()})
+
+	var total, seconds float64
+	for _, s := range r.window.samples {
+		total += s.value
+		seconds += s.seconds
+	}
+
+	max := r.window.d.Seconds()
+	if seconds == 0 || seconds > max {
+		seconds = max
+	}
+	return total / seconds
+}
+
+// count is the number of requests matching a selector over a window (count(all, 1m))
+type count struct {
+	selector selector
+	window   window
+}
+
+func (c *count) value(f *frame) float64 {
+	c.window.add(sample{date: f.now, value: float64(c.selector(f.metrics))})
+
+	var total float64
+	for _, s := range c.window.samples {
+		total += s.value
+	}
+	return total
+}
+
+// aggregation is the average, the maximum or the minimum of a value over a window
+// (avg(latency_p99, 5m))
+type aggregation struct {
+	fn      string
+	operand value
+	window  window
+}
+
+func (a *aggregation) value(f *frame) float64 {
+	a.window.add(sample{date: f.now, value: a.operand.value(f)})
+
+	res := a.window.samples[0].value
+	for _, s := range a.window.samples[1:] {
+		switch a.fn {
+		case "avg":
+			res += s.value
+		case "max":
+			res = math.Max(res, s.value)
+		case "min":
+			res = math.Min(res, s.value)
+		}
+	}
+
+	if a.fn == "avg" {
+		res /= float64(len(a.window.samples))
+	}
+	return res
+}
+
+// comparison compares two values, it keeps the left one to be displayed
+type comparison struct {
+	op          string
+	left, right value
+	last        float64
+}
+
+func (c *comparison) holds(f *frame) bool {
+	l, r := c.left.value(f), c.right.value(f)
+	c.last = l
+	return compare(c.op, l, r)
+}
+
+func compare(op string, l, r float64) bool {
+	switch op {
+	case ">":
+		return l > r
+	case ">=":
+		return l >= r
+	case "<":
+		return l < r
+	case "<=":
+		return l <= r
+	case "==":
+		return l == r
+	}
+	return l != r
+}
+
+// logical is a conjunction (and) or a disjunction (or), both operands are always
+// evaluated so that their windows record every frame
+type logical struct {
+	and         bool
+	left, right condition
+}
+
+func (o *logical) holds(f *frame) bool {
+	l, r := o.left.holds(f), o.right.holds(f)
+	if o.and {
+		return l && r
+	}
+	return l || r
+}
@@ -0,0 +1,410 @@
+package expr
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Juli3nnicolas/http_log_monitor/pkg/task"
+)
+
+// Expr is a compiled alert expression such as
+//
+//	rate(status >= 500, 5m) / rate(all, 5m) > 0.05 for 2m
+//
+// The grammar is:
+//
+//	rule       := condition ["for" duration]
+//	condition  := comparison {("and" | "or") comparison}, "and" binds tighter
+//	comparison := arith (">" | ">=" | "<" | "<=" | "==" | "!=") arith
+//	arith      := operands joined by + - * / and parentheses, "-" negates
+//	operand    := number | metric | function
+//
+// Metrics are the names accepted by task.ParseMetric (latency_p99, error_rate...),
+// metric("section:/api") reads the ones that aren't identifiers. Functions are:
+//
+//	rate(selector, window)  : requests per second matching selector over window
+//	count(selector, window) : number of requests matching selector over window
+//	avg(arith, window), max(arith, window), min(arith, window) : aggregation over window
+//
+// Selectors are all, status <op> code, section == "/api" and method == "GET".
+// Expressions are stateful (windows keep the values of the previous frames), each
+// alert must compile its own.
+type Expr struct {
+	src       string
+	condition condition
+	// For is how long the condition must hold for the alert to be triggered, 0 if
+	// it is triggered as soon as it holds
+	For time.Duration
+}
+
+// Compile parses src. Errors give the column of the faulty token.
+func Compile(src string) (*Expr, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q - %v", src, err)
+	}
+
+	p := &parser{tokens: tokens}
+	e, err := p.parseRule()
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q - %v", src, err)
+	}
+	e.src = src
+
+	return e, nil
+}
+
+// String returns the expression's source
+func (e *Expr) String() string {
+	return e.src
+}
+
+// Eval evaluates the expression on a frame's measures taken at now, frames must be
+// evaluated in chronological order. It returns whether the condition holds and
+// the value of its left-most comparison's left side, the one compared to a threshold.
+func (e *Expr) Eval(m *task.Metrics, now time.Time) (bool, float64) {
+	f := &frame{metrics: m, now: now}
+	holds := e.condition.holds(f)
+	return holds, leftValue(e.condition)
+}
+
+func leftValue(c condition) float64 {
+	switch n := c.(type) {
+	case *comparison:
+		return n.last
+	case *logical:
+		return leftValue(n.left)
+	}
+	return 0
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) isKeyword(word string) bool {
+	t := p.peek()
+	return t.kind == tokIdent && t.text == word
+}
+
+func (p *parser) isOperator(ops ...string) bool {
+	t := p.peek()
+	return t.kind == tokOperator && isOneOf(t, ops...)
+}
+
+func isOneOf(t token, texts ...string) bool {
+	for _, text := range texts {
+		if t.text == text {
+			return true
+		}
+	}
+	return false
+}
+
+func unexpected(t token) error {
+	return fmt.Errorf("unexpected %s at column %d", t, t.pos)
+}
+
+func (p *parser) expect(kind tokenKind, what string) (token, error) {
+	t := p.next()
+	if t.kind != kind {
+		return t, fmt.Errorf("expected %s at column %d, got %s", what, t.pos, t)
+	}
+	return t, nil
+}
+
+func (p *parser) parseRule() (*Expr, error) {
+	c, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	e := &Expr{condition: c}
+
+	if p.isKeyword("for") {
+		p.next()
+		d, err := p.parseDuration()
+		if err != nil {
+			return nil, err
+		}
+		e.For = d
+	}
+
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, unexpected(t)
+	}
+	return e, nil
+}
+
+func (p *parser) parseOr() (condition, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isKeyword("or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logical{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (condition, error) {
+	left, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isKeyword("and") {
+		p.next()
+		right, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		left = &logical{and: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseComparison() (condition, error) {
+	left, err := p.parseArith()
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.isOperator(">", ">=", "<", "<=", "==", "!=") {
+		t := p.peek()
+		return nil, fmt.Errorf("expected a comparison operator at column %d, got %s", t.pos, t)
+	}
+	op := p.next().text
+
+	right, err := p.parseArith()
+	if err != nil {
+		return nil, err
+	}
+	return &comparison{op: op, left: left, right: right}, nil
+}
+
+func (p *parser) parseArith() (value, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isOperator("+", "-") {
+		op := p.next().text
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		left = &arithmetic{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseTerm() (value, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isOperator("*", "/") {
+		op := p.next().text
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &arithmetic{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (value, error) {
+	if p.isOperator("-") {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &negation{operand: operand}, nil
+	}
+	return p.parseOperand()
+}
+
+func (p *parser) parseOperand() (value, error) {
+	t := p.next()
+
+	switch t.kind {
+	case tokNumber:
+		return number(t.number), nil
+
+	case tokLParen:
+		v, err := p.parseArith()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokRParen, `")"`); err != nil {
+			return nil, err
+		}
+		return v, nil
+
+	case tokIdent:
+		if p.peek().kind == tokLParen {
+			return p.parseFunction(t)
+		}
+		return parseMetric(t, t.text)
+	}
+
+	return nil, unexpected(t)
+}
+
+func parseMetric(t token, name string) (value, error) {
+	metric, err := task.ParseMetric(name)
+	if err != nil {
+		return nil, fmt.Errorf("%v at column %d", err, t.pos)
+	}
+	return &metricRef{metric: metric}, nil
+}
+
+func (p *parser) parseFunction(name token) (value, error) {
+	p.next() // (
+
+	var v value
+	switch name.text {
+	case "metric":
+		t, err := p.expect(tokString, "a metric name")
+		if err != nil {
+			return nil, err
+		}
+		if v, err = parseMetric(t, t.text); err != nil {
+			return nil, err
+		}
+
+	case "rate", "count":
+		sel, err := p.parseSelector()
+		if err != nil {
+			return nil, err
+		}
+		w, err := p.parseWindow()
+		if err != nil {
+			return nil, err
+		}
+		if name.text == "rate" {
+			v = &rate{selector: sel, window: w}
+		} else {
+			v = &count{selector: sel, window: w}
+		}
+
+	case "avg", "max", "min":
+		operand, err := p.parseArith()
+		if err != nil {
+			return nil, err
+		}
+		w, err := p.parseWindow()
+		if err != nil {
+			return nil, err
+		}
+		v = &aggregation{fn: name.text, operand: operand, window: w}
+
+	default:
+		return nil, fmt.Errorf("unknown function %q at column %d", name.text, name.pos)
+	}
+
+	if _, err := p.expect(tokRParen, `")"`); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// parseWindow reads a function's window parameter: ", duration"
+func (p *parser) parseWindow() (window, error) {
+	if _, err := p.expect(tokComma, `","`); err != nil {
+		return window{}, err
+	}
+	d, err := p.parseDuration()
+	return window{d: d}, err
+}
+
+func (p *parser) parseDuration() (time.Duration, error) {
+	t, err := p.expect(tokDuration, "a duration")
+	if err != nil {
+		return 0, err
+	}
+	if t.duration <= 0 {
+		return 0, fmt.Errorf("the duration at column %d must be positive", t.pos)
+	}
+	return t.duration, nil
+}
+
+// parseSelector reads all, status <op> code, section == "/api" or method == "GET"
+func (p *parser) parseSelector() (selector, error) {
+	field, err := p.expect(tokIdent, "a selector")
+	if err != nil {
+		return nil, err
+	}
+	if field.text == "all" {
+		return func(m *task.Metrics) uint64 { return m.NbRequests() }, nil
+	}
+
+	op := p.next()
+	if op.kind != tokOperator {
+		return nil, fmt.Errorf("expected a comparison operator at column %d, got %s", op.pos, op)
+	}
+
+	switch field.text {
+	case "status":
+		if !isOneOf(op, ">", ">=", "<", "<=", "==", "!=") {
+			return nil, unexpected(op)
+		}
+		code, err := p.expect(tokNumber, "an HTTP code")
+		if err != nil {
+			return nil, err
+		}
+		return func(m *task.Metrics) uint64 {
+			var total uint64
+			for c, n := range m.Codes {
+				if compare(op.text, float64(c), code.number) {
+					total += n
+				}
+			}
+			return total
+		}, nil
+
+	case "section", "method":
+		if op.text != "==" {
+			return nil, fmt.Errorf("only == is supported for %s at column %d", field.text, op.pos)
+		}
+		s, err := p.expect(tokString, "a quoted "+field.text)
+		if err != nil {
+			return nil, err
+		}
+		if field.text == "section" {
+			return func(m *task.Metrics) uint64 { return m.SectionHits(s.text) }, nil
+		}
+		return func(m *task.Metrics) uint64 {
+			var total uint64
+			for i := range m.Hits {
+				total += m.Hits[i].Methods[s.text]
+			}
+			return total
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unknown selector %q at column %d - it must be all, status, section or method", field.text, field.pos)
+}
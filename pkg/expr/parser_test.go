@@ -0,0 +1,132 @@
+package expr
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/Juli3nnicolas/http_log_monitor/pkg/task"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompileReportsTheFaultyColumn(t *testing.T) {
+	errors := map[string]string{
+		"":                               "end of expression",
+		"error_rate":                     "expected a comparison operator at column 11",
+		"error_rate > ":                  "unexpected end of expression at column 14",
+		"error_rate >> 5":                "unexpected \">\" at column 13",
+		"error_rate = 5":                 "unknown operator \"=\" at column 12",
+		"foo > 5":                        "unknown metric \"foo\" at column 1",
+		"rate(all) > 5":                  "expected \",\" at column 9",
+		"rate(all, 5) > 5":               "expected a duration at column 11, got \"5\"",
+		"rate(all, 5m > 5":               "expected \")\" at column 14",
+		"rate(color == 2, 5m) > 5":       "unknown selector \"color\" at column 6",
+		"rate(section > \"/a\", 5m) > 5": "only == is supported for section at column 14",
+		"sum(all, 5m) > 5":               "unknown function \"sum\" at column 1",
+		"error_rate > 5 for":             "expected a duration at column 19",
+		"error_rate > 5 for 2m and":      "unexpected \"and\" at column 23",
+		"metric(\"status:abc\") > 1":     "the status must be an HTTP code at column 8",
+		"error_rate > 5 # comment":       "unexpected character '#' at column 16",
+		"method == \"GET\"":              "unknown metric \"method\"",
+		"rate(method == \"GET, 5m) > 1":  "unterminated string at column 16",
+	}
+
+	for src, msg := range errors {
+		_, err := Compile(src)
+		if assert.NotNil(t, err, src) {
+			assert.Contains(t, err.Error(), msg, src)
+		}
+	}
+}
+
+func TestEvalComputesArithmeticAndLogic(t *testing.T) {
+	// Setup stage
+	m := &task.Metrics{
+		Latency: task.Latency{Frame: task.LatencyStats{P99: 300 * time.Millisecond}},
+		Codes:   map[uint32]uint64{200: 90, 503: 10},
+		Hits:    []task.Hit{{Section: "/api", Total: 60, Methods: map[string]uint64{"GET": 40, "POST": 20}}},
+	}
+
+	values := map[string]float64{
+		"-2 + 3 * 4 - (1 + 1) / 2 > 0":                 9,
+		"latency_p99 / 1000 >= 0":                      0.3,
+		"error_rate == 10":                             10,
+		"metric(\"status:503\") == 10":                 10,
+		"count(all, 1m) > 0":                           100,
+		"count(status >= 500, 1m) > 0":                 10,
+		"count(status != 200, 1m) > 0":                 10,
+		"count(section == \"/api\", 1m) > 0":           60,
+		"count(method == \"POST\", 1m) > 0":            20,
+		"max(latency_p99, 1m) > 0 and error_rate > 50": 300,
+	}
+
+	for src, expected := range values {
+		e, err := Compile(src)
+		if !assert.Nil(t, err, src) {
+			continue
+		}
+
+		// Exercise stage
+		_, value := e.Eval(m, time.Now())
+
+		// Validation stage
+		assert.Equal(t, expected, value, src)
+	}
+
+	e, _ := Compile("error_rate > 50 or latency_p99 > 200 and error_rate > 5")
+	holds, _ := e.Eval(m, time.Now())
+	assert.True(t, holds)
+
+	e, _ = Compile("count(status >= 500, 1m) / count(method == \"PUT\", 1m) > 1")
+	holds, value := e.Eval(m, time.Now())
+	assert.True(t, holds)
+	assert.True(t, math.IsInf(value, 1))
+
+	e, _ = Compile("count(method == \"PUT\", 1m) / count(method == \"PUT\", 1m) < 1")
+	holds, _ = e.Eval(m, time.Now())
+	assert.False(t, holds) // NaN never compares true
+}
+
+func TestWindowsKeepTheFramesOfTheirDuration(t *testing.T) {
+	// Setup stage - 10s frames
+	start := time.Date(2020, 2, 9, 16, 27, 0, 0, time.UTC)
+	frame := func(ok, errors uint64, p99 time.Duration) *task.Metrics {
+		return &task.Metrics{
+			Rates:   task.Rates{Frame: task.FrameRates{Duration: 10}},
+			Codes:   map[uint32]uint64{200: ok, 500: errors},
+			Latency: task.Latency{Frame: task.LatencyStats{P99: p99}},
+		}
+	}
+
+	rate, err := Compile("rate(status >= 500, 30s) > 0")
+	assert.Nil(t, err)
+	avg, err := Compile("avg(latency_p99, 20s) > 0")
+	assert.Nil(t, err)
+	min, err := Compile("min(latency_p99, 20s) > 0")
+	assert.Nil(t, err)
+
+	frames := []*task.Metrics{
+		frame(10, 10, 100*time.Millisecond),
+		frame(10, 20, 200*time.Millisecond),
+		frame(10, 30, 600*time.Millisecond),
+		frame(10, 0, 100*time.Millisecond),
+	}
+	expectedRates := []float64{1, 1.5, 2, 50.0 / 30}
+	expectedAvgs := []float64{100, 150, 400, 350}
+	expectedMins := []float64{100, 100, 200, 100}
+
+	for i, m := range frames {
+		now := start.Add(time.Duration(i) * 10 * time.Second)
+
+		// Exercise stage
+		_, r := rate.Eval(m, now)
+		_, a := avg.Eval(m, now)
+		_, mn := min.Eval(m, now)
+
+		// Validation stage
+		assert.InDelta(t, expectedRates[i], r, 1e-9, "frame %d", i)
+		assert.InDelta(t, expectedAvgs[i], a, 1e-9, "frame %d", i)
+		assert.InDelta(t, expectedMins[i], mn, 1e-9, "frame %d", i)
+	}
+}
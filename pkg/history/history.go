@@ -0,0 +1,307 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"time"
+
+	"github.com/Juli3nnicolas/http_log_monitor/pkg/task"
+)
+
+// DefaultMaxIncidents is the default number of incidents kept in memory
+const DefaultMaxIncidents int = 100
+
+// EventKind is the kind of an alert's transition
+type EventKind string
+
+const (
+	// Fired is the transition of an alert switched on
+	Fired EventKind = "fire"
+	// Resolved is the transition of an alert switched off
+	Resolved EventKind = "resolve"
+)
+
+// Event is an alert's transition, the history's log file holds an event per line (JSON)
+type Event struct {
+	Alert    string    `json:"alert"`
+	Kind     EventKind `json:"event"`
+	Time     time.Time `json:"time"`
+	Value    float64   `json:"value"`                // value the alert fired at
+	Unit     string    `json:"unit,omitempty"`       // unit of the values, empty for expressions
+	Peak     float64   `json:"peak,omitempty"`       // most extreme value while firing, set on resolve
+	Duration float64   `json:"duration_s,omitempty"` // time spent firing in seconds, set on resolve
+}
+
+// Incident is the time an alert has fired
+type Incident struct {
+	Alert    string
+	Start    time.Time     // time the alert fired
+	End      time.Time     // time the alert resolved, zero if it hasn't
+	Value    float64       // value the alert fired at
+	Unit     string        // unit of the values, empty for expressions
+	Peak     float64       // most extreme value while firing (the lowest for task.Below alerts)
+	Duration time.Duration // time spent firing, 0 until the alert resolves
+	// Ongoing is true while the alert fires. An incident that is neither ongoing nor
+	// resolved was still open when the app stopped.
+	Ongoing bool
+}
+
+// IsResolved returns true if the alert has resolved
+func (o *Incident) IsResolved() bool {
+	return !o.End.IsZero()
+}
+
+// AlertHistory is a task recording every alert's transitions. Transitions are
+// appended to a JSON-lines file so that the incidents survive restarts.
+type AlertHistory struct {
+	file      *os.File
+	max       int
+	incidents []*Incident          // oldest first
+	open      map[string]*Incident // ongoing incidents by alert name
+	below     map[string]bool      // alerts whose peak is the lowest value
+	done      bool
+}
+
+// Init loads the past incidents and opens the log file, it accepts optional parameters :
+// - path string : JSON-lines file the transitions are appended to, nothing is persisted if empty
+// - max int : number of incidents kept in memory (DefaultMaxIncidents by default)
+func (o *AlertHistory) Init(args ...interface{}) error {
+	if len(args) > 2 {
+		return fmt.Errorf("wrong parameters - in order optional parameters must be (path string, max int)")
+	}
+
+	path := ""
+	if len(args) >= 1 {
+		var ok bool
+		path, ok = args[0].(string)
+		if !ok {
+			return fmt.Errorf("type error - got %T instead of string", args[0])
+		}
+	}
+
+	max := DefaultMaxIncidents
+	if len(args) == 2 {
+		var ok bool
+		max, ok = args[1].(int)
+		if !ok {
+			return fmt.Errorf("type error - got %T instead of int", args[1])
+		}
+		if max <= 0 {
+			return fmt.Errorf("wrong parameters - the number of incidents must be greater than 0, got %d", max)
+		}
+	}
+
+	o.max = max
+	o.incidents = nil
+	o.open = make(map[string]*Incident)
+	o.below = make(map[string]bool)
+
+	if path == "" {
+		return nil
+	}
+
+	if err := o.load(path); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	o.file = f
+
+	return nil
+}
+
+// load reads the incidents recorded in path, if it exists
+func (o *AlertHistory) load(path string) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// Incidents still open when the app stopped are never resolved
+	open := make(map[string]*Incident)
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e Event
+		// A line is truncated if the app was killed while writing it, skip it
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+
+		switch e.Kind {
+		case Fired:
+			inc := &Incident{Alert: e.Alert, Start: e.Time, Value: e.Value, Unit: e.Unit, Peak: e.Value}
+			open[e.Alert] = inc
+			o.append(inc)
+
+		case Resolved:
+			if inc, ok := open[e.Alert]; ok {
+				inc.End = e.Time
+				inc.Peak = e.Peak
+				inc.Duration = time.Duration(e.Duration * float64(time.Second))
+				delete(open, e.Alert)
+			}
+		}
+	}
+
+	return scanner.Err()
+}
+
+// BeforeRun flags the task as not done
+func (o *AlertHistory) BeforeRun(...interface{}) error {
+	o.done = false
+	return nil
+}
+
+// Run records the alerts' transitions since the last run. It takes one parameter :
+// - alerts []task.AlertState : the alerts' current states (see task.AlertManager)
+func (o *AlertHistory) Run(args ...interface{}) error {
+	if len(args) != 1 {
+		return fmt.Errorf("wrong parameters - this function expects an []task.AlertState parameter")
+	}
+
+	alerts, ok := args[0].([]task.AlertState)
+	if !ok {
+		return fmt.Errorf("type error - got %T instead of []task.AlertState", args[0])
+	}
+
+	if o.open == nil {
+		if err := o.Init(); err != nil {
+			return err
+		}
+	}
+
+	for i := range alerts {
+		if err := o.record(&alerts[i]); err != nil {
+			return err
+		}
+	}
+	o.done = true
+
+	return nil
+}
+
+// record compares alert to its ongoing incident
+func (o *AlertHistory) record(alert *task.AlertState) error {
+	inc, firing := o.open[alert.Name]
+
+	switch {
+	case alert.IsOn && !firing:
+		inc = &Incident{
+			Alert:   alert.Name,
+			Start:   alert.Date,
+			Value:   alert.Value,
+			Unit:    alert.Unit,
+			Peak:    alert.Value,
+			Ongoing: true,
+		}
+		o.open[alert.Name] = inc
+		o.below[alert.Name] = alert.Comparison == task.Below
+		o.append(inc)
+
+		return o.write(Event{Alert: inc.Alert, Kind: Fired, Time: inc.Start, Value: inc.Value, Unit: inc.Unit})
+
+	case alert.IsOn && firing:
+		if o.below[alert.Name] {
+			inc.Peak = math.Min(inc.Peak, alert.Current)
+		} else {
+			inc.Peak = math.Max(inc.Peak, alert.Current)
+		}
+
+	case !alert.IsOn && firing:
+		inc.End = alert.Date
+		inc.Duration = inc.End.Sub(inc.Start)
+		inc.Ongoing = false
+		delete(o.open, alert.Name)
+
+		return o.write(Event{
+			Alert:    inc.Alert,
+			Kind:     Resolved,
+			Time:     inc.End,
+			Value:    inc.Value,
+			Unit:     inc.Unit,
+			Peak:     inc.Peak,
+			Duration: inc.Duration.Seconds(),
+		})
+	}
+
+	return nil
+}
+
+// append adds inc to the incidents, the oldest ones are dropped past the maximum
+func (o *AlertHistory) append(inc *Incident) {
+	o.incidents = append(o.incidents, inc)
+	if len(o.incidents) > o.max {
+		o.incidents = o.incidents[len(o.incidents)-o.max:]
+	}
+}
+
+// write appends e to the log file, if any
+func (o *AlertHistory) write(e Event) error {
+	if o.file == nil {
+		return nil
+	}
+
+	// JSON has no infinity, a change from 0 is recorded as the greatest value
+	e.Value = finite(e.Value)
+	e.Peak = finite(e.Peak)
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	_, err = o.file.Write(append(line, '\n'))
+	return err
+}
+
+func finite(v float64) float64 {
+	switch {
+	case math.IsInf(v, 1):
+		return math.MaxFloat64
+	case math.IsInf(v, -1):
+		return -math.MaxFloat64
+	case math.IsNaN(v):
+		return 0
+	}
+	return v
+}
+
+// AfterRun does nothing, implements the Task interface
+func (o *AlertHistory) AfterRun() error {
+	return nil
+}
+
+// Result returns a copy of the incidents, the latest first
+func (o *AlertHistory) Result() []Incident {
+	incidents := make([]Incident, len(o.incidents))
+	for i, inc := range o.incidents {
+		incidents[len(o.incidents)-1-i] = *inc
+	}
+	return incidents
+}
+
+// IsDone is true once the transitions of the current frame have been recorded
+func (o *AlertHistory) IsDone() bool {
+	return o.done
+}
+
+// Close closes the log file and wipes the history. Call Init to use it again.
+func (o *AlertHistory) Close() error {
+	var err error
+	if o.file != nil {
+		err = o.file.Close()
+	}
+	*o = AlertHistory{}
+	return err
+}
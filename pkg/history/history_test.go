@@ -0,0 +1,171 @@
+package history
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Juli3nnicolas/http_log_monitor/pkg/task"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunRecordsTheAlertsTransitions(t *testing.T) {
+	// Setup stage
+	dir, err := ioutil.TempDir("", "history_test")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "alerts.jsonl")
+
+	h := AlertHistory{}
+	if err := h.Init(path); err != nil {
+		panic(err)
+	}
+
+	fired := time.Date(2020, 2, 9, 16, 27, 0, 0, time.UTC)
+	resolved := fired.Add(8 * time.Minute)
+	alert := func(isOn bool, date time.Time, value, current float64) []task.AlertState {
+		return []task.AlertState{{Name: "traffic", Unit: "req/s", IsOn: isOn, Date: date, Value: value, Current: current}}
+	}
+
+	// Exercise stage
+	assert.Nil(t, h.Run(alert(false, time.Time{}, 0, 3)))
+	assert.Empty(t, h.Result())
+
+	assert.Nil(t, h.Run(alert(true, fired, 12, 12)))
+	assert.Nil(t, h.Run(alert(true, fired, 12, 20)))
+	assert.Nil(t, h.Run(alert(true, fired, 12, 15)))
+
+	res := h.Result()
+	assert.Len(t, res, 1)
+	assert.True(t, res[0].Ongoing)
+	assert.False(t, res[0].IsResolved())
+	assert.Equal(t, float64(20), res[0].Peak)
+
+	assert.Nil(t, h.Run(alert(false, resolved, 0, 4)))
+	assert.Nil(t, h.Close())
+
+	// Validation stage
+	expected := Incident{
+		Alert:    "traffic",
+		Start:    fired,
+		End:      resolved,
+		Value:    12,
+		Unit:     "req/s",
+		Peak:     20,
+		Duration: 8 * time.Minute,
+	}
+
+	data, err := ioutil.ReadFile(path)
+	assert.Nil(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	assert.Equal(t, []string{
+		`{"alert":"traffic","event":"fire","time":"2020-02-09T16:27:00Z","value":12,"unit":"req/s"}`,
+		`{"alert":"traffic","event":"resolve","time":"2020-02-09T16:35:00Z","value":12,"unit":"req/s","peak":20,"duration_s":480}`,
+	}, lines)
+
+	// The incidents survive a restart
+	if err := h.Init(path); err != nil {
+		panic(err)
+	}
+	defer h.Close()
+	res = h.Result()
+	assert.Len(t, res, 1)
+	assert.Equal(t, expected.Start, res[0].Start.UTC())
+	assert.Equal(t, expected.End, res[0].End.UTC())
+	res[0].Start, res[0].End = expected.Start, expected.End
+	assert.Equal(t, expected, res[0])
+}
+
+func TestInitLoadsIncidentsLeftOpenAsUnresolved(t *testing.T) {
+	// Setup stage - the last line has been truncated
+	f, err := ioutil.TempFile("", "history_test")
+	if err != nil {
+		panic(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString(`{"alert":"slow","event":"fire","time":"2020-02-09T16:27:00Z","value":300,"unit":"ms"}` + "\n" +
+		`{"alert":"slow","event":"reso`)
+	f.Close()
+
+	// Exercise stage
+	h := AlertHistory{}
+	err = h.Init(f.Name())
+	defer h.Close()
+
+	// Validation stage
+	assert.Nil(t, err)
+	res := h.Result()
+	assert.Len(t, res, 1)
+	assert.Equal(t, "slow", res[0].Alert)
+	assert.False(t, res[0].Ongoing)
+	assert.False(t, res[0].IsResolved())
+
+	// The alert firing again opens a new incident
+	date := time.Date(2020, 2, 9, 17, 0, 0, 0, time.UTC)
+	assert.Nil(t, h.Run([]task.AlertState{{Name: "slow", IsOn: true, Date: date, Value: 280}}))
+	res = h.Result()
+	assert.Len(t, res, 2)
+	assert.True(t, res[0].Ongoing)
+	assert.Equal(t, date, res[0].Start)
+}
+
+func TestRunKeepsTheLatestIncidentsAndTracksBelowPeaks(t *testing.T) {
+	// Setup stage - nothing is persisted, 2 incidents are kept
+	h := AlertHistory{}
+	if err := h.Init("", 2); err != nil {
+		panic(err)
+	}
+
+	start := time.Date(2020, 2, 9, 16, 27, 0, 0, time.UTC)
+	outage := func(isOn bool, minutes int, current float64) []task.AlertState {
+		return []task.AlertState{{
+			Name:       "outage",
+			Comparison: task.Below,
+			IsOn:       isOn,
+			Date:       start.Add(time.Duration(minutes) * time.Minute),
+			Value:      0.5,
+			Current:    current,
+		}}
+	}
+
+	// Exercise stage
+	assert.Nil(t, h.Run(outage(true, 0, 0.5)))
+	assert.Nil(t, h.Run(outage(true, 0, 0.1)))
+	assert.Nil(t, h.Run(outage(false, 2, 3)))
+	assert.Nil(t, h.Run(outage(true, 5, 0.5)))
+	assert.Nil(t, h.Run(outage(false, 6, 3)))
+	assert.Nil(t, h.Run(outage(true, 10, 0.2)))
+
+	// Validation stage
+	res := h.Result()
+	assert.Len(t, res, 2)
+	assert.Equal(t, start.Add(10*time.Minute), res[0].Start)
+	assert.True(t, res[0].Ongoing)
+	assert.Equal(t, start.Add(5*time.Minute), res[1].Start)
+	assert.Equal(t, time.Minute, res[1].Duration)
+
+	h.Close()
+	if err := h.Init("", 2); err != nil {
+		panic(err)
+	}
+	assert.Nil(t, h.Run(outage(true, 0, 0.5)))
+	assert.Nil(t, h.Run(outage(true, 0, 0.1)))
+	assert.Nil(t, h.Run(outage(true, 0, 0.3)))
+	assert.Equal(t, 0.1, h.Result()[0].Peak)
+}
+
+func TestInitReturnsAnErrorOnInvalidParameters(t *testing.T) {
+	h := AlertHistory{}
+
+	assert.NotNil(t, h.Init(42))
+	assert.NotNil(t, h.Init("", "10"))
+	assert.NotNil(t, h.Init("", 0))
+	assert.NotNil(t, h.Run([]task.AlertState{}, 42))
+	assert.NotNil(t, h.Run(task.AlertState{}))
+}
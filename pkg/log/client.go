@@ -0,0 +1,105 @@
+package log
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// setHost sets the client's host and parses its IP address. IPv6 addresses can
+// be bracketed ([2001:db8::1]) and hosts can be followed by a port
+// (172.17.0.1:2817, [2001:db8::1]:2817), the port is dropped.
+func (o *Info) setHost(field string) {
+	o.Host = parseHost(field)
+	o.ClientIP = net.ParseIP(o.Host)
+}
+
+func parseHost(field string) string {
+	if strings.HasPrefix(field, "[") {
+		if end := strings.IndexByte(field, ']'); end > 0 {
+			return field[1:end]
+		}
+		return field
+	}
+
+	// A single colon separates a port, IPv6 addresses have several
+	if i := strings.IndexByte(field, ':'); i >= 0 && strings.LastIndexByte(field, ':') == i {
+		return field[:i]
+	}
+	return field
+}
+
+// TrustedProxies are the networks of the proxies whose X-Forwarded-For header
+// is trusted to give the real client's address
+type TrustedProxies []*net.IPNet
+
+// ParseTrustedProxies reads a list of CIDRs (10.0.0.0/8, 2001:db8::/32) or IP addresses
+func ParseTrustedProxies(specs []string) (TrustedProxies, error) {
+	proxies := make(TrustedProxies, 0, len(specs))
+
+	for _, spec := range specs {
+		spec = strings.TrimSpace(spec)
+		if !strings.Contains(spec, "/") {
+			ip := net.ParseIP(spec)
+			if ip == nil {
+				return nil, fmt.Errorf("log.ParseTrustedProxies error - invalid IP address %q", spec)
+			}
+
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			proxies = append(proxies, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(spec)
+		if err != nil {
+			return nil, fmt.Errorf("log.ParseTrustedProxies error - %v", err)
+		}
+		proxies = append(proxies, network)
+	}
+
+	return proxies, nil
+}
+
+// Contains returns true if ip is a trusted proxy's address
+func (t TrustedProxies) Contains(ip net.IP) bool {
+	for _, network := range t {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveClient replaces the client of a request sent by a trusted proxy by the
+// one given by the X-Forwarded-For header. Each proxy appends the address it
+// received the request from to the header so it is read from right to left,
+// the first untrusted address is the client's. If all the addresses are trusted,
+// the leftmost one is the client's.
+func (t TrustedProxies) ResolveClient(info *Info) {
+	if info.ClientIP == nil || info.ForwardedFor == "" || !t.Contains(info.ClientIP) {
+		return
+	}
+
+	var client net.IP
+	hops := strings.Split(info.ForwardedFor, ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := net.ParseIP(parseHost(strings.TrimSpace(hops[i])))
+		if ip == nil {
+			// The header has been tampered with, don't go any further
+			break
+		}
+
+		client = ip
+		if !t.Contains(ip) {
+			break
+		}
+	}
+
+	if client != nil {
+		info.Host = client.String()
+		info.ClientIP = client
+	}
+}
@@ -0,0 +1,101 @@
+package log
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseHostSupportsBracketedIPv6AndPorts(t *testing.T) {
+	hosts := map[string]string{
+		"172.17.0.1":          "172.17.0.1",
+		"172.17.0.1:2817":     "172.17.0.1",
+		"2001:db8::1":         "2001:db8::1",
+		"[2001:db8::1]":       "2001:db8::1",
+		"[2001:db8::1]:2817":  "2001:db8::1",
+		"proxy.example.com":   "proxy.example.com",
+		"proxy.example.com:8": "proxy.example.com",
+	}
+
+	for field, expected := range hosts {
+		assert.Equal(t, expected, parseHost(field), field)
+	}
+}
+
+func TestParseFillsTheClientIP(t *testing.T) {
+	info, err := Parse(`[2001:db8::1] - - [09/Feb/2020:16:27:00 +0000] "GET / HTTP/1.1" 200 612`)
+	assert.Nil(t, err)
+	assert.Equal(t, "2001:db8::1", info.Host)
+	assert.Equal(t, net.ParseIP("2001:db8::1"), info.ClientIP)
+
+	info, err = Parse(`localhost - - [09/Feb/2020:16:27:00 +0000] "GET / HTTP/1.1" 200 612`)
+	assert.Nil(t, err)
+	assert.Equal(t, "localhost", info.Host)
+	assert.Nil(t, info.ClientIP)
+}
+
+func TestFormatsReadTheForwardedForHeader(t *testing.T) {
+	f, err := NewFormat(`$remote_addr [$time_local] "$request" $status "$http_x_forwarded_for"`)
+	assert.Nil(t, err)
+	info, err := f.Parse([]byte(`10.0.0.2 [09/Feb/2020:16:27:00 +0000] "GET / HTTP/1.1" 200 "203.0.113.7, 10.0.0.1"`))
+	assert.Nil(t, err)
+	assert.Equal(t, "203.0.113.7, 10.0.0.1", info.ForwardedFor)
+
+	fields, _ := JSONPreset("caddy")
+	info, err = NewJSONFormat(fields).Parse([]byte(`{"request":{"remote_ip":"10.0.0.2","uri":"/","headers":{"X-Forwarded-For":["203.0.113.7"]}},"status":200}`))
+	assert.Nil(t, err)
+	assert.Equal(t, "203.0.113.7", info.ForwardedFor)
+
+	w := NewW3C()
+	_, err = w.Parse([]byte("#Fields: date time c-ip cs-uri-stem sc-status cs(X-Forwarded-For)"))
+	assert.Equal(t, ErrDirective, err)
+	info, err = w.Parse([]byte("2020-02-09 16:27:00 10.0.0.2 / 200 203.0.113.7"))
+	assert.Nil(t, err)
+	assert.Equal(t, "203.0.113.7", info.ForwardedFor)
+	assert.Equal(t, time.Date(2020, 2, 9, 16, 27, 0, 0, time.UTC), info.LocalTime)
+}
+
+func TestResolveClientReadsForwardedForFromTrustedProxies(t *testing.T) {
+	// Setup stage
+	proxies, err := ParseTrustedProxies([]string{"10.0.0.0/8", "2001:db8::/32", "192.168.1.1"})
+	assert.Nil(t, err)
+
+	cases := []struct {
+		host         string
+		forwardedFor string
+		expected     string
+	}{
+		// Sent by a trusted proxy, the first untrusted address from the right is the client
+		{"10.0.0.2", "198.51.100.1, 203.0.113.7, 10.0.0.1", "203.0.113.7"},
+		{"[2001:db8::2]", "[2001:db8:ffff::1]:1234", "2001:db8:ffff::1"},
+		// All addresses are trusted, the leftmost is the client
+		{"10.0.0.2", "192.168.1.1, 10.0.0.1", "192.168.1.1"},
+		// Invalid addresses stop the walk
+		{"10.0.0.2", "203.0.113.7, unknown, 10.0.0.1", "10.0.0.1"},
+		{"10.0.0.2", "garbage", "10.0.0.2"},
+		// Sent by an untrusted client, the header could be forged
+		{"203.0.113.9", "198.51.100.1", "203.0.113.9"},
+		{"10.0.0.2", "", "10.0.0.2"},
+	}
+
+	for _, c := range cases {
+		info := Info{ForwardedFor: c.forwardedFor}
+		info.setHost(c.host)
+
+		// Exercise stage
+		proxies.ResolveClient(&info)
+
+		// Validation stage
+		assert.Equal(t, c.expected, info.Host, c.forwardedFor)
+		assert.Equal(t, net.ParseIP(c.expected), info.ClientIP, c.forwardedFor)
+	}
+}
+
+func TestParseTrustedProxiesReturnsAnErrorOnInvalidNetworks(t *testing.T) {
+	for _, spec := range []string{"10.0.0.0/33", "proxy.example.com", "10.0.0"} {
+		_, err := ParseTrustedProxies([]string{spec})
+		assert.NotNil(t, err, spec)
+	}
+}
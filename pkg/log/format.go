@@ -0,0 +1,776 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format is a compiled custom log format. It is created from an nginx
+// log_format string or an Apache LogFormat string and is able to parse
+// lines following it. Formats are read-only once compiled so they can be
+// shared between goroutines.
+//
+// Example :
+// f, err := NewFormat(`$remote_addr - $remote_user [$time_local] "$request" $status $body_bytes_sent`)
+// info, err := f.Parse([]byte(`172.17.0.1 - - [09/Feb/2020:16:27:00 +0000] "GET / HTTP/1.1" 200 612`))
+//
+// Variables or directives that don't match any Info field are read and ignored.
+type Format struct {
+	items []formatItem
+	// fields the format doesn't provide
+	missing Field
+}
+
+// formatField identifies the Info field a variable or a directive is read into
+type formatField uint8
+
+const (
+	fieldLiteral formatField = iota // not a field, a literal text
+	fieldIgnored
+	fieldHost
+	fieldAuthUser
+	fieldTimeLocal   // common log format time (09/Feb/2020:16:27:00 +0000)
+	fieldTimeISO8601 // RFC3339 time
+	fieldTimeSec     // seconds since epoch, can have a fractional part
+	fieldTimeMsec    // milliseconds since epoch
+	fieldTimeUsec    // microseconds since epoch
+	fieldRequest     // request line (GET / HTTP/1.1)
+	fieldMethod
+	fieldPath  // route without query string
+	fieldQuery // query string, with or without the leading "?"
+	fieldURI   // route with query string
+	fieldProtocol
+	fieldStatus
+	fieldSize
+	fieldReferer
+	fieldUserAgent
+	fieldForwardedFor // X-Forwarded-For header
+	fieldDurationSec  // request duration in seconds, can have a fractional part
+	fieldDurationMsec // request duration in milliseconds
+	fieldDurationUsec // request duration in microseconds
+	nbFormatFields
+)
+
+// formatItem is either a literal text or a field
+type formatItem struct {
+	literal []byte
+	field   formatField
+	// quoted is true if the field is enclosed in double-quotes, it can then
+	// contain escaped characters
+	quoted bool
+}
+
+// nginxVariables maps nginx variables to Info fields
+var nginxVariables = map[string]formatField{
+	"remote_addr":          fieldHost,
+	"remote_user":          fieldAuthUser,
+	"time_local":           fieldTimeLocal,
+	"time_iso8601":         fieldTimeISO8601,
+	"msec":                 fieldTimeSec,
+	"request":              fieldRequest,
+	"request_method":       fieldMethod,
+	"uri":                  fieldPath,
+	"document_uri":         fieldPath,
+	"args":                 fieldQuery,
+	"query_string":         fieldQuery,
+	"request_uri":          fieldURI,
+	"server_protocol":      fieldProtocol,
+	"status":               fieldStatus,
+	"body_bytes_sent":      fieldSize,
+	"bytes_sent":           fieldSize,
+	"http_referer":         fieldReferer,
+	"http_user_agent":      fieldUserAgent,
+	"http_x_forwarded_for": fieldForwardedFor,
+	"request_time":         fieldDurationSec,
+}
+
+// apacheDirectives maps Apache's LogFormat directives (without their
+// modifiers) to Info fields
+var apacheDirectives = map[byte]formatField{
+	'h': fieldHost,
+	'a': fieldHost,
+	'u': fieldAuthUser,
+	't': fieldTimeLocal,
+	'r': fieldRequest,
+	'm': fieldMethod,
+	'U': fieldPath,
+	'q': fieldQuery,
+	'H': fieldProtocol,
+	's': fieldStatus,
+	'b': fieldSize,
+	'B': fieldSize,
+	'D': fieldDurationUsec,
+	'T': fieldDurationSec,
+}
+
+// apacheTimeFormats maps %{format}t directives to Info fields
+var apacheTimeFormats = map[string]formatField{
+	"sec":  fieldTimeSec,
+	"msec": fieldTimeMsec,
+	"usec": fieldTimeUsec,
+}
+
+// apacheDurationUnits maps %{unit}T directives to Info fields
+var apacheDurationUnits = map[string]formatField{
+	"s":  fieldDurationSec,
+	"ms": fieldDurationMsec,
+	"us": fieldDurationUsec,
+}
+
+// apacheHeaders maps %{header}i directives (lower case) to Info fields
+var apacheHeaders = map[string]formatField{
+	"referer":         fieldReferer,
+	"user-agent":      fieldUserAgent,
+	"x-forwarded-for": fieldForwardedFor,
+}
+
+// NewFormat compiles an nginx log_format string or an Apache LogFormat string.
+// Whole configuration directives are accepted as well, such as :
+// log_format main '$remote_addr - $remote_user [$time_local] "$request"';
+// LogFormat "%h %l %u %t \"%r\" %>s %b" common
+func NewFormat(spec string) (*Format, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, fmt.Errorf("log.NewFormat error - empty format")
+	}
+
+	var (
+		items []formatItem
+		err   error
+	)
+
+	switch {
+	case strings.HasPrefix(spec, "log_format "):
+		if spec, err = unwrapNginxDirective(spec); err != nil {
+			return nil, err
+		}
+		items, err = compileNginx(spec)
+
+	case strings.HasPrefix(spec, "LogFormat "):
+		if spec, err = unwrapApacheDirective(spec); err != nil {
+			return nil, err
+		}
+		items, err = compileApache(spec)
+
+	// nginx formats can contain a literal % ("$request" 100%), variables are looked for first
+	case hasNginxVariable(spec):
+		items, err = compileNginx(spec)
+
+	case strings.Contains(spec, "%"):
+		items, err = compileApache(spec)
+
+	default:
+		err = fmt.Errorf("log.NewFormat error - %q has neither nginx variables nor Apache directives", spec)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return newFormat(items)
+}
+
+// newFormat checks the compiled items and works out which fields are missing
+func newFormat(items []formatItem) (*Format, error) {
+	var provided [nbFormatFields]bool
+
+	for i := range items {
+		if items[i].field == fieldLiteral {
+			continue
+		}
+
+		// A path can be directly followed by a query string (%U%q), the query
+		// string starting with a "?". Otherwise fields can't be told apart.
+		if i > 0 && items[i-1].field != fieldLiteral &&
+			!(items[i-1].field == fieldPath && items[i].field == fieldQuery) {
+			return nil, fmt.Errorf("log.NewFormat error - fields must be separated by a literal text")
+		}
+		provided[items[i].field] = true
+
+		items[i].quoted = i > 0 && i+1 < len(items) &&
+			bytes.HasSuffix(items[i-1].literal, []byte{'"'}) &&
+			bytes.HasPrefix(items[i+1].literal, []byte{'"'})
+	}
+
+	if !hasField(items) {
+		return nil, fmt.Errorf("log.NewFormat error - the format doesn't contain any field")
+	}
+
+	f := &Format{items: items}
+
+	if !provided[fieldAuthUser] {
+		f.missing |= AuthUserField
+	}
+	if !provided[fieldRequest] && !provided[fieldPath] && !provided[fieldURI] {
+		f.missing |= RequestField
+	}
+	if !provided[fieldSize] {
+		f.missing |= SizeField
+	}
+	if !provided[fieldReferer] {
+		f.missing |= RefererField
+	}
+	if !provided[fieldUserAgent] {
+		f.missing |= UserAgentField
+	}
+	if !provided[fieldTimeLocal] && !provided[fieldTimeISO8601] &&
+		!provided[fieldTimeSec] && !provided[fieldTimeMsec] && !provided[fieldTimeUsec] {
+		f.missing |= TimeField
+	}
+	if !provided[fieldDurationSec] && !provided[fieldDurationMsec] && !provided[fieldDurationUsec] {
+		f.missing |= DurationField
+	}
+
+	return f, nil
+}
+
+func hasField(items []formatItem) bool {
+	for i := range items {
+		if items[i].field != fieldLiteral {
+			return true
+		}
+	}
+	return false
+}
+
+// unwrapNginxDirective returns the format string of a log_format directive.
+// Its quoted parts are concatenated like nginx does.
+func unwrapNginxDirective(spec string) (string, error) {
+	// Skip "log_format name" and the optional escape parameter
+	rest := strings.TrimSpace(strings.TrimPrefix(spec, "log_format "))
+	rest = strings.TrimSuffix(rest, ";")
+	for i := 0; i < 2 && rest != "" && rest[0] != '\'' && rest[0] != '"'; i++ {
+		end := strings.IndexAny(rest, " \t")
+		if end < 0 {
+			return "", fmt.Errorf("log.NewFormat error - log_format expects a name and a format")
+		}
+		rest = strings.TrimSpace(rest[end:])
+	}
+
+	var out strings.Builder
+	for rest != "" {
+		quote := rest[0]
+		if quote != '\'' && quote != '"' {
+			return "", fmt.Errorf("log.NewFormat error - log_format strings must be quoted")
+		}
+
+		end := strings.IndexByte(rest[1:], quote)
+		if end < 0 {
+			return "", fmt.Errorf("log.NewFormat error - unterminated log_format string")
+		}
+		out.WriteString(rest[1 : end+1])
+		rest = strings.TrimSpace(rest[end+2:])
+	}
+
+	return out.String(), nil
+}
+
+// unwrapApacheDirective returns the format string of a LogFormat directive
+func unwrapApacheDirective(spec string) (string, error) {
+	rest := strings.TrimSpace(strings.TrimPrefix(spec, "LogFormat "))
+	if rest == "" || rest[0] != '"' {
+		return "", fmt.Errorf("log.NewFormat error - LogFormat strings must be double-quoted")
+	}
+
+	var out strings.Builder
+	for i := 1; i < len(rest); i++ {
+		switch rest[i] {
+		case '\\':
+			if i+1 < len(rest) && (rest[i+1] == '"' || rest[i+1] == '\\') {
+				i++
+			}
+			out.WriteByte(rest[i])
+		case '"':
+			return out.String(), nil
+		default:
+			out.WriteByte(rest[i])
+		}
+	}
+
+	return "", fmt.Errorf("log.NewFormat error - unterminated LogFormat string")
+}
+
+// compileNginx splits an nginx format into literals and variables ($name or ${name})
+func compileNginx(spec string) ([]formatItem, error) {
+	items := make([]formatItem, 0, 16)
+	literal := make([]byte, 0, len(spec))
+
+	for i := 0; i < len(spec); i++ {
+		if spec[i] != '$' {
+			literal = append(literal, spec[i])
+			continue
+		}
+
+		var name string
+		if i+1 < len(spec) && spec[i+1] == '{' {
+			end := strings.IndexByte(spec[i+2:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("log.NewFormat error - unterminated variable at offset %d", i)
+			}
+			name = spec[i+2 : i+2+end]
+			i += end + 2
+		} else {
+			j := i + 1
+			for j < len(spec) && isVariableChar(spec[j]) {
+				j++
+			}
+			name = spec[i+1 : j]
+			i = j - 1
+		}
+
+		if name == "" {
+			return nil, fmt.Errorf("log.NewFormat error - empty variable name at offset %d", i)
+		}
+
+		items = appendLiteral(items, literal)
+		literal = literal[:0]
+
+		field, ok := nginxVariables[name]
+		if !ok {
+			field = fieldIgnored
+		}
+		items = append(items, formatItem{field: field})
+	}
+
+	return appendLiteral(items, literal), nil
+}
+
+// hasNginxVariable returns true if spec contains an nginx variable ($name or ${name})
+func hasNginxVariable(spec string) bool {
+	for i := 0; i+1 < len(spec); i++ {
+		if spec[i] == '$' && (spec[i+1] == '{' || isVariableChar(spec[i+1])) {
+			return true
+		}
+	}
+	return false
+}
+
+func isVariableChar(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
+
+// compileApache splits an Apache format into literals and directives
+// (%h, %>s, %{User-Agent}i, %400,501{Referer}i...)
+func compileApache(spec string) ([]formatItem, error) {
+	items := make([]formatItem, 0, 16)
+	literal := make([]byte, 0, len(spec))
+
+	for i := 0; i < len(spec); i++ {
+		c := spec[i]
+
+		// Apache escapes quotes and backslashes in LogFormat strings
+		if c == '\\' && i+1 < len(spec) {
+			i++
+			switch spec[i] {
+			case 'n':
+				literal = append(literal, '\n')
+			case 't':
+				literal = append(literal, '\t')
+			default:
+				literal = append(literal, spec[i])
+			}
+			continue
+		}
+
+		if c != '%' {
+			literal = append(literal, c)
+			continue
+		}
+
+		i++
+		if i < len(spec) && spec[i] == '%' {
+			literal = append(literal, '%')
+			continue
+		}
+
+		// Skip modifiers : status-code conditions and request selection
+		for i < len(spec) && strings.IndexByte("<>!,0123456789", spec[i]) >= 0 {
+			i++
+		}
+
+		var arg string
+		if i < len(spec) && spec[i] == '{' {
+			end := strings.IndexByte(spec[i+1:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("log.NewFormat error - unterminated directive argument at offset %d", i)
+			}
+			arg = spec[i+1 : i+1+end]
+			i += end + 2
+		}
+
+		if i >= len(spec) {
+			return nil, fmt.Errorf("log.NewFormat error - incomplete directive at the end of the format")
+		}
+
+		items = appendLiteral(items, literal)
+		literal = literal[:0]
+		items = append(items, formatItem{field: apacheField(spec[i], arg)})
+	}
+
+	return appendLiteral(items, literal), nil
+}
+
+// apacheField returns the Info field matching a directive and its argument
+func apacheField(directive byte, arg string) formatField {
+	switch directive {
+	case 'i':
+		if field, ok := apacheHeaders[strings.ToLower(arg)]; ok {
+			return field
+		}
+		return fieldIgnored
+
+	case 't':
+		if arg == "" {
+			return fieldTimeLocal
+		}
+		if field, ok := apacheTimeFormats[arg]; ok {
+			return field
+		}
+		return fieldIgnored
+
+	case 'T':
+		if arg == "" {
+			return fieldDurationSec
+		}
+		if field, ok := apacheDurationUnits[arg]; ok {
+			return field
+		}
+		return fieldIgnored
+	}
+
+	if field, ok := apacheDirectives[directive]; ok {
+		return field
+	}
+	return fieldIgnored
+}
+
+func appendLiteral(items []formatItem, literal []byte) []formatItem {
+	if len(literal) == 0 {
+		return items
+	}
+
+	l := make([]byte, len(literal))
+	copy(l, literal)
+	return append(items, formatItem{literal: l, field: fieldLiteral})
+}
+
+// formatValue is a raw value read from a line
+type formatValue struct {
+	data    []byte
+	escaped bool
+	set     bool
+}
+
+func (v formatValue) text() string {
+	if v.escaped {
+		return unescape(v.data)
+	}
+	return string(v.data)
+}
+
+// Parse reads a line following the format and returns a properly hydrated Info
+// struct. The slice isn't retained so it can be reused by the caller once the
+// function returns.
+func (f *Format) Parse(data []byte) (Info, error) {
+	for len(data) > 0 && (data[len(data)-1] == '\n' || data[len(data)-1] == '\r') {
+		data = data[:len(data)-1]
+	}
+	if len(data) == 0 {
+		return Info{}, fmt.Errorf("log.Format.Parse error - empty line")
+	}
+
+	var values [nbFormatFields]formatValue
+	pos := 0
+
+	for i := range f.items {
+		item := &f.items[i]
+
+		if item.field == fieldLiteral {
+			if !bytes.HasPrefix(data[pos:], item.literal) {
+				return Info{}, fmt.Errorf("log.Format.Parse error - expected %q at offset %d", item.literal, pos)
+			}
+			pos += len(item.literal)
+			continue
+		}
+
+		value, next, err := f.readValue(data, pos, i)
+		if err != nil {
+			return Info{}, err
+		}
+		values[item.field] = value
+		pos = next
+	}
+
+	return f.info(&values)
+}
+
+// readValue reads the value of the i-th item starting at pos. It returns the
+// value and the position right after it.
+func (f *Format) readValue(data []byte, pos, i int) (formatValue, int, error) {
+	item := &f.items[i]
+	value := formatValue{set: true}
+
+	switch {
+	case item.quoted:
+		// The next literal starts with a double-quote, find the unescaped one
+		for j := pos; j < len(data); j++ {
+			switch data[j] {
+			case '\\':
+				value.escaped = true
+				j++
+			case '"':
+				value.data = data[pos:j]
+				return value, j, nil
+			}
+		}
+		return value, pos, fmt.Errorf("log.Format.Parse error - unterminated quoted field at offset %d", pos)
+
+	case isTimeField(item.field) && pos < len(data) && data[pos] == '[':
+		// Apache's %t is written between brackets
+		end := bytes.IndexByte(data[pos:], ']')
+		if end < 0 {
+			return value, pos, fmt.Errorf("log.Format.Parse error - unterminated bracketed field at offset %d", pos)
+		}
+		value.data = data[pos+1 : pos+end]
+		return value, pos + end + 1, nil
+
+	case i+1 < len(f.items) && f.items[i+1].field != fieldLiteral:
+		// A path directly followed by a query string
+		end := len(data)
+		if i+2 < len(f.items) {
+			if j := bytes.IndexByte(data[pos:], f.items[i+2].literal[0]); j >= 0 {
+				end = pos + j
+			}
+		}
+		if j := bytes.IndexByte(data[pos:end], '?'); j >= 0 {
+			end = pos + j
+		}
+		value.data = data[pos:end]
+		return value, end, nil
+
+	case i+1 < len(f.items):
+		end := bytes.IndexByte(data[pos:], f.items[i+1].literal[0])
+		if end < 0 {
+			return value, pos, fmt.Errorf("log.Format.Parse error - missing %q after offset %d", f.items[i+1].literal[0], pos)
+		}
+		value.data = data[pos : pos+end]
+		return value, pos + end, nil
+	}
+
+	value.data = data[pos:]
+	return value, len(data), nil
+}
+
+func isTimeField(field formatField) bool {
+	return field >= fieldTimeLocal && field <= fieldTimeUsec
+}
+
+func isUnknown(v formatValue) bool {
+	return !v.set || len(v.data) == 0 || isPlaceholder(v.data)
+}
+
+// info converts the values read from a line into an Info struct
+func (f *Format) info(values *[nbFormatFields]formatValue) (Info, error) {
+	info := Info{Missing: f.missing}
+
+	info.setHost(values[fieldHost].text())
+
+	if isUnknown(values[fieldAuthUser]) {
+		info.Missing |= AuthUserField
+	} else {
+		info.AuthUser = values[fieldAuthUser].text()
+	}
+
+	if err := f.parseTime(&info, values); err != nil {
+		return Info{}, err
+	}
+
+	if values[fieldStatus].set {
+		code, err := parseUint(values[fieldStatus].data)
+		if err != nil {
+			return Info{}, err
+		}
+		info.Request.Code = uint32(code)
+	}
+
+	if err := f.parseRequest(&info, values); err != nil {
+		return Info{}, err
+	}
+
+	if isUnknown(values[fieldSize]) {
+		info.Missing |= SizeField
+	} else {
+		size, err := parseUint(values[fieldSize].data)
+		if err != nil {
+			return Info{}, err
+		}
+		info.Request.Size = size
+	}
+
+	if isUnknown(values[fieldReferer]) {
+		info.Missing |= RefererField
+	} else {
+		info.Referer = values[fieldReferer].text()
+	}
+
+	if isUnknown(values[fieldUserAgent]) {
+		info.Missing |= UserAgentField
+	} else {
+		info.UserAgent = values[fieldUserAgent].text()
+	}
+
+	if !isUnknown(values[fieldForwardedFor]) {
+		info.ForwardedFor = values[fieldForwardedFor].text()
+	}
+
+	if err := f.parseDuration(&info, values); err != nil {
+		return Info{}, err
+	}
+
+	return info, nil
+}
+
+// parseDuration reads the first duration field provided by the format
+func (f *Format) parseDuration(info *Info, values *[nbFormatFields]formatValue) error {
+	units := [...]time.Duration{time.Second, time.Millisecond, time.Microsecond}
+
+	for field := fieldDurationSec; field <= fieldDurationUsec; field++ {
+		v := values[field]
+		if !v.set {
+			continue
+		}
+
+		if isUnknown(v) {
+			info.Missing |= DurationField
+			return nil
+		}
+
+		d, err := parseUnits(string(v.data), units[field-fieldDurationSec])
+		if err != nil {
+			return err
+		}
+		info.Request.Duration = d
+		return nil
+	}
+
+	return nil
+}
+
+// parseTime reads the first time field provided by the format
+func (f *Format) parseTime(info *Info, values *[nbFormatFields]formatValue) error {
+	var err error
+
+	for field := fieldTimeLocal; field <= fieldTimeUsec; field++ {
+		v := values[field]
+		if !v.set {
+			continue
+		}
+
+		if isUnknown(v) {
+			info.Missing |= TimeField
+			return nil
+		}
+
+		switch field {
+		case fieldTimeLocal:
+			info.LocalTime, err = parseLocalTime(string(v.data))
+		case fieldTimeISO8601:
+			info.LocalTime, err = time.Parse(time.RFC3339, string(v.data))
+		case fieldTimeSec:
+			info.LocalTime, err = parseEpoch(string(v.data), time.Second)
+		case fieldTimeMsec:
+			info.LocalTime, err = parseEpoch(string(v.data), time.Millisecond)
+		case fieldTimeUsec:
+			info.LocalTime, err = parseEpoch(string(v.data), time.Microsecond)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// parseRequest reads the request line or, if the format doesn't provide it,
+// its components (method, path, query string, protocol)
+func (f *Format) parseRequest(info *Info, values *[nbFormatFields]formatValue) error {
+	if values[fieldRequest].set {
+		if isUnknown(values[fieldRequest]) {
+			info.Missing |= RequestField
+			return nil
+		}
+
+		method, uri, version, err := parseRequestLine(values[fieldRequest].text())
+		if err != nil {
+			return err
+		}
+		info.Request.Method, info.Request.Version = method, version
+		info.Request.setURI(uri)
+		return nil
+	}
+
+	switch {
+	case !isUnknown(values[fieldURI]):
+		info.Request.setURI(values[fieldURI].text())
+
+	case !isUnknown(values[fieldPath]):
+		info.Request.setURI(values[fieldPath].text())
+		if query := values[fieldQuery]; !isUnknown(query) {
+			info.Request.Query = strings.TrimPrefix(query.text(), "?")
+		}
+
+	default:
+		info.Missing |= RequestField
+		return nil
+	}
+
+	if !isUnknown(values[fieldMethod]) {
+		info.Request.Method = values[fieldMethod].text()
+	}
+	if !isUnknown(values[fieldProtocol]) {
+		info.Request.Version = values[fieldProtocol].text()
+	}
+
+	return nil
+}
+
+// parseEpoch reads a time expressed as a number of units since epoch. The
+// number can have a fractional part (1581265620.123).
+func parseEpoch(field string, unit time.Duration) (time.Time, error) {
+	d, err := parseUnits(field, unit)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("log.Parse error - invalid epoch time %q", field)
+	}
+
+	return time.Unix(0, 0).Add(d).UTC(), nil
+}
+
+// parseUnits reads a number of units which can have a fractional part (0.012).
+// Durations are never negative, signs are rejected.
+func parseUnits(field string, unit time.Duration) (time.Duration, error) {
+	integer, fraction := field, ""
+	if dot := strings.IndexByte(field, '.'); dot >= 0 {
+		integer, fraction = field[:dot], field[dot+1:]
+	}
+
+	n, err := strconv.ParseUint(integer, 10, 64)
+	if err != nil || n > uint64(math.MaxInt64/unit) {
+		return 0, fmt.Errorf("log.Parse error - invalid number %q", field)
+	}
+	d := time.Duration(n) * unit
+
+	for i := 0; i < len(fraction); i++ {
+		if fraction[i] < '0' || fraction[i] > '9' {
+			return 0, fmt.Errorf("log.Parse error - invalid number %q", field)
+		}
+	}
+
+	// Only keep the digits having a meaning at the nanosecond scale
+	for scale := unit / 10; fraction != "" && scale > 0; scale /= 10 {
+		d += time.Duration(fraction[0]-'0') * scale
+		fraction = fraction[1:]
+	}
+
+	return d, nil
+}
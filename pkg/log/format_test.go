@@ -0,0 +1,179 @@
+package log
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewFormatReturnsAnErrorOnInvalidFormats(t *testing.T) {
+	invalid := []string{
+		"",
+		"no fields at all",
+		"$remote_addr$remote_user",
+		"%h %{Referer",
+		"%h %",
+		"${remote_addr",
+		`log_format main '$remote_addr`,
+		`LogFormat "%h %u`,
+	}
+
+	for _, spec := range invalid {
+		_, err := NewFormat(spec)
+		assert.NotNil(t, err, spec)
+	}
+}
+
+func TestFormatParsesNginxCombinedFormat(t *testing.T) {
+	// Setup stage
+	f, err := NewFormat(`$remote_addr - $remote_user [$time_local] "$request" $status $body_bytes_sent "$http_referer" "$http_user_agent"`)
+	assert.Nil(t, err)
+	refTime, err := time.Parse(`02/Jan/2006:15:04:05 -0700`, "09/Feb/2020:16:27:00 +0000")
+	if err != nil {
+		panic(err)
+	}
+
+	// Exercise
+	info, err := f.Parse([]byte(`172.17.0.1 - bob [09/Feb/2020:16:27:00 +0000] "GET /a?b=1 HTTP/1.1" 200 612 "-" "curl/7.54.0"`))
+
+	// Validation
+	assert.Nil(t, err)
+	assert.Equal(t, "172.17.0.1", info.Host)
+	assert.Equal(t, "bob", info.AuthUser)
+	assert.Equal(t, refTime, info.LocalTime)
+	assert.Equal(t, HTTP{Method: "GET", Route: "/a", Query: "b=1", Version: "HTTP/1.1", Code: 200, Size: 612}, info.Request)
+	assert.Equal(t, "", info.Referer)
+	assert.Equal(t, "curl/7.54.0", info.UserAgent)
+	assert.Equal(t, RefererField|DurationField, info.Missing)
+}
+
+func TestFormatParsesNginxFormatsWithALiteralPercent(t *testing.T) {
+	f, err := NewFormat(`$remote_addr "$request" $status $body_bytes_sent 100%`)
+	assert.Nil(t, err)
+
+	info, err := f.Parse([]byte(`172.17.0.1 "GET /a HTTP/1.1" 200 612 100%`))
+
+	assert.Nil(t, err)
+	assert.Equal(t, "172.17.0.1", info.Host)
+	assert.Equal(t, HTTP{Method: "GET", Route: "/a", Version: "HTTP/1.1", Code: 200, Size: 612}, info.Request)
+
+	// Unknown request lines are flagged as missing, malformed ones are errors
+	info, err = f.Parse([]byte(`172.17.0.1 "-" 400 0 100%`))
+	assert.Nil(t, err)
+	assert.False(t, info.Has(RequestField))
+
+	_, err = f.Parse([]byte(`172.17.0.1 "GARBAGE" 400 0 100%`))
+	assert.NotNil(t, err)
+}
+
+func TestFormatParsesNginxLogFormatDirective(t *testing.T) {
+	f, err := NewFormat(`log_format main escape=default '$remote_addr [$time_iso8601] '
+		'"$request_method $uri $args" $status $request_time';`)
+	assert.Nil(t, err)
+
+	info, err := f.Parse([]byte(`::1 [2020-02-09T16:27:00+01:00] "POST /api/users a=1" 201 0.012`))
+
+	assert.Nil(t, err)
+	assert.Equal(t, "::1", info.Host)
+	assert.Equal(t, "POST", info.Request.Method)
+	assert.Equal(t, "/api/users", info.Request.Route)
+	assert.Equal(t, "a=1", info.Request.Query)
+	assert.Equal(t, uint32(201), info.Request.Code)
+	assert.Equal(t, int64(1581262020), info.LocalTime.Unix())
+	assert.Equal(t, 12*time.Millisecond, info.Request.Duration)
+	assert.False(t, info.Has(SizeField))
+	assert.False(t, info.Has(AuthUserField))
+	assert.True(t, info.Has(RequestField|TimeField))
+}
+
+func TestFormatParsesApacheCommonFormatWithPlaceholders(t *testing.T) {
+	f, err := NewFormat(`LogFormat "%h %l %u %t \"%r\" %>s %b" common`)
+	assert.Nil(t, err)
+
+	info, err := f.Parse([]byte(`10.0.0.1 - - [09/Feb/2020:16:27:00 +0000] "GET / HTTP/1.1" 304 -`))
+
+	assert.Nil(t, err)
+	assert.Equal(t, "10.0.0.1", info.Host)
+	assert.Equal(t, uint32(304), info.Request.Code)
+	assert.Equal(t, "/", info.Request.Route)
+	assert.False(t, info.Has(SizeField))
+	assert.False(t, info.Has(AuthUserField))
+	assert.True(t, info.Has(TimeField))
+}
+
+func TestFormatParsesApacheDirectivesWithModifiersAndHeaders(t *testing.T) {
+	f, err := NewFormat(`%a %{msec}t "%m %U%q %H" %400,501>s %B "%{Referer}i" "%{User-agent}i" %D %{X-Request-Id}o`)
+	assert.Nil(t, err)
+
+	info, err := f.Parse([]byte(`10.0.0.1 1581265620123 "GET /search?q=go HTTP/2.0" 500 1024 "http://a.b/" "Mozilla \"5\"" 1234 abc-42`))
+
+	assert.Nil(t, err)
+	assert.Equal(t, "10.0.0.1", info.Host)
+	assert.Equal(t, time.Unix(1581265620, 123*int64(time.Millisecond)).UTC(), info.LocalTime)
+	assert.Equal(t, HTTP{Method: "GET", Route: "/search", Query: "q=go", Version: "HTTP/2.0", Code: 500, Size: 1024, Duration: 1234 * time.Microsecond}, info.Request)
+	assert.Equal(t, "http://a.b/", info.Referer)
+	assert.Equal(t, `Mozilla "5"`, info.UserAgent)
+}
+
+func TestFormatReturnsAnErrorWhenALineDoesntMatch(t *testing.T) {
+	f, err := NewFormat(`$remote_addr [$time_local] "$request" $status`)
+	assert.Nil(t, err)
+
+	_, err = f.Parse([]byte(`172.17.0.1 - - [09/Feb/2020:16:27:00 +0000] "GET / HTTP/1.1" 200 612`))
+	assert.NotNil(t, err)
+
+	_, err = f.Parse([]byte(`172.17.0.1 [09/Feb/2020:16:27:00 +0000] "GET / HTTP/1.1" abc`))
+	assert.NotNil(t, err)
+
+	_, err = f.Parse([]byte(""))
+	assert.NotNil(t, err)
+}
+
+func TestParseEpochReadsFractionalParts(t *testing.T) {
+	ts, err := parseEpoch("1581265620.5", time.Second)
+	assert.Nil(t, err)
+	assert.Equal(t, time.Unix(1581265620, int64(500*time.Millisecond)).UTC(), ts)
+
+	ts, err = parseEpoch("1581265620123456", time.Microsecond)
+	assert.Nil(t, err)
+	assert.Equal(t, time.Unix(1581265620, int64(123456*time.Microsecond)).UTC(), ts)
+
+	_, err = parseEpoch("12a", time.Second)
+	assert.NotNil(t, err)
+}
+
+func TestParseUnitsRejectsNegativeAndInvalidNumbers(t *testing.T) {
+	d, err := parseUnits("1.5", time.Second)
+	assert.Nil(t, err)
+	assert.Equal(t, 1500*time.Millisecond, d)
+
+	// Digits beyond the nanosecond are dropped
+	d, err = parseUnits("0.0000000019", time.Second)
+	assert.Nil(t, err)
+	assert.Equal(t, time.Nanosecond, d)
+
+	for _, field := range []string{"-1.5", "-0.5", "-1", "+1", "", ".5", "1.5a", "0.0000000001x", "1.2.3", "99999999999999999999"} {
+		_, err := parseUnits(field, time.Second)
+		assert.NotNil(t, err, field)
+	}
+}
+
+func TestFormatReadsDurationsInAllUnits(t *testing.T) {
+	formats := map[string]string{
+		`%s %T`:                 "200 2",
+		`%s %{ms}T`:             "200 2000",
+		`%s %{us}T`:             "200 2000000",
+		`$status $request_time`: "200 2.000",
+	}
+
+	for spec, line := range formats {
+		f, err := NewFormat(spec)
+		assert.Nil(t, err, spec)
+
+		info, err := f.Parse([]byte(line))
+		assert.Nil(t, err, spec)
+		assert.Equal(t, 2*time.Second, info.Request.Duration, spec)
+		assert.True(t, info.Has(DurationField), spec)
+	}
+}
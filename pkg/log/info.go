@@ -1,26 +1,92 @@
 package log
 
 import (
+	"net"
+	"net/url"
+	"strings"
 	"time"
 )
 
 // RFC931User RFC931-compliant log user, this field doesn't seem to be used anymore
 type RFC931User struct{}
 
+// Field identifies an optional log field. Fields can be combined as flags.
+type Field uint16
+
+const (
+	// AuthUserField is the authenticated user
+	AuthUserField Field = 1 << iota
+	// RequestField is the request line (HTTP.Method, HTTP.Route and HTTP.Version)
+	RequestField
+	// SizeField is the served-content's size (HTTP.Size)
+	SizeField
+	// RefererField is the referer
+	RefererField
+	// UserAgentField is the client's user-agent
+	UserAgentField
+	// TimeField is the request's local time (Info.LocalTime)
+	TimeField
+	// DurationField is the time taken to serve the request (HTTP.Duration)
+	DurationField
+)
+
 // Info details a log line's content
 type Info struct {
 	Host      string
+	ClientIP  net.IP // client's address parsed from Host, nil if Host isn't an IP address (a hostname)
 	LogUser   RFC931User
 	AuthUser  string
 	LocalTime time.Time
 	Request   HTTP
+	Referer   string // page the request comes from, empty if unknown (combined log format)
+	UserAgent string // client's user-agent, empty if unknown (combined log format)
+	// ForwardedFor is the X-Forwarded-For header listing the addresses a request
+	// went through when sent by proxies, empty if unknown (see TrustedProxies)
+	ForwardedFor string
+	// Missing flags the optional fields that are unknown, either because the
+	// line holds a placeholder ("-") or because the log format doesn't provide them.
+	// Unknown fields are set to their zero value.
+	Missing Field
+}
+
+// Has returns true if all fields flagged in f are known
+func (o *Info) Has(f Field) bool {
+	return o.Missing&f == 0
 }
 
 // HTTP describes an HTTP-request-log
 type HTTP struct {
-	Method  string
-	Route   string
-	Code    uint32
-	Size    uint64
-	Version string
+	Method   string
+	Route    string // requested path, without the query string nor the fragment
+	Query    string // query string, without the leading '?' (empty if none)
+	Code     uint32
+	Size     uint64
+	Version  string
+	Duration time.Duration // time taken to serve the request (nginx's $request_time, Apache's %D)
+}
+
+// URI returns the requested URI, the route followed by the query string
+func (o *HTTP) URI() string {
+	if o.Query == "" {
+		return o.Route
+	}
+	return o.Route + "?" + o.Query
+}
+
+// QueryValues parses the query string. Malformed pairs are ignored.
+func (o *HTTP) QueryValues() url.Values {
+	values, _ := url.ParseQuery(o.Query)
+	return values
+}
+
+// setURI splits uri into the route and the query string, the fragment is dropped
+func (o *HTTP) setURI(uri string) {
+	if i := strings.IndexByte(uri, '#'); i >= 0 {
+		uri = uri[:i]
+	}
+
+	o.Route, o.Query = uri, ""
+	if i := strings.IndexByte(uri, '?'); i >= 0 {
+		o.Route, o.Query = uri[:i], uri[i+1:]
+	}
 }
@@ -0,0 +1,378 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// JSONFields maps Info fields to the keys of a JSON access log. Keys of nested
+// objects are separated by dots (request.uri). An empty key means the field
+// isn't provided by the log.
+type JSONFields struct {
+	Host      string
+	AuthUser  string
+	Time      string // epoch (s, ms, µs or ns), RFC3339 or common log format time
+	Method    string
+	URI       string // route, the query string included
+	Protocol  string
+	Status    string
+	Size      string
+	Referer   string
+	UserAgent string
+	// ForwardedFor is the X-Forwarded-For header
+	ForwardedFor string
+	Duration     string
+	// DurationUnit is the unit of the durations (the log holds a number of units)
+	DurationUnit time.Duration
+}
+
+// jsonPresets are the field mappings of well known JSON access logs
+var jsonPresets = map[string]JSONFields{
+	// Generic keys, such as the ones of an nginx log_format using escape=json
+	"json": {
+		Host:         "remote_addr",
+		AuthUser:     "remote_user",
+		Time:         "time",
+		Method:       "method",
+		URI:          "uri",
+		Protocol:     "protocol",
+		Status:       "status",
+		Size:         "size",
+		Referer:      "referer",
+		UserAgent:    "user_agent",
+		ForwardedFor: "x_forwarded_for",
+		Duration:     "request_time",
+		DurationUnit: time.Second,
+	},
+	"caddy": {
+		Host:         "request.remote_ip",
+		AuthUser:     "user_id",
+		Time:         "ts",
+		Method:       "request.method",
+		URI:          "request.uri",
+		Protocol:     "request.proto",
+		Status:       "status",
+		Size:         "size",
+		Referer:      "request.headers.Referer",
+		UserAgent:    "request.headers.User-Agent",
+		ForwardedFor: "request.headers.X-Forwarded-For",
+		Duration:     "duration",
+		DurationUnit: time.Second,
+	},
+	"traefik": {
+		Host:         "ClientHost",
+		AuthUser:     "ClientUsername",
+		Time:         "StartUTC",
+		Method:       "RequestMethod",
+		URI:          "RequestPath",
+		Protocol:     "RequestProtocol",
+		Status:       "DownstreamStatus",
+		Size:         "DownstreamContentSize",
+		Referer:      "request_Referer",
+		UserAgent:    "request_User-Agent",
+		ForwardedFor: "request_X-Forwarded-For",
+		Duration:     "Duration",
+		DurationUnit: time.Nanosecond,
+	},
+}
+
+// JSONPreset returns the field mapping of a well known JSON access log
+// (json, caddy or traefik). The boolean is false if the preset doesn't exist.
+func JSONPreset(name string) (JSONFields, bool) {
+	fields, ok := jsonPresets[name]
+	return fields, ok
+}
+
+// Override returns a copy of the mapping where the keys listed in spec replace
+// the current ones. The spec reads "field=key,field=key", valid fields are host,
+// auth_user, time, method, uri, protocol, status, size, referer, user_agent,
+// forwarded_for and duration. The duration's unit is set with duration_unit (s, ms, us or ns).
+// Example : "uri=request.path,status=response.code,duration_unit=ms"
+func (o JSONFields) Override(spec string) (JSONFields, error) {
+	for _, assignment := range strings.Split(spec, ",") {
+		assignment = strings.TrimSpace(assignment)
+		if assignment == "" {
+			continue
+		}
+
+		eq := strings.IndexByte(assignment, '=')
+		if eq < 0 {
+			return JSONFields{}, fmt.Errorf("log.JSONFields error - %q must read field=key", assignment)
+		}
+
+		field, value := strings.TrimSpace(assignment[:eq]), strings.TrimSpace(assignment[eq+1:])
+		if field == "duration_unit" {
+			unit, ok := durationUnits[value]
+			if !ok {
+				return JSONFields{}, fmt.Errorf("log.JSONFields error - unknown duration unit %q", value)
+			}
+			o.DurationUnit = unit
+			continue
+		}
+
+		key := o.key(field)
+		if key == nil {
+			return JSONFields{}, fmt.Errorf("log.JSONFields error - unknown field %q", assignment[:eq])
+		}
+		*key = value
+	}
+
+	return o, nil
+}
+
+// durationUnits are the units durations can be expressed in
+var durationUnits = map[string]time.Duration{
+	"s":  time.Second,
+	"ms": time.Millisecond,
+	"us": time.Microsecond,
+	"ns": time.Nanosecond,
+}
+
+// key returns a pointer to the key a field name is mapped to, nil if the field doesn't exist
+func (o *JSONFields) key(field string) *string {
+	switch field {
+	case "host":
+		return &o.Host
+	case "auth_user":
+		return &o.AuthUser
+	case "time":
+		return &o.Time
+	case "method":
+		return &o.Method
+	case "uri":
+		return &o.URI
+	case "protocol":
+		return &o.Protocol
+	case "status":
+		return &o.Status
+	case "size":
+		return &o.Size
+	case "referer":
+		return &o.Referer
+	case "user_agent":
+		return &o.UserAgent
+	case "forwarded_for":
+		return &o.ForwardedFor
+	case "duration":
+		return &o.Duration
+	}
+	return nil
+}
+
+// JSONFormat parses JSON-lines access logs, one JSON object per line
+type JSONFormat struct {
+	fields JSONFields
+}
+
+// NewJSONFormat returns a JSON-lines parser reading the keys given by fields
+func NewJSONFormat(fields JSONFields) *JSONFormat {
+	return &JSONFormat{fields: fields}
+}
+
+// Parse reads a JSON object and returns a properly hydrated Info struct. The
+// slice isn't retained so it can be reused by the caller once the function returns.
+func (f *JSONFormat) Parse(data []byte) (Info, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return Info{}, fmt.Errorf("log.JSONFormat.Parse error - empty line")
+	}
+
+	var obj map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&obj); err != nil {
+		return Info{}, fmt.Errorf("log.JSONFormat.Parse error - %v", err)
+	}
+
+	info := Info{}
+	fields := &f.fields
+
+	info.setHost(jsonString(lookup(obj, fields.Host)))
+	info.ForwardedFor = jsonString(lookup(obj, fields.ForwardedFor))
+
+	if info.AuthUser = jsonString(lookup(obj, fields.AuthUser)); info.AuthUser == "" {
+		info.Missing |= AuthUserField
+	}
+
+	t, ok, err := jsonTime(lookup(obj, fields.Time))
+	if err != nil {
+		return Info{}, err
+	}
+	if ok {
+		info.LocalTime = t
+	} else {
+		info.Missing |= TimeField
+	}
+
+	code, ok, err := jsonUint(lookup(obj, fields.Status))
+	if err != nil {
+		return Info{}, err
+	}
+	if !ok {
+		return Info{}, fmt.Errorf("log.JSONFormat.Parse error - no status code found at key %q", fields.Status)
+	}
+	info.Request.Code = uint32(code)
+
+	if uri := jsonString(lookup(obj, fields.URI)); uri == "" {
+		info.Missing |= RequestField
+	} else {
+		info.Request.setURI(uri)
+		info.Request.Method = jsonString(lookup(obj, fields.Method))
+		info.Request.Version = jsonString(lookup(obj, fields.Protocol))
+	}
+
+	size, ok, err := jsonUint(lookup(obj, fields.Size))
+	if err != nil {
+		return Info{}, err
+	}
+	if ok {
+		info.Request.Size = size
+	} else {
+		info.Missing |= SizeField
+	}
+
+	if info.Referer = jsonString(lookup(obj, fields.Referer)); info.Referer == "" {
+		info.Missing |= RefererField
+	}
+
+	if info.UserAgent = jsonString(lookup(obj, fields.UserAgent)); info.UserAgent == "" {
+		info.Missing |= UserAgentField
+	}
+
+	if d := jsonString(lookup(obj, fields.Duration)); d != "" {
+		unit := fields.DurationUnit
+		if unit == 0 {
+			unit = time.Second
+		}
+
+		duration, err := parseUnits(d, unit)
+		if err != nil {
+			return Info{}, fmt.Errorf("log.JSONFormat.Parse error - invalid duration %q", d)
+		}
+		info.Request.Duration = duration
+	} else {
+		info.Missing |= DurationField
+	}
+
+	return info, nil
+}
+
+// lookup returns the value found at key in obj, nil if there is none. Nested
+// objects are walked through using dots (request.uri). Keys containing dots are
+// matched first. Arrays are replaced by their first value (headers are often
+// logged as arrays).
+func lookup(obj map[string]interface{}, key string) interface{} {
+	if key == "" {
+		return nil
+	}
+
+	for {
+		if v, ok := obj[key]; ok {
+			return firstValue(v)
+		}
+
+		dot := strings.IndexByte(key, '.')
+		if dot < 0 {
+			return nil
+		}
+
+		child, ok := firstValue(obj[key[:dot]]).(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		obj, key = child, key[dot+1:]
+	}
+}
+
+func firstValue(v interface{}) interface{} {
+	if array, ok := v.([]interface{}); ok {
+		if len(array) == 0 {
+			return nil
+		}
+		return array[0]
+	}
+	return v
+}
+
+// jsonString converts a value to a string, placeholders ("-") become empty strings
+func jsonString(v interface{}) string {
+	var s string
+
+	switch value := v.(type) {
+	case string:
+		s = value
+	case json.Number:
+		s = value.String()
+	case bool:
+		s = strconv.FormatBool(value)
+	}
+
+	if s == "-" {
+		return ""
+	}
+	return s
+}
+
+// jsonUint reads an unsigned integer given as a number or a string. The boolean
+// is false if the value is unknown.
+func jsonUint(v interface{}) (uint64, bool, error) {
+	s := jsonString(v)
+	if s == "" {
+		return 0, false, nil
+	}
+
+	n, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		// Some servers log integers as floats (612.0)
+		f, ferr := strconv.ParseFloat(s, 64)
+		if ferr != nil || f < 0 {
+			return 0, false, fmt.Errorf("log.JSONFormat.Parse error - invalid number %q", s)
+		}
+		n = uint64(f)
+	}
+
+	return n, true, nil
+}
+
+// jsonTime reads a time given as an epoch number or as a string (RFC3339,
+// common log format time or epoch). The epoch unit is deduced from its magnitude.
+// The boolean is false if the value is unknown.
+func jsonTime(v interface{}) (time.Time, bool, error) {
+	s := jsonString(v)
+	if s == "" {
+		return time.Time{}, false, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, true, nil
+	}
+
+	if t, err := parseLocalTime(s); err == nil {
+		return t, true, nil
+	}
+
+	integer := s
+	if dot := strings.IndexByte(s, '.'); dot >= 0 {
+		integer = s[:dot]
+	}
+
+	unit := time.Second
+	switch digits := len(strings.TrimPrefix(integer, "-")); {
+	case digits > 18:
+		unit = time.Nanosecond
+	case digits > 15:
+		unit = time.Microsecond
+	case digits > 12:
+		unit = time.Millisecond
+	}
+
+	t, err := parseEpoch(s, unit)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("log.JSONFormat.Parse error - invalid time %q", s)
+	}
+	return t, true, nil
+}
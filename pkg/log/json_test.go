@@ -0,0 +1,132 @@
+package log
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONFormatParsesCaddyLogs(t *testing.T) {
+	// Setup stage
+	fields, ok := JSONPreset("caddy")
+	assert.True(t, ok)
+	f := NewJSONFormat(fields)
+	const line string = `{"level":"info","ts":1581265620.5,"logger":"http.log.access","msg":"handled request","request":{"remote_ip":"10.0.0.1","proto":"HTTP/2.0","method":"GET","host":"example.com","uri":"/api/users?page=2","headers":{"User-Agent":["curl/7.68.0"]}},"user_id":"","duration":0.0012,"size":612,"status":200}`
+
+	// Exercise
+	info, err := f.Parse([]byte(line))
+
+	// Validation
+	assert.Nil(t, err)
+	assert.Equal(t, "10.0.0.1", info.Host)
+	assert.Equal(t, time.Unix(1581265620, int64(500*time.Millisecond)).UTC(), info.LocalTime)
+	assert.Equal(t, HTTP{Method: "GET", Route: "/api/users", Query: "page=2", Version: "HTTP/2.0", Code: 200, Size: 612, Duration: 1200 * time.Microsecond}, info.Request)
+	assert.Equal(t, "curl/7.68.0", info.UserAgent)
+	assert.Equal(t, AuthUserField|RefererField, info.Missing)
+}
+
+func TestJSONFormatParsesTraefikLogs(t *testing.T) {
+	fields, ok := JSONPreset("traefik")
+	assert.True(t, ok)
+	f := NewJSONFormat(fields)
+
+	info, err := f.Parse([]byte(`{"ClientHost":"192.168.1.7","Duration":1500000,"ClientUsername":"-","DownstreamContentSize":17,"DownstreamStatus":404,"RequestMethod":"POST","RequestPath":"/login","RequestProtocol":"HTTP/1.1","StartUTC":"2020-02-09T16:27:00.123456789Z","request_User-Agent":"Go-http-client/1.1"}`))
+
+	assert.Nil(t, err)
+	assert.Equal(t, "192.168.1.7", info.Host)
+	assert.Equal(t, "", info.AuthUser)
+	assert.Equal(t, time.Date(2020, 2, 9, 16, 27, 0, 123456789, time.UTC), info.LocalTime)
+	assert.Equal(t, HTTP{Method: "POST", Route: "/login", Version: "HTTP/1.1", Code: 404, Size: 17, Duration: 1500 * time.Microsecond}, info.Request)
+	assert.Equal(t, "Go-http-client/1.1", info.UserAgent)
+	assert.False(t, info.Has(AuthUserField))
+}
+
+func TestJSONFormatReadsEpochTimesOfAnyUnitAndStringNumbers(t *testing.T) {
+	fields, _ := JSONPreset("json")
+	f := NewJSONFormat(fields)
+	ref := time.Unix(1581265620, 0).UTC()
+
+	for _, ts := range []string{`1581265620`, `1581265620000`, `"1581265620000000"`, `1581265620000000000`, `"09/Feb/2020:16:27:00 +0000"`} {
+		info, err := f.Parse([]byte(`{"time":` + ts + `,"status":"200","uri":"/"}`))
+
+		assert.Nil(t, err, ts)
+		assert.True(t, ref.Equal(info.LocalTime), ts)
+		assert.Equal(t, uint32(200), info.Request.Code, ts)
+	}
+}
+
+func TestJSONFormatFlagsUnknownTimesAsMissing(t *testing.T) {
+	fields, _ := JSONPreset("json")
+	f := NewJSONFormat(fields)
+
+	for _, line := range []string{
+		`{"time":"-","status":200,"uri":"/"}`,
+		`{"time":"","status":200,"uri":"/"}`,
+		`{"time":null,"status":200,"uri":"/"}`,
+		`{"status":200,"uri":"/"}`,
+	} {
+		info, err := f.Parse([]byte(line))
+
+		assert.Nil(t, err, line)
+		assert.False(t, info.Has(TimeField), line)
+		assert.True(t, info.LocalTime.IsZero(), line)
+		assert.Equal(t, uint32(200), info.Request.Code, line)
+	}
+}
+
+func TestJSONFormatReturnsAnErrorOnInvalidLines(t *testing.T) {
+	fields, _ := JSONPreset("json")
+	f := NewJSONFormat(fields)
+
+	invalid := []string{
+		``,
+		`not json`,
+		`{"uri":"/"}`,
+		`{"status":"abc"}`,
+		`{"status":200,"time":"yesterday"}`,
+		`{"status":200,"size":-1}`,
+	}
+	for _, line := range invalid {
+		_, err := f.Parse([]byte(line))
+		assert.NotNil(t, err, line)
+	}
+}
+
+func TestJSONFieldsOverrideReplacesKeys(t *testing.T) {
+	fields, _ := JSONPreset("json")
+
+	custom, err := fields.Override("uri=request.path, status=response.code")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "request.path", custom.URI)
+	assert.Equal(t, "response.code", custom.Status)
+
+	custom, err = fields.Override("duration=elapsed,duration_unit=ms")
+	assert.Nil(t, err)
+	assert.Equal(t, "elapsed", custom.Duration)
+	assert.Equal(t, time.Millisecond, custom.DurationUnit)
+
+	_, err = fields.Override("duration_unit=h")
+	assert.NotNil(t, err)
+	assert.Equal(t, fields.Host, custom.Host)
+	assert.Equal(t, "uri", fields.URI)
+
+	_, err = fields.Override("foo=bar")
+	assert.NotNil(t, err)
+
+	_, err = fields.Override("uri")
+	assert.NotNil(t, err)
+}
+
+func TestLookupPrefersKeysContainingDots(t *testing.T) {
+	obj := map[string]interface{}{
+		"a.b": "flat",
+		"a":   map[string]interface{}{"b": "nested", "c": []interface{}{"first", "second"}},
+	}
+
+	assert.Equal(t, "flat", lookup(obj, "a.b"))
+	assert.Equal(t, "first", lookup(obj, "a.c"))
+	assert.Nil(t, lookup(obj, "a.d"))
+	assert.Nil(t, lookup(obj, ""))
+}
@@ -0,0 +1,110 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// AWS load balancers write space-delimited access logs, their first fields are:
+// Application Load Balancer (ALB)
+// http 2018-07-02T22:23:00.186641Z app/my-lb/50dc6c495c0c9188 192.168.131.39:2817 10.0.0.1:80 0.000 0.001 0.000 200 200 34 366 "GET http://www.example.com:80/ HTTP/1.1" "curl/7.46.0" ...
+// Classic Load Balancer (ELB)
+// 2015-05-13T23:39:43.945958Z my-lb 192.168.131.39:2817 10.0.0.1:80 0.000073 0.001048 0.000057 200 200 0 29 "GET http://www.example.com:80/ HTTP/1.1" "curl/7.38.0" - -
+// Where the orderly enumerated values correspond to :
+// - http is the type of request (ALB only)
+// - 2018-07-02T22:23:00.186641Z is the time the response was sent (RFC3339, UTC)
+// - app/my-lb/50dc6c495c0c9188 is the load balancer's id
+// - 192.168.131.39:2817 is the client's address
+// - 10.0.0.1:80 is the target's address ("-" if the request wasn't forwarded)
+// - 0.000 0.001 0.000 are the request, target and response processing times in
+// seconds (-1 if the request wasn't forwarded)
+// - 200 200 are the load balancer's and the target's status codes
+// - 34 366 are the received and sent bytes
+// - "GET http://www.example.com:80/ HTTP/1.1" is the request line, the URL is absolute
+// - "curl/7.46.0" is the client's user-agent
+// ALB logs hold more fields which are ignored.
+
+const (
+	// number of fields in an ELB line
+	elbNbFields int = 13
+)
+
+// ParseLoadBalancer reads an AWS ALB or ELB access log line and returns a properly
+// hydrated Info struct. The duration is the target's processing time. The slice
+// isn't retained so it can be reused by the caller once the function returns.
+func ParseLoadBalancer(data []byte) (Info, error) {
+	var buf [2 * maxFields]span
+	fields, err := tokenizeLine(data, buf[:0], elbNbFields)
+	if err != nil {
+		return Info{}, err
+	}
+
+	// ALB lines start with the request type whereas ELB ones start with the time
+	if _, err := time.Parse(time.RFC3339Nano, fields[0].text(data)); err != nil {
+		if len(fields) < elbNbFields+1 {
+			return Info{}, fmt.Errorf("log.ParseLoadBalancer error - expected at least %d fields, got %d", elbNbFields+1, len(fields))
+		}
+		fields = fields[1:]
+	}
+
+	localTime, err := time.Parse(time.RFC3339Nano, fields[0].text(data))
+	if err != nil {
+		return Info{}, err
+	}
+
+	httpReq, missing, err := parseHTTP(fields[11].text(data), fields[7].bytes(data), fields[10].bytes(data))
+	if err != nil {
+		return Info{}, err
+	}
+	httpReq.Route = relativeURL(httpReq.Route)
+
+	info := Info{
+		LocalTime: localTime,
+		Request:   httpReq,
+		UserAgent: parseUserAgent(fields[12].text(data)),
+		// Load balancers don't log users nor referers
+		Missing: missing | AuthUserField | RefererField,
+	}
+	info.setHost(clientHost(fields[2].text(data)))
+	if info.UserAgent == "" {
+		info.Missing |= UserAgentField
+	}
+
+	// -1 if the load balancer couldn't forward the request
+	duration := fields[5].text(data)
+	if duration == "-1" || duration == "-" {
+		info.Missing |= DurationField
+	} else if info.Request.Duration, err = parseUnits(duration, time.Second); err != nil {
+		return Info{}, fmt.Errorf("log.ParseLoadBalancer error - invalid processing time %q", duration)
+	}
+
+	return info, nil
+}
+
+// clientHost removes the port from a client's address (192.168.131.39:2817)
+func clientHost(address string) string {
+	if i := strings.LastIndexByte(address, ':'); i >= 0 {
+		return address[:i]
+	}
+	return address
+}
+
+// relativeURL removes the scheme and the host of an absolute URL
+// (http://www.example.com:80/index.html becomes /index.html)
+func relativeURL(url string) string {
+	i := strings.Index(url, "://")
+	if i < 0 {
+		return url
+	}
+
+	url = url[i+3:]
+	path := strings.IndexAny(url, "/?")
+	if path < 0 {
+		return "/"
+	}
+	if url[path] == '?' {
+		return "/" + url[path:]
+	}
+	return url[path:]
+}
@@ -0,0 +1,62 @@
+package log
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLoadBalancerReadsALBLines(t *testing.T) {
+	// Setup stage
+	const line string = `https 2018-07-02T22:23:00.186641Z app/my-loadbalancer/50dc6c495c0c9188 192.168.131.39:2817 10.0.0.1:80 0.086 0.048 0.037 200 200 0 57 "GET https://www.example.com:443/api/users?page=2 HTTP/1.1" "curl/7.46.0" ECDHE-RSA-AES128-GCM-SHA256 TLSv1.2 arn:aws:elasticloadbalancing:us-east-2:123456789012:targetgroup/my-targets/73e2d6bc24d8a067 "Root=1-58337281-1d84f3d73c47ec4e58577259" "www.example.com" "arn:aws:acm:us-east-2:123456789012:certificate/12345678-1234-1234-1234-123456789012" 1 2018-07-02T22:22:48.364000Z "authenticate,forward" "-" "-" "10.0.0.1:80" "200" "-" "-"`
+
+	// Exercise stage
+	info, err := ParseLoadBalancer([]byte(line))
+
+	// Validation stage
+	assert.Nil(t, err)
+	assert.Equal(t, "192.168.131.39", info.Host)
+	assert.Equal(t, time.Date(2018, 7, 2, 22, 23, 0, 186641000, time.UTC), info.LocalTime)
+	assert.Equal(t, HTTP{Method: "GET", Route: "/api/users", Query: "page=2", Version: "HTTP/1.1", Code: 200, Size: 57, Duration: 48 * time.Millisecond}, info.Request)
+	assert.Equal(t, "curl/7.46.0", info.UserAgent)
+	assert.Equal(t, AuthUserField|RefererField, info.Missing)
+}
+
+func TestParseLoadBalancerReadsELBLines(t *testing.T) {
+	const line string = `2015-05-13T23:39:43.945958Z my-loadbalancer 192.168.131.39:2817 - -1 -1 -1 503 0 0 0 "GET http://www.example.com:80 HTTP/1.1" "-" - -`
+
+	info, err := ParseLoadBalancer([]byte(line))
+
+	assert.Nil(t, err)
+	assert.Equal(t, "192.168.131.39", info.Host)
+	assert.Equal(t, time.Date(2015, 5, 13, 23, 39, 43, 945958000, time.UTC), info.LocalTime)
+	assert.Equal(t, HTTP{Method: "GET", Route: "/", Version: "HTTP/1.1", Code: 503}, info.Request)
+	assert.Equal(t, AuthUserField|RefererField|UserAgentField|DurationField, info.Missing)
+}
+
+func TestParseLoadBalancerReturnsAnErrorOnInvalidLines(t *testing.T) {
+	for _, line := range []string{
+		``,
+		`http 2018-07-02T22:23:00.186641Z app/my-lb 192.168.131.39:2817`,
+		`http yesterday app/my-lb 192.168.131.39:2817 10.0.0.1:80 0.000 0.001 0.000 200 200 34 366 "GET / HTTP/1.1" "curl/7.46.0"`,
+		`2015-05-13T23:39:43.945958Z my-lb 192.168.131.39:2817 10.0.0.1:80 0.000 fast 0.000 200 200 0 29 "GET / HTTP/1.1" "curl/7.38.0" - -`,
+		`172.17.0.1 - - [09/Feb/2020:16:27:00 +0000] "GET / HTTP/1.1" 200 612 "-" "curl/7.54.0"`,
+	} {
+		_, err := ParseLoadBalancer([]byte(line))
+		assert.NotNil(t, err, line)
+	}
+}
+
+func TestRelativeURLRemovesSchemeAndHost(t *testing.T) {
+	urls := map[string]string{
+		"http://www.example.com:80/index.html": "/index.html",
+		"https://example.com?q=1":              "/?q=1",
+		"http://example.com":                   "/",
+		"/already/relative":                    "/already/relative",
+	}
+
+	for url, expected := range urls {
+		assert.Equal(t, expected, relativeURL(url), url)
+	}
+}
@@ -2,7 +2,7 @@ package log
 
 import (
 	"fmt"
-	"strconv"
+	"math"
 	"strings"
 	"time"
 )
@@ -22,47 +22,150 @@ import (
 // - HTTP/1.1 is the protocol version
 // - 200 is the http-return-code
 // - 612 is the served-content's size
+// Optional fields can hold a "-" placeholder, for instance the size of a 304
+// response or the request line of a connection closed before sending a request.
+// Such fields are flagged in Info.Missing instead of making the parsing fail.
 // Please bear in mind that additional, non-standard information can be provided
 // by servers.
+//
+// The Combined Log Format appends two quoted fields to the common one :
+// 172.17.0.1 - - [09/Feb/2020:16:27:00 +0000] "GET / HTTP/1.1" 200 612 "http://example.com/" "curl/7.54.0"
+// - "http://example.com/" is the referer ("-" if none)
+// - "curl/7.54.0" is the client's user-agent
+
+const (
+	// number of fields in a common-log-formatted line
+	commonLogFormatNbFields int = 7
+	// number of fields in a combined-log-formatted line
+	combinedLogFormatNbFields int = 9
+)
 
 // Parse reads a log string and returns a properly hydrated Info struct
 func Parse(line string) (Info, error) {
-	if line == "" {
-		return Info{}, fmt.Errorf("log.Parse error - empty line")
+	return ParseBytes([]byte(line))
+}
+
+// ParseBytes is like Parse but reads the line from a byte slice. The slice isn't
+// retained so it can be reused by the caller once the function returns.
+func ParseBytes(data []byte) (Info, error) {
+	var buf [maxFields]span
+	fields, err := tokenizeLine(data, buf[:0], commonLogFormatNbFields)
+	if err != nil {
+		return Info{}, err
+	}
+
+	info, err := parseCommonFields(data, fields)
+	if err != nil {
+		return Info{}, err
+	}
+
+	// The common log format doesn't provide these fields
+	info.Missing |= RefererField | UserAgentField | DurationField
+
+	return info, nil
+}
+
+// ParseCombined reads a log string following the Combined Log Format
+// and returns a properly hydrated Info struct
+func ParseCombined(line string) (Info, error) {
+	return ParseCombinedBytes([]byte(line))
+}
+
+// ParseCombinedBytes is like ParseCombined but reads the line from a byte slice.
+// The slice isn't retained so it can be reused by the caller once the function returns.
+func ParseCombinedBytes(data []byte) (Info, error) {
+	var buf [maxFields]span
+	fields, err := tokenizeLine(data, buf[:0], combinedLogFormatNbFields)
+	if err != nil {
+		return Info{}, err
+	}
+
+	info, err := parseCommonFields(data, fields)
+	if err != nil {
+		return Info{}, err
+	}
+
+	// The combined log format doesn't provide the request duration
+	info.Missing |= DurationField
+
+	info.Referer = parseReferer(fields[7].text(data))
+	if info.Referer == "" {
+		info.Missing |= RefererField
+	}
+
+	info.UserAgent = parseUserAgent(fields[8].text(data))
+	if info.UserAgent == "" {
+		info.Missing |= UserAgentField
+	}
+
+	return info, nil
+}
+
+// tokenizeLine tokenizes data and checks at least minFields have been found
+func tokenizeLine(data []byte, buf []span, minFields int) ([]span, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("log.Parse error - empty line")
+	}
+
+	fields, err := tokenize(data, buf)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(fields) < minFields {
+		return nil, fmt.Errorf("log.Parse error - expected at least %d fields, got %d", minFields, len(fields))
 	}
 
-	fields := strings.Split(line, " ")
+	return fields, nil
+}
 
-	localTime, err := parseLocalTime(strings.Join(fields[3:5], " "))
+// parseCommonFields hydrates an Info struct from the first tokenized fields of
+// a common-log-formatted line
+func parseCommonFields(data []byte, fields []span) (Info, error) {
+	localTime, err := parseLocalTime(string(fields[3].bytes(data)))
 	if err != nil {
 		return Info{}, err
 	}
 
-	httpReq, err := parseHTTP(fields[5:10])
+	httpReq, missing, err := parseHTTP(fields[4].text(data), fields[5].bytes(data), fields[6].bytes(data))
 	if err != nil {
 		return Info{}, err
 	}
 
 	info := Info{
-		Host:      parseHost(fields[0]),
-		LogUser:   parseRFC931User(fields[1]),
-		AuthUser:  parseAuthUser(fields[2]),
+		LogUser:   parseRFC931User(fields[1].bytes(data)),
+		AuthUser:  parseAuthUser(fields[2].text(data)),
 		LocalTime: localTime,
 		Request:   httpReq,
+		Missing:   missing,
+	}
+	info.setHost(string(fields[0].bytes(data)))
+	if info.AuthUser == "" {
+		info.Missing |= AuthUserField
 	}
 
 	return info, nil
 }
 
-func parseHost(field string) string {
+func parseRFC931User(field []byte) (user RFC931User) {
+	return
+}
+
+func parseAuthUser(field string) string {
+	if field == "-" {
+		return ""
+	}
 	return field
 }
 
-func parseRFC931User(field string) (user RFC931User) {
-	return
+func parseReferer(field string) string {
+	if field == "-" {
+		return ""
+	}
+	return field
 }
 
-func parseAuthUser(field string) string {
+func parseUserAgent(field string) string {
 	if field == "-" {
 		return ""
 	}
@@ -72,7 +175,6 @@ func parseAuthUser(field string) string {
 func parseLocalTime(field string) (time.Time, error) {
 	const commonLogFormat string = `02/Jan/2006:15:04:05 -0700`
 
-	field = field[1 : len(field)-1]
 	t, err := time.Parse(commonLogFormat, field)
 	if err != nil {
 		return time.Time{}, err
@@ -80,22 +182,90 @@ func parseLocalTime(field string) (time.Time, error) {
 	return t, nil
 }
 
-func parseHTTP(fields []string) (HTTP, error) {
-	code, err := strconv.Atoi(fields[3])
+// parseHTTP reads the request line ("GET / HTTP/1.1"), the return code and the
+// content size. The request line and the size are optional, when unknown ("-")
+// they are flagged in the returned Field.
+func parseHTTP(request string, codeField, sizeField []byte) (HTTP, Field, error) {
+	var missing Field
+
+	code, err := parseUint(codeField)
 	if err != nil {
-		return HTTP{}, err
+		return HTTP{}, 0, err
 	}
 
-	size, err := strconv.Atoi(fields[4])
-	if err != nil {
-		return HTTP{}, err
+	httpReq := HTTP{Code: uint32(code)}
+
+	if request == "-" {
+		missing |= RequestField
+	} else {
+		var uri string
+		httpReq.Method, uri, httpReq.Version, err = parseRequestLine(request)
+		if err != nil {
+			return HTTP{}, 0, err
+		}
+		httpReq.setURI(uri)
+	}
+
+	if isPlaceholder(sizeField) {
+		missing |= SizeField
+	} else {
+		httpReq.Size, err = parseUint(sizeField)
+		if err != nil {
+			return HTTP{}, 0, err
+		}
+	}
+
+	return httpReq, missing, nil
+}
+
+// isPlaceholder returns true if a field holds the "-" placeholder used
+// by web servers for unknown values
+func isPlaceholder(field []byte) bool {
+	return len(field) == 1 && field[0] == '-'
+}
+
+// parseRequestLine splits a request line into a method, a route and a protocol
+// version. The version is optional ("GET /" is valid) and routes containing
+// spaces are supported as long as the version starts with "HTTP/".
+func parseRequestLine(request string) (method, route, version string, err error) {
+	first := strings.IndexByte(request, ' ')
+	if first <= 0 {
+		return "", "", "", fmt.Errorf("log.Parse error - malformed request line %q", request)
+	}
+	method = request[:first]
+	rest := strings.TrimLeft(request[first+1:], " ")
+
+	if last := strings.LastIndexByte(rest, ' '); last >= 0 && strings.HasPrefix(rest[last+1:], "HTTP/") {
+		version = rest[last+1:]
+		rest = strings.TrimRight(rest[:last], " ")
+	}
+
+	if rest == "" {
+		return "", "", "", fmt.Errorf("log.Parse error - malformed request line %q", request)
+	}
+	route = rest
+
+	return method, route, version, nil
+}
+
+// parseUint reads a decimal unsigned integer without allocating
+func parseUint(field []byte) (uint64, error) {
+	if len(field) == 0 {
+		return 0, fmt.Errorf("log.Parse error - empty number")
+	}
+
+	var n uint64
+	for _, c := range field {
+		if c < '0' || c > '9' {
+			return 0, fmt.Errorf("log.Parse error - invalid number %q", field)
+		}
+
+		d := uint64(c - '0')
+		if n > (math.MaxUint64-d)/10 {
+			return 0, fmt.Errorf("log.Parse error - number %q is out of range", field)
+		}
+		n = n*10 + d
 	}
 
-	return HTTP{
-		Method:  strings.Trim(fields[0], `"`),
-		Route:   fields[1],
-		Version: strings.Trim(fields[2], `"`),
-		Code:    uint32(code),
-		Size:    uint64(size),
-	}, nil
+	return n, nil
 }
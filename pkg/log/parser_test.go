@@ -61,3 +61,174 @@ func TestParseReturnsAnErrorIfTheInputStringIsEmpty(t *testing.T) {
 	_, err := Parse("")
 	assert.NotNil(t, err)
 }
+
+func TestParseReturnsAnErrorIfALineHasMissingFields(t *testing.T) {
+	_, err := Parse(`172.17.0.1 - - [09/Feb/2020:16:27:00 +0000] "GET / HTTP/1.1" 200`)
+	assert.NotNil(t, err)
+}
+
+func TestParseCombinedReturnsValidStructFromStandardEntry(t *testing.T) {
+	// Setup stage
+	const logEntry string = `83.149.9.216 - frank [17/May/2015:10:05:03 +0000] "GET /presentations/kibana-search.png HTTP/1.1" 200 203023 "http://semicomplete.com/presentations/" "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_9_1) AppleWebKit/537.36"`
+	const timeFormat string = `02/Jan/2006:15:04:05 -0700`
+	refTime, err := time.Parse(timeFormat, "17/May/2015:10:05:03 +0000")
+	if err != nil {
+		panic(err)
+	}
+
+	// Exercise
+	info, err := ParseCombined(logEntry)
+
+	// Validation
+	assert.Nil(t, err)
+	assert.Equal(t, "83.149.9.216", info.Host)
+	assert.Equal(t, "frank", info.AuthUser)
+	assert.Equal(t, refTime, info.LocalTime)
+	assert.Equal(t, "GET", info.Request.Method)
+	assert.Equal(t, "/presentations/kibana-search.png", info.Request.Route)
+	assert.Equal(t, uint32(200), info.Request.Code)
+	assert.Equal(t, uint64(203023), info.Request.Size)
+	assert.Equal(t, "HTTP/1.1", info.Request.Version)
+	assert.Equal(t, "http://semicomplete.com/presentations/", info.Referer)
+	assert.Equal(t, "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_9_1) AppleWebKit/537.36", info.UserAgent)
+}
+
+func TestParseCombinedReturnsEmptyRefererAndUserAgentForPlaceholders(t *testing.T) {
+	info, err := ParseCombined(`172.17.0.1 - - [09/Feb/2020:16:27:00 +0000] "GET / HTTP/1.1" 200 612 "-" "-"`)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "", info.Referer)
+	assert.Equal(t, "", info.UserAgent)
+}
+
+func TestParseCombinedReturnsAnErrorOnCommonLogFormatLines(t *testing.T) {
+	_, err := ParseCombined(`172.17.0.1 - - [09/Feb/2020:16:27:00 +0000] "GET / HTTP/1.1" 200 612`)
+	assert.NotNil(t, err)
+}
+
+func TestParseAcceptsRequestLinesWithoutProtocol(t *testing.T) {
+	info, err := Parse(`172.17.0.1 - - [09/Feb/2020:16:27:00 +0000] "GET /" 200 612`)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "GET", info.Request.Method)
+	assert.Equal(t, "/", info.Request.Route)
+	assert.Equal(t, "", info.Request.Version)
+	assert.Equal(t, uint32(200), info.Request.Code)
+}
+
+func TestParseAcceptsRoutesContainingSpaces(t *testing.T) {
+	info, err := Parse(`172.17.0.1 - - [09/Feb/2020:16:27:00 +0000] "GET /my file.html HTTP/1.0" 404 12`)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "/my file.html", info.Request.Route)
+	assert.Equal(t, "HTTP/1.0", info.Request.Version)
+}
+
+func TestParseCombinedDecodesEscapedQuotes(t *testing.T) {
+	info, err := ParseCombined(`172.17.0.1 - - [09/Feb/2020:16:27:00 +0000] "GET /?q=\"a\" HTTP/1.1" 200 612 "-" "say \"hi\" \x5C"`)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "/", info.Request.Route)
+	assert.Equal(t, `q="a"`, info.Request.Query)
+	assert.Equal(t, `say "hi" \`, info.UserAgent)
+}
+
+func TestParseReturnsAnErrorOnInvalidCodesAndSizes(t *testing.T) {
+	_, err := Parse(`172.17.0.1 - - [09/Feb/2020:16:27:00 +0000] "GET / HTTP/1.1" 2x0 612`)
+	assert.NotNil(t, err)
+
+	_, err = Parse(`172.17.0.1 - - [09/Feb/2020:16:27:00 +0000] "GET / HTTP/1.1" 200 99999999999999999999999`)
+	assert.NotNil(t, err)
+}
+
+func TestParseBytesDoesntRetainItsInput(t *testing.T) {
+	data := []byte(`172.17.0.1 - bob [09/Feb/2020:16:27:00 +0000] "GET /a HTTP/1.1" 200 612`)
+
+	info, err := ParseBytes(data)
+	for i := range data {
+		data[i] = 'x'
+	}
+
+	assert.Nil(t, err)
+	assert.Equal(t, "172.17.0.1", info.Host)
+	assert.Equal(t, "bob", info.AuthUser)
+	assert.Equal(t, "/a", info.Request.Route)
+}
+
+func BenchmarkParseBytes(b *testing.B) {
+	data := []byte(`83.149.9.216 - - [17/May/2015:10:05:03 +0000] "GET /presentations/logstash-monitorama-2013/images/kibana-search.png HTTP/1.1" 200 203023 "http://semicomplete.com/presentations/logstash-monitorama-2013/" "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_9_1) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/32.0.1700.77 Safari/537.36"`)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := ParseCombinedBytes(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestParseFlagsAnUnknownSizeAsMissing(t *testing.T) {
+	info, err := Parse(`172.17.0.1 - - [09/Feb/2020:16:27:00 +0000] "GET / HTTP/1.1" 304 -`)
+
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(304), info.Request.Code)
+	assert.Equal(t, uint64(0), info.Request.Size)
+	assert.False(t, info.Has(SizeField))
+	assert.True(t, info.Has(RequestField))
+}
+
+func TestParseFlagsAnUnknownRequestLineAsMissing(t *testing.T) {
+	info, err := Parse(`172.17.0.1 - - [09/Feb/2020:16:27:00 +0000] "-" 400 0`)
+
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(400), info.Request.Code)
+	assert.Equal(t, HTTP{Code: 400}, info.Request)
+	assert.False(t, info.Has(RequestField))
+	assert.True(t, info.Has(SizeField))
+}
+
+func TestParseReturnsAnErrorOnMalformedRequestLines(t *testing.T) {
+	for _, request := range []string{"GARBAGE", "", "GET", "GET ", " /"} {
+		_, err := Parse(`172.17.0.1 - - [09/Feb/2020:16:27:00 +0000] "` + request + `" 400 0`)
+		assert.NotNil(t, err, request)
+	}
+}
+
+func TestParseFlagsAuthUserRefererAndUserAgentAsMissing(t *testing.T) {
+	info, err := Parse(`172.17.0.1 - - [09/Feb/2020:16:27:00 +0000] "GET / HTTP/1.1" 200 612`)
+
+	assert.Nil(t, err)
+	assert.False(t, info.Has(AuthUserField))
+	assert.False(t, info.Has(RefererField))
+	assert.False(t, info.Has(UserAgentField))
+	assert.False(t, info.Has(DurationField))
+
+	info, err = ParseCombined(`172.17.0.1 - bob [09/Feb/2020:16:27:00 +0000] "GET / HTTP/1.1" 200 612 "-" "curl/7.54.0"`)
+
+	assert.Nil(t, err)
+	assert.True(t, info.Has(AuthUserField|UserAgentField))
+	assert.False(t, info.Has(RefererField))
+	assert.False(t, info.Has(RefererField|UserAgentField))
+}
+
+func TestParseSplitsTheQueryStringFromTheRoute(t *testing.T) {
+	uris := map[string]HTTP{
+		"/":                   {Route: "/"},
+		"/?a=1":               {Route: "/", Query: "a=1"},
+		"/search?q=go&page=2": {Route: "/search", Query: "q=go&page=2"},
+		"/doc#intro":          {Route: "/doc"},
+		"/doc?v=2#intro":      {Route: "/doc", Query: "v=2"},
+		"/weird?a=1?b=2":      {Route: "/weird", Query: "a=1?b=2"},
+	}
+
+	for uri, expected := range uris {
+		info, err := Parse(`172.17.0.1 - - [09/Feb/2020:16:27:00 +0000] "GET ` + uri + ` HTTP/1.1" 200 612`)
+
+		assert.Nil(t, err, uri)
+		assert.Equal(t, expected.Route, info.Request.Route, uri)
+		assert.Equal(t, expected.Query, info.Request.Query, uri)
+	}
+
+	req := HTTP{Route: "/search", Query: "q=go&page=2"}
+	assert.Equal(t, "/search?q=go&page=2", req.URI())
+	assert.Equal(t, "2", req.QueryValues().Get("page"))
+}
@@ -0,0 +1,170 @@
+package log
+
+import (
+	"fmt"
+)
+
+// span is a tokenized field. It refers to the [start, end) byte-interval of the
+// tokenized line so that no copy is made while tokenizing.
+type span struct {
+	start   int
+	end     int
+	escaped bool // true if the field contains backslash-escaped sequences
+}
+
+// tokenizer states
+type tokenizerState uint8
+
+const (
+	stateSeparator tokenizerState = iota // between two fields
+	stateBare                            // in a field delimited by separators
+	stateQuoted                          // in a double-quoted field
+	stateEscape                          // right after a backslash in a double-quoted field
+	stateBracket                         // in a bracketed field
+)
+
+// maxFields is the number of fields that can be tokenized without allocating
+// (spans are stored in a caller-provided array)
+const maxFields int = 16
+
+// tokenize splits a log line into fields using a state machine. Fields are
+// separated by spaces or tabs, except when they are enclosed in double-quotes
+// ("GET / HTTP/1.1") or brackets ([09/Feb/2020:16:27:00 +0000]). In double-quoted
+// fields, a backslash escapes the next character so that \" doesn't end the field.
+// Enclosing characters are not part of the returned spans.
+// The spans are appended to fields which should be given enough capacity to
+// avoid allocations. An error is returned if a quoted or bracketed field isn't
+// terminated.
+func tokenize(data []byte, fields []span) ([]span, error) {
+	state := stateSeparator
+	current := span{}
+
+	// Ignore line terminators
+	end := len(data)
+	for end > 0 && (data[end-1] == '\n' || data[end-1] == '\r') {
+		end--
+	}
+
+	for i := 0; i < end; i++ {
+		c := data[i]
+
+		switch state {
+		case stateSeparator:
+			switch c {
+			case ' ', '\t':
+			case '"':
+				current = span{start: i + 1}
+				state = stateQuoted
+			case '[':
+				current = span{start: i + 1}
+				state = stateBracket
+			default:
+				current = span{start: i}
+				state = stateBare
+			}
+
+		case stateBare:
+			if c == ' ' || c == '\t' {
+				current.end = i
+				fields = append(fields, current)
+				state = stateSeparator
+			}
+
+		case stateQuoted:
+			switch c {
+			case '\\':
+				current.escaped = true
+				state = stateEscape
+			case '"':
+				current.end = i
+				fields = append(fields, current)
+				state = stateSeparator
+			}
+
+		case stateEscape:
+			state = stateQuoted
+
+		case stateBracket:
+			if c == ']' {
+				current.end = i
+				fields = append(fields, current)
+				state = stateSeparator
+			}
+		}
+	}
+
+	switch state {
+	case stateBare:
+		current.end = end
+		fields = append(fields, current)
+
+	case stateQuoted, stateEscape:
+		return fields, fmt.Errorf("log.tokenize error - unterminated quoted field at offset %d", current.start-1)
+
+	case stateBracket:
+		return fields, fmt.Errorf("log.tokenize error - unterminated bracketed field at offset %d", current.start-1)
+	}
+
+	return fields, nil
+}
+
+// bytes returns the span's raw content
+func (s span) bytes(data []byte) []byte {
+	return data[s.start:s.end]
+}
+
+// text returns the span's content as a string, escaped sequences are decoded
+func (s span) text(data []byte) string {
+	if !s.escaped {
+		return string(data[s.start:s.end])
+	}
+	return unescape(data[s.start:s.end])
+}
+
+// unescape decodes backslash-escaped sequences such as the ones written by
+// Apache's httpd (\" and \\) and nginx (\x22).
+func unescape(field []byte) string {
+	out := make([]byte, 0, len(field))
+
+	for i := 0; i < len(field); i++ {
+		if field[i] != '\\' || i+1 == len(field) {
+			out = append(out, field[i])
+			continue
+		}
+
+		i++
+		switch field[i] {
+		case 'n':
+			out = append(out, '\n')
+		case 't':
+			out = append(out, '\t')
+		case 'x':
+			if i+2 < len(field) {
+				hi, okHi := fromHex(field[i+1])
+				lo, okLo := fromHex(field[i+2])
+				if okHi && okLo {
+					out = append(out, hi<<4|lo)
+					i += 2
+					continue
+				}
+			}
+			out = append(out, '\\', 'x')
+		default:
+			out = append(out, field[i])
+		}
+	}
+
+	return string(out)
+}
+
+func fromHex(c byte) (byte, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0', true
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10, true
+	case 'A' <= c && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
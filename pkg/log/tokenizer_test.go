@@ -0,0 +1,125 @@
+package log
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// texts returns the content of all spans
+func texts(data []byte, fields []span) []string {
+	out := make([]string, 0, len(fields))
+	for _, f := range fields {
+		out = append(out, f.text(data))
+	}
+	return out
+}
+
+func TestTokenizeKeepsQuotedAndBracketedFieldsTogether(t *testing.T) {
+	data := []byte(`1.2.3.4 - - [09/Feb/2020:16:27:00 +0000] "GET / HTTP/1.1" 200 612 "-" "curl/7.54.0 (x86_64)"`)
+
+	fields, err := tokenize(data, nil)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"1.2.3.4", "-", "-", "09/Feb/2020:16:27:00 +0000", "GET / HTTP/1.1", "200", "612", "-", "curl/7.54.0 (x86_64)",
+	}, texts(data, fields))
+}
+
+func TestTokenizeHandlesEscapedQuotes(t *testing.T) {
+	data := []byte(`a "b \"c\" \\" d`)
+
+	fields, err := tokenize(data, nil)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a", `b "c" \`, "d"}, texts(data, fields))
+}
+
+func TestTokenizeHandlesEmptyQuotedFieldsAndRepeatedSeparators(t *testing.T) {
+	data := []byte("a  \"\"\t[] b\r\n")
+
+	fields, err := tokenize(data, nil)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a", "", "", "b"}, texts(data, fields))
+}
+
+func TestTokenizeReturnsAnErrorOnUnterminatedFields(t *testing.T) {
+	_, err := tokenize([]byte(`a "b c`), nil)
+	assert.NotNil(t, err)
+
+	_, err = tokenize([]byte(`a "b c\"`), nil)
+	assert.NotNil(t, err)
+
+	_, err = tokenize([]byte(`a [b c`), nil)
+	assert.NotNil(t, err)
+}
+
+func TestTokenizeDoesntAllocateWithEnoughCapacity(t *testing.T) {
+	data := []byte(`172.17.0.1 - - [09/Feb/2020:16:27:00 +0000] "GET / HTTP/1.1" 200 612 "-" "curl/7.54.0"`)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		var buf [maxFields]span
+		if _, err := tokenize(data, buf[:0]); err != nil {
+			panic(err)
+		}
+	})
+
+	assert.Equal(t, float64(0), allocs)
+}
+
+// fuzzAlphabet contains all characters having a meaning for the tokenizer
+const fuzzAlphabet string = ` "[]\\-x0/` + "\t\r\n"
+
+func TestTokenizeFuzzNeverPanicsAndReturnsValidSpans(t *testing.T) {
+	rnd := rand.New(rand.NewSource(42))
+
+	for i := 0; i < 20000; i++ {
+		data := make([]byte, rnd.Intn(40))
+		for j := range data {
+			data[j] = fuzzAlphabet[rnd.Intn(len(fuzzAlphabet))]
+		}
+
+		fields, _ := tokenize(data, nil)
+		for _, f := range fields {
+			if f.start < 0 || f.end < f.start || f.end > len(data) {
+				t.Fatalf("invalid span %+v for input %q", f, data)
+			}
+			_ = f.text(data)
+		}
+
+		// The parsers must reject garbage without panicking
+		_, _ = ParseBytes(data)
+		_, _ = ParseCombinedBytes(data)
+	}
+}
+
+func TestTokenizeFuzzRoundTripsQuotedFields(t *testing.T) {
+	rnd := rand.New(rand.NewSource(7))
+	escaper := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+	for i := 0; i < 5000; i++ {
+		values := make([]string, 1+rnd.Intn(6))
+		var line strings.Builder
+		for j := range values {
+			value := make([]byte, rnd.Intn(12))
+			for k := range value {
+				value[k] = fuzzAlphabet[rnd.Intn(len(fuzzAlphabet)-3)]
+			}
+			values[j] = string(value)
+
+			if j > 0 {
+				line.WriteByte(' ')
+			}
+			line.WriteString(`"` + escaper.Replace(values[j]) + `"`)
+		}
+
+		data := []byte(line.String())
+		fields, err := tokenize(data, nil)
+
+		assert.Nil(t, err, "input %q", data)
+		assert.Equal(t, values, texts(data, fields), "input %q", data)
+	}
+}
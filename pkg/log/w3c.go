@@ -0,0 +1,299 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The W3C Extended Log File Format is the format used by Microsoft IIS. Lines
+// starting with a # are directives, the #Fields directive lists the fields of
+// the following lines. It can change in the middle of a file.
+// #Software: Microsoft Internet Information Services 10.0
+// #Version: 1.0
+// #Date: 2020-02-09 16:27:00
+// #Fields: date time s-ip cs-method cs-uri-stem cs-uri-query s-port cs-username c-ip cs(User-Agent) cs(Referer) sc-status sc-substatus sc-win32-status time-taken
+// 2020-02-09 16:27:00 10.0.0.1 GET /index.html page=2 80 - 192.168.1.1 Mozilla/5.0+(Windows+NT+10.0) - 200 0 0 15
+// Fields are separated by spaces, spaces in values are replaced by '+'.
+// Unknown values are logged as "-". Times are UTC.
+//
+// AWS CloudFront logs follow the same format with a few differences :
+// - fields are separated by tabs
+// - values are URL-encoded (Mozilla/5.0%20(Windows%20NT%2010.0))
+// - time-taken is in seconds (0.001)
+// - the protocol version is logged in cs-protocol-version
+
+// ErrDirective is returned by stateful parsers when a line is a directive (such
+// as W3C's #Fields) instead of a log. Readers skip such lines.
+var ErrDirective = errors.New("log.Parse - directive line")
+
+// IISDefaultFields are the fields logged by IIS by default. They are used until
+// a #Fields directive is read.
+const IISDefaultFields string = "date time s-ip cs-method cs-uri-stem cs-uri-query s-port cs-username c-ip cs(User-Agent) cs(Referer) sc-status sc-substatus sc-win32-status time-taken"
+
+// CloudFrontDefaultFields are the fields of CloudFront's standard logs. They are
+// used until a #Fields directive is read.
+const CloudFrontDefaultFields string = "date time x-edge-location sc-bytes c-ip cs-method cs(Host) cs-uri-stem sc-status cs(Referer) cs(User-Agent) cs-uri-query cs(Cookie) x-edge-result-type x-edge-request-id x-host-header cs-protocol cs-bytes time-taken x-forwarded-for ssl-protocol ssl-cipher x-edge-response-result-type cs-protocol-version fle-status fle-encrypted-fields c-port time-to-first-byte x-edge-detailed-result-type sc-content-type sc-content-len sc-range-start sc-range-end"
+
+// w3cField identifies a W3C field mapped into Info
+type w3cField int
+
+const (
+	w3cIgnored w3cField = iota
+	w3cDate
+	w3cTime
+	w3cClientIP
+	w3cUsername
+	w3cMethod
+	w3cURIStem
+	w3cURIQuery
+	w3cURI
+	w3cVersion
+	w3cStatus
+	w3cBytes
+	w3cReferer
+	w3cUserAgent
+	w3cTimeTaken
+	w3cForwardedFor
+	nbW3CFields
+)
+
+// w3cFields maps W3C field identifiers (lower-cased) to Info fields
+var w3cFields = map[string]w3cField{
+	"date":                w3cDate,
+	"time":                w3cTime,
+	"c-ip":                w3cClientIP,
+	"cs-username":         w3cUsername,
+	"cs-method":           w3cMethod,
+	"cs-uri-stem":         w3cURIStem,
+	"cs-uri-query":        w3cURIQuery,
+	"cs-uri":              w3cURI,
+	"cs-version":          w3cVersion,
+	"cs-protocol-version": w3cVersion,
+	"sc-status":           w3cStatus,
+	"sc-bytes":            w3cBytes,
+	"cs(referer)":         w3cReferer,
+	"cs(user-agent)":      w3cUserAgent,
+	"time-taken":          w3cTimeTaken,
+	"x-forwarded-for":     w3cForwardedFor,
+	"cs(x-forwarded-for)": w3cForwardedFor,
+}
+
+// W3C parses W3C Extended logs. It is stateful, it keeps track of the #Fields
+// directives so all the lines of a file must be given to the same W3C parser.
+// It is safe for concurrent use.
+type W3C struct {
+	// TimeTakenUnit is the unit of the time-taken field. IIS logs milliseconds
+	// whereas the W3C draft specifies seconds.
+	TimeTakenUnit time.Duration
+	// URLEncoded is true if values are URL-encoded (CloudFront) instead of
+	// having their spaces replaced by '+' (IIS)
+	URLEncoded bool
+
+	// required is a field the #Fields directives must list, it tells apart
+	// formats such as CloudFront from other W3C logs
+	required string
+
+	mutex  sync.Mutex
+	fields []w3cField // nil if the last #Fields directive lacks the required field
+	date   string     // date of the #Date directive, used when lines have no date field
+}
+
+// NewW3C returns a parser reading IIS logs, using IISDefaultFields until a
+// #Fields directive is read
+func NewW3C() *W3C {
+	w := &W3C{TimeTakenUnit: time.Millisecond}
+	w.SetFields(IISDefaultFields)
+	return w
+}
+
+// NewCloudFront returns a parser reading CloudFront logs, using CloudFrontDefaultFields
+// until a #Fields directive is read
+func NewCloudFront() *W3C {
+	w := &W3C{TimeTakenUnit: time.Second, URLEncoded: true, required: "x-edge-location"}
+	w.SetFields(CloudFrontDefaultFields)
+	return w
+}
+
+// SetFields sets the fields of the following lines. fields is the content of
+// a #Fields directive. Lines are rejected until the next call if fields lacks
+// a field required by the format (x-edge-location for CloudFront).
+func (w *W3C) SetFields(fields string) {
+	ids := strings.Fields(fields)
+	parsed := make([]w3cField, len(ids))
+	found := w.required == ""
+	for i, id := range ids {
+		id = strings.ToLower(id)
+		parsed[i] = w3cFields[id]
+		found = found || id == w.required
+	}
+	if !found {
+		parsed = nil
+	}
+
+	w.mutex.Lock()
+	w.fields = parsed
+	w.mutex.Unlock()
+}
+
+// Parse reads a W3C Extended log line and returns a properly hydrated Info struct.
+// Directive lines update the parser's state and return ErrDirective. The slice
+// isn't retained so it can be reused by the caller once the function returns.
+func (w *W3C) Parse(data []byte) (Info, error) {
+	line := strings.TrimRight(string(data), " \t\r\n")
+	if line == "" {
+		return Info{}, fmt.Errorf("log.W3C.Parse error - empty line")
+	}
+
+	if line[0] == '#' {
+		w.readDirective(line[1:])
+		return Info{}, ErrDirective
+	}
+
+	w.mutex.Lock()
+	fields, date := w.fields, w.date
+	w.mutex.Unlock()
+
+	if fields == nil {
+		return Info{}, fmt.Errorf("log.W3C.Parse error - the #Fields directive doesn't list %s", w.required)
+	}
+
+	values := strings.Fields(line)
+	if len(values) != len(fields) {
+		return Info{}, fmt.Errorf("log.W3C.Parse error - expected %d fields, got %d", len(fields), len(values))
+	}
+
+	var known [nbW3CFields]string
+	for i, f := range fields {
+		if values[i] != "-" {
+			known[f] = values[i]
+		}
+	}
+
+	return w.info(&known, date)
+}
+
+// readDirective handles the directive following a '#'
+func (w *W3C) readDirective(directive string) {
+	name, value := directive, ""
+	if i := strings.IndexByte(directive, ':'); i >= 0 {
+		name, value = directive[:i], strings.TrimSpace(directive[i+1:])
+	}
+
+	switch strings.ToLower(name) {
+	case "fields":
+		w.SetFields(value)
+	case "date":
+		// "2020-02-09 16:27:00", only the date is kept
+		if i := strings.IndexByte(value, ' '); i >= 0 {
+			value = value[:i]
+		}
+		w.mutex.Lock()
+		w.date = value
+		w.mutex.Unlock()
+	}
+}
+
+// info hydrates an Info struct from the values of a line. Unknown values are empty.
+func (w *W3C) info(values *[nbW3CFields]string, date string) (Info, error) {
+	info := Info{ForwardedFor: w.decode(values[w3cForwardedFor])}
+	info.setHost(values[w3cClientIP])
+
+	if values[w3cStatus] == "" {
+		return Info{}, fmt.Errorf("log.W3C.Parse error - no sc-status field")
+	}
+	code, err := parseUint([]byte(values[w3cStatus]))
+	if err != nil {
+		return Info{}, err
+	}
+	info.Request.Code = uint32(code)
+
+	if info.AuthUser = values[w3cUsername]; info.AuthUser == "" {
+		info.Missing |= AuthUserField
+	}
+
+	if values[w3cDate] != "" {
+		date = values[w3cDate]
+	}
+	if values[w3cTime] != "" && date != "" {
+		t, err := time.Parse("2006-01-02 15:04:05", date+" "+values[w3cTime])
+		if err != nil {
+			return Info{}, fmt.Errorf("log.W3C.Parse error - invalid date %q", date+" "+values[w3cTime])
+		}
+		info.LocalTime = t
+	} else {
+		info.Missing |= TimeField
+	}
+
+	switch {
+	case values[w3cURI] != "":
+		info.Request.setURI(values[w3cURI])
+	case values[w3cURIStem] != "":
+		info.Request.Route, info.Request.Query = values[w3cURIStem], values[w3cURIQuery]
+	}
+	if info.Request.Route != "" {
+		info.Request.Route = w.decode(info.Request.Route)
+		info.Request.Method = values[w3cMethod]
+		info.Request.Version = values[w3cVersion]
+	} else {
+		info.Missing |= RequestField
+	}
+
+	if values[w3cBytes] != "" {
+		info.Request.Size, err = parseUint([]byte(values[w3cBytes]))
+		if err != nil {
+			return Info{}, err
+		}
+	} else {
+		info.Missing |= SizeField
+	}
+
+	if info.Referer = w.decode(values[w3cReferer]); info.Referer == "" {
+		info.Missing |= RefererField
+	}
+
+	userAgent := values[w3cUserAgent]
+	if w.URLEncoded {
+		// CloudFront encodes the user-agent twice (%2520 for a space)
+		userAgent = w.decode(w.decode(userAgent))
+	} else {
+		// IIS replaces the spaces of the user-agent by '+'
+		userAgent = strings.Replace(userAgent, "+", " ", -1)
+	}
+	if info.UserAgent = userAgent; info.UserAgent == "" {
+		info.Missing |= UserAgentField
+	}
+
+	if values[w3cTimeTaken] != "" {
+		unit := w.TimeTakenUnit
+		if unit == 0 {
+			unit = time.Millisecond
+		}
+
+		info.Request.Duration, err = parseUnits(values[w3cTimeTaken], unit)
+		if err != nil {
+			return Info{}, fmt.Errorf("log.W3C.Parse error - invalid time-taken %q", values[w3cTimeTaken])
+		}
+	} else {
+		info.Missing |= DurationField
+	}
+
+	return info, nil
+}
+
+// decode URL-decodes value if the parser reads URL-encoded values. Values that
+// can't be decoded are returned as is.
+func (w *W3C) decode(value string) string {
+	if !w.URLEncoded {
+		return value
+	}
+
+	decoded, err := url.PathUnescape(value)
+	if err != nil {
+		return value
+	}
+	return decoded
+}
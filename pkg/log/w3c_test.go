@@ -0,0 +1,106 @@
+package log
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestW3CParsesIISDefaultFields(t *testing.T) {
+	// Setup stage
+	w := NewW3C()
+	const line string = `2020-02-09 16:27:00 10.0.0.1 GET /index.html page=2 80 - 192.168.1.1 Mozilla/5.0+(Windows+NT+10.0) - 200 0 0 15`
+
+	// Exercise stage
+	info, err := w.Parse([]byte(line))
+
+	// Validation stage
+	assert.Nil(t, err)
+	assert.Equal(t, "192.168.1.1", info.Host)
+	assert.Equal(t, time.Date(2020, 2, 9, 16, 27, 0, 0, time.UTC), info.LocalTime)
+	assert.Equal(t, HTTP{Method: "GET", Route: "/index.html", Query: "page=2", Code: 200, Duration: 15 * time.Millisecond}, info.Request)
+	assert.Equal(t, "Mozilla/5.0 (Windows NT 10.0)", info.UserAgent)
+	assert.Equal(t, AuthUserField|SizeField|RefererField, info.Missing)
+}
+
+func TestW3CFollowsFieldsDirectives(t *testing.T) {
+	w := NewW3C()
+
+	for _, directive := range []string{"#Software: Microsoft Internet Information Services 10.0", "#Version: 1.0", "#Date: 2020-02-09 16:00:00", "#Fields: time c-ip cs-method cs-uri sc-status sc-bytes cs-version"} {
+		_, err := w.Parse([]byte(directive))
+		assert.Equal(t, ErrDirective, err, directive)
+	}
+
+	// The date comes from the #Date directive
+	info, err := w.Parse([]byte("16:27:00.123 10.0.0.2 POST /login 302 17 HTTP/1.1\r\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, "10.0.0.2", info.Host)
+	assert.Equal(t, time.Date(2020, 2, 9, 16, 27, 0, int(123*time.Millisecond), time.UTC), info.LocalTime)
+	assert.Equal(t, HTTP{Method: "POST", Route: "/login", Version: "HTTP/1.1", Code: 302, Size: 17}, info.Request)
+	assert.Equal(t, AuthUserField|RefererField|UserAgentField|DurationField, info.Missing)
+
+	// Lines logged with the previous fields are now invalid
+	_, err = w.Parse([]byte(`2020-02-09 16:27:00 10.0.0.1 GET /index.html - 80 - 192.168.1.1 - - 200 0 0 15`))
+	assert.NotNil(t, err)
+
+	// The fields can change in the middle of a file
+	_, err = w.Parse([]byte("#Fields: date time c-ip cs-username cs-uri-stem sc-status"))
+	assert.Equal(t, ErrDirective, err)
+	info, err = w.Parse([]byte("2020-02-10 08:00:00 10.0.0.3 DOMAIN\\bob /admin 401"))
+	assert.Nil(t, err)
+	assert.Equal(t, "DOMAIN\\bob", info.AuthUser)
+	assert.Equal(t, "/admin", info.Request.Route)
+	assert.Equal(t, uint32(401), info.Request.Code)
+	assert.Equal(t, SizeField|RefererField|UserAgentField|DurationField, info.Missing)
+}
+
+func TestW3CReturnsAnErrorOnInvalidLines(t *testing.T) {
+	w := NewW3C()
+	_, err := w.Parse([]byte("#Fields: date time c-ip cs-uri-stem sc-status time-taken"))
+	assert.Equal(t, ErrDirective, err)
+
+	for _, line := range []string{
+		"",
+		"2020-02-09 16:27:00 10.0.0.1 / 200",
+		"2020-02-09 16:27:00 10.0.0.1 / abc 15",
+		"2020-02-09 16:27:00 10.0.0.1 / - 15",
+		"2020-02-09 25:27:00 10.0.0.1 / 200 15",
+		"2020-02-09 16:27:00 10.0.0.1 / 200 fast",
+	} {
+		_, err := w.Parse([]byte(line))
+		assert.NotNil(t, err, line)
+		assert.NotEqual(t, ErrDirective, err, line)
+	}
+}
+
+func TestCloudFrontDecodesValuesAndReadsSeconds(t *testing.T) {
+	// Setup stage
+	w := NewCloudFront()
+	_, err := w.Parse([]byte("#Version: 1.0"))
+	assert.Equal(t, ErrDirective, err)
+	line := strings.Join([]string{"2019-12-04", "21:02:31", "LAX1", "392", "192.0.2.100", "GET", "d111111abcdef8.cloudfront.net", "/index%20page.html", "200", "-",
+		"Mozilla/5.0%2520(Windows%2520NT%252010.0)", "a=1", "-", "Hit", "SOX4xwn4XV6Q4rgb7XiVGOHms_BGlTAC4KyHmureZmBNrjGdRLiNIQ==", "d111111abcdef8.cloudfront.net", "https", "23", "0.012",
+		"-", "TLSv1.2", "ECDHE-RSA-AES128-GCM-SHA256", "Hit", "HTTP/2.0", "-", "-", "11040", "0.001", "Hit", "text/html", "78", "-", "-"}, "\t")
+
+	// Exercise stage
+	info, err := w.Parse([]byte(line))
+
+	// Validation stage
+	assert.Nil(t, err)
+	assert.Equal(t, "192.0.2.100", info.Host)
+	assert.Equal(t, time.Date(2019, 12, 4, 21, 2, 31, 0, time.UTC), info.LocalTime)
+	assert.Equal(t, HTTP{Method: "GET", Route: "/index page.html", Query: "a=1", Version: "HTTP/2.0", Code: 200, Size: 392, Duration: 12 * time.Millisecond}, info.Request)
+	assert.Equal(t, "Mozilla/5.0 (Windows NT 10.0)", info.UserAgent)
+	assert.Equal(t, AuthUserField|RefererField, info.Missing)
+}
+
+func TestCloudFrontRejectsOtherW3CLogs(t *testing.T) {
+	w := NewCloudFront()
+
+	_, err := w.Parse([]byte("#Fields: " + IISDefaultFields))
+	assert.Equal(t, ErrDirective, err)
+	_, err = w.Parse([]byte(`2020-02-09 16:27:00 10.0.0.1 GET /index.html - 80 - 192.168.1.1 - - 200 0 0 15`))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,103 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// jsonTemplate writes a notification in JSON (the json preset)
+var jsonTemplate = template.Must(newTemplate(Presets["json"]))
+
+// Command is a notifier running a shell command for each notification. The command
+// reads the notification in JSON (the json preset, see Presets) on its standard input,
+// the alert is also described by environment variables :
+// ALERT_NAME, ALERT_STATUS (firing or resolved), ALERT_METRIC, ALERT_EXPR, ALERT_COMPARISON,
+// ALERT_THRESHOLD, ALERT_VALUE, ALERT_UNIT, ALERT_DATE (RFC 3339), ALERT_MESSAGE and ALERT_SUPPRESSED.
+type Command struct {
+	command string
+	timeout time.Duration
+}
+
+// NewCommand returns a notifier running command with sh, it is killed after timeout
+func NewCommand(command string, timeout time.Duration) (*Command, error) {
+	if strings.TrimSpace(command) == "" {
+		return nil, fmt.Errorf("invalid notification command - it is empty")
+	}
+	if timeout <= 0 {
+		return nil, fmt.Errorf("invalid notification command - the timeout must be greater than 0")
+	}
+
+	return &Command{command: command, timeout: timeout}, nil
+}
+
+// Notify runs the command, it fails if the command exits with a non-zero status
+func (o *Command) Notify(n *Notification) error {
+	var stdin bytes.Buffer
+	if err := jsonTemplate.Execute(&stdin, n); err != nil {
+		return fmt.Errorf("command %q - %v", o.command, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), o.timeout)
+	defer cancel()
+
+	// The error output is written to a file rather than a pipe, Run would otherwise
+	// wait for the processes started by the command to exit on a timeout
+	stderr, err := ioutil.TempFile("", "notify_command")
+	if err != nil {
+		return fmt.Errorf("command %q - %v", o.command, err)
+	}
+	defer os.Remove(stderr.Name())
+	defer stderr.Close()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", o.command)
+	cmd.Stdin = &stdin
+	cmd.Stderr = stderr
+	cmd.Env = append(os.Environ(), environment(n)...)
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			err = fmt.Errorf("timed out after %v", o.timeout)
+		}
+		if msg := readAll(stderr); msg != "" {
+			return fmt.Errorf("command %q - %v: %s", o.command, err, msg)
+		}
+		return fmt.Errorf("command %q - %v", o.command, err)
+	}
+
+	return nil
+}
+
+// readAll returns the beginning of f's content
+func readAll(f *os.File) string {
+	const maxLength = 512
+
+	buf := make([]byte, maxLength)
+	n, _ := f.ReadAt(buf, 0)
+	return strings.TrimSpace(string(buf[:n]))
+}
+
+// environment describes n as environment variables
+func environment(n *Notification) []string {
+	a := &n.Alert
+	return []string{
+		"ALERT_NAME=" + a.Name,
+		"ALERT_STATUS=" + n.Status,
+		"ALERT_METRIC=" + a.Metric,
+		"ALERT_EXPR=" + a.Expr,
+		"ALERT_COMPARISON=" + a.Comparison.String(),
+		"ALERT_THRESHOLD=" + strconv.FormatFloat(a.Threshold, 'g', -1, 64),
+		"ALERT_VALUE=" + strconv.FormatFloat(a.Value, 'g', -1, 64),
+		"ALERT_UNIT=" + a.Unit,
+		"ALERT_DATE=" + a.Date.Format(time.RFC3339),
+		"ALERT_MESSAGE=" + n.Message,
+		"ALERT_SUPPRESSED=" + strconv.FormatUint(n.Suppressed, 10),
+	}
+}
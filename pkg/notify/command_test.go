@@ -0,0 +1,72 @@
+package notify
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommandReadsTheAlertOnItsInputAndEnvironment(t *testing.T) {
+	// Setup stage
+	dir, err := ioutil.TempDir("", "command_test")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(dir)
+	stdin, env := filepath.Join(dir, "stdin"), filepath.Join(dir, "env")
+
+	cmd, err := NewCommand(`cat > `+stdin+` && echo "$ALERT_NAME $ALERT_STATUS $ALERT_METRIC $ALERT_COMPARISON $ALERT_THRESHOLD $ALERT_VALUE $ALERT_DATE" > `+env, time.Second)
+	if err != nil {
+		panic(err)
+	}
+
+	// Exercise stage
+	err = cmd.Notify(NewNotification(firingAlert()))
+
+	// Validation stage
+	assert.Nil(t, err)
+
+	data, err := ioutil.ReadFile(stdin)
+	assert.Nil(t, err)
+	var payload map[string]interface{}
+	assert.Nil(t, json.Unmarshal(data, &payload))
+	assert.Equal(t, "traffic", payload["name"])
+	assert.Equal(t, Firing, payload["status"])
+	assert.Equal(t, 12.5, payload["value"])
+	assert.Equal(t, 0.0, payload["suppressed"])
+
+	data, err = ioutil.ReadFile(env)
+	assert.Nil(t, err)
+	assert.Equal(t, "traffic firing req_rate > 10 12.5 2020-02-09T16:27:00Z", strings.TrimSpace(string(data)))
+}
+
+func TestCommandReportsFailures(t *testing.T) {
+	// Exercise & validation stages
+
+	// The error output is part of the error
+	cmd, err := NewCommand("echo unreachable >&2; exit 3", time.Second)
+	assert.Nil(t, err)
+	err = cmd.Notify(NewNotification(firingAlert()))
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "exit status 3: unreachable")
+
+	// The command is killed after the timeout
+	cmd, err = NewCommand("sleep 5; sleep 5", 50*time.Millisecond)
+	assert.Nil(t, err)
+	start := time.Now()
+	err = cmd.Notify(NewNotification(firingAlert()))
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "timed out")
+	assert.True(t, time.Since(start) < 2*time.Second)
+
+	_, err = NewCommand(" ", time.Second)
+	assert.NotNil(t, err)
+	_, err = NewCommand("true", 0)
+	assert.NotNil(t, err)
+}
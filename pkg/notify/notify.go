@@ -0,0 +1,280 @@
+package notify
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/Juli3nnicolas/http_log_monitor/pkg/logger"
+	"github.com/Juli3nnicolas/http_log_monitor/pkg/task"
+	"github.com/Juli3nnicolas/http_log_monitor/pkg/timer"
+)
+
+// DefaultQueueSize is the number of notifications waiting to be sent before new ones are dropped
+const DefaultQueueSize int = 64
+
+const (
+	// Firing is the status of the notifications sent when an alert is switched on
+	Firing string = "firing"
+	// Resolved is the status of the notifications sent when an alert is switched off
+	Resolved string = "resolved"
+)
+
+// dateFormat is the format of the dates in the messages
+const dateFormat string = "2006-01-02 15:04:05 MST"
+
+// Notifier sends the notification of an alert's transition
+type Notifier interface {
+	Notify(n *Notification) error
+}
+
+// Notification describes an alert's transition
+type Notification struct {
+	Alert   task.AlertState
+	Status  string // Firing or Resolved
+	Message string // human readable description of the transition
+	// Suppressed is the number of the alert's transitions that haven't been notified
+	// since the previous notification because of the rate limit (see RateLimit)
+	Suppressed uint64
+}
+
+// RateLimit limits the number of notifications sent per alert so that a flapping
+// alert doesn't flood the notifiers
+type RateLimit struct {
+	Max    int           // maximum number of notifications per alert and Period, 0 for no limit
+	Period time.Duration // sliding window the notifications are counted over
+}
+
+// NewNotification returns the notification of alert's transition, its status follows alert.IsOn
+func NewNotification(alert task.AlertState) *Notification {
+	n := &Notification{Alert: alert, Status: Resolved}
+	if alert.IsOn {
+		n.Status = Firing
+	}
+	n.Message = message(&alert)
+	if alert.Acknowledged {
+		n.Message += " (acknowledged)"
+	}
+	return n
+}
+
+// message describes alert's transition in a sentence
+func message(alert *task.AlertState) string {
+	date := alert.Date.Local().Format(dateFormat)
+
+	switch {
+	case !alert.IsOn:
+		return fmt.Sprintf("[%s] resolved at %s", alert.Name, date)
+
+	case alert.Expr != "":
+		return fmt.Sprintf("[%s] firing - %s holds, value = %.4g at %s", alert.Name, alert.Expr, alert.Value, date)
+
+	case alert.Comparison == task.ChangeBy:
+		return fmt.Sprintf("[%s] firing - %s changed by %.1f%% (threshold %g%%) at %s",
+			alert.Name, alert.Metric, finite(alert.Value), alert.Threshold, date)
+	}
+
+	return fmt.Sprintf("[%s] firing - %s %s %g %s, value = %.2f %s at %s",
+		alert.Name, alert.Metric, alert.Comparison, alert.Threshold, alert.Unit, alert.Value, alert.Unit, date)
+}
+
+// finite returns the greatest float instead of an infinite change (from 0)
+func finite(v float64) float64 {
+	if math.IsInf(v, 1) {
+		return math.MaxFloat64
+	}
+	return v
+}
+
+// Dispatcher is a task sending a notification to every notifier whenever an alert
+// is switched on or off. Notifications are sent in the background so that slow
+// notifiers don't delay the measures, delivery errors are logged.
+// Once an alert has reached the rate limit, its transitions are held back and its
+// latest state is notified as soon as the limit allows it (if it differs from the
+// last one notified). Silenced alerts aren't notified, their state is notified
+// once the silence is lifted if it has changed.
+type Dispatcher struct {
+	notifiers []Notifier
+	limit     RateLimit
+	notified  map[string]bool        // state last notified by alert name (true if switched on)
+	seen      map[string]bool        // state last seen by alert name
+	pending   map[string]uint64      // transitions seen since the last notification by alert name
+	sent      map[string][]time.Time // dates of the notifications sent during the rate limit's period
+	queue     chan *Notification
+	stopped   chan struct{} // closed once the queue has been emptied
+	done      bool
+}
+
+// Init starts sending the notifications, it accepts optional parameters :
+// - notifiers []Notifier : where the notifications are sent, nothing is sent if there is none
+// - limit RateLimit : the notifications aren't limited by default
+func (o *Dispatcher) Init(args ...interface{}) error {
+	if len(args) > 2 {
+		return fmt.Errorf("wrong parameters - in order optional parameters must be (notifiers []Notifier, limit RateLimit)")
+	}
+
+	var notifiers []Notifier
+	if len(args) >= 1 {
+		var ok bool
+		notifiers, ok = args[0].([]Notifier)
+		if !ok {
+			return fmt.Errorf("type error - got %T instead of []Notifier", args[0])
+		}
+	}
+
+	var limit RateLimit
+	if len(args) == 2 {
+		var ok bool
+		limit, ok = args[1].(RateLimit)
+		if !ok {
+			return fmt.Errorf("type error - got %T instead of RateLimit", args[1])
+		}
+		if limit.Max < 0 || (limit.Max > 0 && limit.Period <= 0) {
+			return fmt.Errorf("wrong parameters - the rate limit must be positive, got %d per %v", limit.Max, limit.Period)
+		}
+	}
+
+	o.notifiers = notifiers
+	o.limit = limit
+	o.notified = make(map[string]bool)
+	o.seen = make(map[string]bool)
+	o.pending = make(map[string]uint64)
+	o.sent = make(map[string][]time.Time)
+	o.queue = nil
+	o.stopped = nil
+	if len(notifiers) == 0 {
+		return nil
+	}
+
+	o.queue = make(chan *Notification, DefaultQueueSize)
+	o.stopped = make(chan struct{})
+	go send(notifiers, o.queue, o.stopped)
+
+	return nil
+}
+
+// send delivers the queued notifications until the queue is closed
+func send(notifiers []Notifier, queue chan *Notification, stopped chan struct{}) {
+	defer close(stopped)
+
+	for n := range queue {
+		for _, notifier := range notifiers {
+			if err := notifier.Notify(n); err != nil {
+				logger.Get().Errorln(err)
+			}
+		}
+	}
+}
+
+// BeforeRun flags the task as not done
+func (o *Dispatcher) BeforeRun(...interface{}) error {
+	o.done = false
+	return nil
+}
+
+// Run queues the notifications of the alerts switched on or off since the last run. It takes
+// one parameter and an optional one :
+// - alerts []task.AlertState : the alerts' current states (see task.AlertManager)
+// - t timer.Timer : clock the rate limit follows (the system's by default)
+func (o *Dispatcher) Run(args ...interface{}) error {
+	if len(args) != 1 && len(args) != 2 {
+		return fmt.Errorf("wrong parameters - in order parameters must be (alerts []task.AlertState[, t timer.Timer])")
+	}
+
+	alerts, ok := args[0].([]task.AlertState)
+	if !ok {
+		return fmt.Errorf("type error - got %T instead of []task.AlertState", args[0])
+	}
+
+	var clock timer.Timer = &timer.Time{}
+	if len(args) == 2 {
+		clock, ok = args[1].(timer.Timer)
+		if !ok {
+			return fmt.Errorf("type error - got %T instead of timer.Timer", args[1])
+		}
+	}
+
+	if o.notified == nil {
+		if err := o.Init(); err != nil {
+			return err
+		}
+	}
+
+	now := clock.Now()
+	for i := range alerts {
+		alert := alerts[i]
+		if alert.IsOn != o.seen[alert.Name] {
+			o.seen[alert.Name] = alert.IsOn
+			if !alert.Silenced {
+				o.pending[alert.Name]++
+			}
+		}
+
+		if alert.Silenced || alert.IsOn == o.notified[alert.Name] || !o.allow(alert.Name, now) {
+			continue
+		}
+
+		n := NewNotification(alert)
+		if pending := o.pending[alert.Name]; pending > 1 {
+			n.Suppressed = pending - 1
+		}
+		if n.Suppressed > 0 {
+			n.Message += fmt.Sprintf(" (%d transitions suppressed by the rate limit)", n.Suppressed)
+		}
+		o.notified[alert.Name] = alert.IsOn
+		o.pending[alert.Name] = 0
+
+		if o.queue == nil {
+			continue
+		}
+
+		select {
+		case o.queue <- n:
+		default:
+			logger.Get().Errorf("notification queue full - the %s notification of alert %s is dropped", n.Status, alert.Name)
+		}
+	}
+	o.done = true
+
+	return nil
+}
+
+// allow returns true and counts the notification if name's alert can be notified at now
+func (o *Dispatcher) allow(name string, now time.Time) bool {
+	if o.limit.Max == 0 {
+		return true
+	}
+
+	sent := o.sent[name]
+	for len(sent) > 0 && now.Sub(sent[0]) >= o.limit.Period {
+		sent = sent[1:]
+	}
+
+	if len(sent) >= o.limit.Max {
+		o.sent[name] = sent
+		return false
+	}
+
+	o.sent[name] = append(sent, now)
+	return true
+}
+
+// AfterRun does nothing, implements the Task interface
+func (o *Dispatcher) AfterRun() error {
+	return nil
+}
+
+// IsDone is true once the transitions of the current frame have been queued
+func (o *Dispatcher) IsDone() bool {
+	return o.done
+}
+
+// Close waits for the queued notifications to be sent. Call Init to use it again.
+func (o *Dispatcher) Close() error {
+	if o.queue != nil {
+		close(o.queue)
+		<-o.stopped
+	}
+	*o = Dispatcher{}
+	return nil
+}
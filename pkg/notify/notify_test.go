@@ -0,0 +1,224 @@
+package notify
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Juli3nnicolas/http_log_monitor/pkg/task"
+	"github.com/Juli3nnicolas/http_log_monitor/pkg/timer"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// notifierStub records the notifications it is sent
+type notifierStub struct {
+	mu            sync.Mutex
+	notifications []*Notification
+	err           error
+}
+
+func (o *notifierStub) Notify(n *Notification) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.notifications = append(o.notifications, n)
+	return o.err
+}
+
+func TestDispatcherNotifiesEveryTransition(t *testing.T) {
+	// Setup stage - the second notifier always fails
+	first, second := &notifierStub{}, &notifierStub{err: fmt.Errorf("unreachable")}
+	d := Dispatcher{}
+	if err := d.Init([]Notifier{first, second}); err != nil {
+		panic(err)
+	}
+
+	date := time.Date(2020, 2, 9, 16, 27, 0, 0, time.UTC)
+	states := func(trafficOn, slowOn bool) []task.AlertState {
+		return []task.AlertState{
+			{Name: "traffic", IsOn: trafficOn, Date: date, Metric: task.RequestRateMetricName, Unit: "req/s", Threshold: 10, Value: 12},
+			{Name: "slow", IsOn: slowOn, Date: date, Expr: "avg(latency_p99, 5m) > 250", Value: 300},
+		}
+	}
+
+	// Exercise stage
+	assert.Nil(t, d.BeforeRun())
+	assert.Nil(t, d.Run(states(false, false)))
+	assert.True(t, d.IsDone())
+	assert.Nil(t, d.Run(states(true, false)))
+	assert.Nil(t, d.Run(states(true, true)))
+	assert.Nil(t, d.Run(states(true, true)))
+	assert.Nil(t, d.Run(states(false, true)))
+
+	// Close waits for the notifications to be sent
+	assert.Nil(t, d.Close())
+
+	// Validation stage
+	for _, stub := range []*notifierStub{first, second} {
+		assert.Len(t, stub.notifications, 3)
+		assert.Equal(t, "traffic", stub.notifications[0].Alert.Name)
+		assert.Equal(t, Firing, stub.notifications[0].Status)
+		assert.Equal(t, "slow", stub.notifications[1].Alert.Name)
+		assert.Equal(t, Firing, stub.notifications[1].Status)
+		assert.Equal(t, "traffic", stub.notifications[2].Alert.Name)
+		assert.Equal(t, Resolved, stub.notifications[2].Status)
+	}
+
+	local := date.Local().Format(dateFormat)
+	assert.Equal(t, "[traffic] firing - req_rate > 10 req/s, value = 12.00 req/s at "+local, first.notifications[0].Message)
+	assert.Equal(t, "[slow] firing - avg(latency_p99, 5m) > 250 holds, value = 300 at "+local, first.notifications[1].Message)
+	assert.Equal(t, "[traffic] resolved at "+local, first.notifications[2].Message)
+}
+
+func TestDispatcherRateLimitsFlappingAlerts(t *testing.T) {
+	// Setup stage - 2 notifications per alert every 10 minutes, frames last a minute
+	stub := &notifierStub{}
+	d := Dispatcher{}
+	if err := d.Init([]Notifier{stub}, RateLimit{Max: 2, Period: 10 * time.Minute}); err != nil {
+		panic(err)
+	}
+
+	now := time.Date(2020, 2, 9, 16, 27, 0, 0, time.UTC)
+	t1 := &timer.TimeStub{
+		NowStub: func() time.Time {
+			now = now.Add(time.Minute)
+			return now
+		},
+	}
+	flapping := func(isOn bool) []task.AlertState {
+		return []task.AlertState{{Name: "traffic", IsOn: isOn, Date: now}}
+	}
+
+	// Exercise stage
+
+	// The alert fires and resolves, both are notified
+	assert.Nil(t, d.Run(flapping(true), t1))
+	assert.Nil(t, d.Run(flapping(false), t1))
+
+	// It keeps flapping until the end of the period, nothing is notified
+	for i := 0; i < 7; i++ {
+		assert.Nil(t, d.Run(flapping(i%2 == 0), t1))
+	}
+	assert.Nil(t, d.Run(flapping(true), t1))
+
+	// The first notification is out of the period, the alert's latest state is notified
+	assert.Nil(t, d.Run(flapping(true), t1))
+
+	// The alert keeps firing, nothing new is notified
+	assert.Nil(t, d.Run(flapping(true), t1))
+	assert.Nil(t, d.Close())
+
+	// Validation stage
+	assert.Len(t, stub.notifications, 3)
+	assert.Equal(t, Firing, stub.notifications[0].Status)
+	assert.Equal(t, Resolved, stub.notifications[1].Status)
+	last := stub.notifications[2]
+	assert.Equal(t, Firing, last.Status)
+	assert.Equal(t, uint64(6), last.Suppressed)
+	assert.Contains(t, last.Message, "(6 transitions suppressed by the rate limit)")
+
+	assert.NotNil(t, d.Init([]Notifier{stub}, RateLimit{Max: -1}))
+	assert.NotNil(t, d.Init([]Notifier{stub}, RateLimit{Max: 1}))
+}
+
+func TestDispatcherDoesntNotifySilencedAlerts(t *testing.T) {
+	// Setup stage
+	stub := &notifierStub{}
+	d := Dispatcher{}
+	if err := d.Init([]Notifier{stub}); err != nil {
+		panic(err)
+	}
+	alert := func(isOn, silenced bool) []task.AlertState {
+		return []task.AlertState{{Name: "traffic", IsOn: isOn, Silenced: silenced}}
+	}
+
+	// Exercise stage
+
+	// The alert fires and resolves while it is silenced
+	assert.Nil(t, d.Run(alert(true, true)))
+	assert.Nil(t, d.Run(alert(false, true)))
+
+	// It fires again while it is silenced, its state is notified once the silence is lifted
+	assert.Nil(t, d.Run(alert(true, true)))
+	assert.Nil(t, d.Run(alert(true, false)))
+	assert.Nil(t, d.Run(alert(true, false)))
+	assert.Nil(t, d.Close())
+
+	// Validation stage
+	assert.Len(t, stub.notifications, 1)
+	assert.Equal(t, Firing, stub.notifications[0].Status)
+	assert.Equal(t, uint64(0), stub.notifications[0].Suppressed)
+}
+
+func TestDispatcherNotifiesTheAlertManagerControls(t *testing.T) {
+	// Setup stage - the alert is evaluated every frame
+	const frameDuration time.Duration = time.Minute
+
+	manager := task.AlertManager{}
+	err := manager.Init([]task.AlertRule{
+		{Name: "traffic", Metric: task.RequestRateMetric(), Duration: frameDuration, Threshold: 10},
+	})
+	if err != nil {
+		panic(err)
+	}
+	if err := manager.BeforeRun(); err != nil {
+		panic(err)
+	}
+
+	stub := &notifierStub{}
+	d := Dispatcher{}
+	if err := d.Init([]Notifier{stub}); err != nil {
+		panic(err)
+	}
+
+	now := time.Now()
+	t1 := &timer.TimeStub{NowStub: func() time.Time { return now }}
+	frame := func(reqPerS uint64) {
+		now = now.Add(frameDuration)
+		rates := task.Rates{Frame: task.FrameRates{Duration: 60, ReqPerS: reqPerS, Rate: float64(reqPerS)}}
+		if err := manager.Run(task.Metrics{Rates: rates}, t1); err != nil {
+			panic(err)
+		}
+		if err := d.Run(manager.Result(), t1); err != nil {
+			panic(err)
+		}
+	}
+
+	// Exercise stage
+
+	// The alert fires and resolves while it is silenced
+	assert.Nil(t, manager.Apply(task.AlertControl{Action: task.Silence, Name: "traffic", Duration: 3 * frameDuration}, now))
+	frame(20)
+	frame(1)
+
+	// The silence has expired, the alert fires, is acknowledged and resolves
+	frame(1)
+	frame(20)
+	assert.Nil(t, manager.Apply(task.AlertControl{Action: task.Acknowledge, Name: "traffic"}, now))
+	frame(20)
+	frame(1)
+
+	// It fires again, the acknowledgement has been lifted
+	frame(20)
+	assert.Nil(t, d.Close())
+
+	// Validation stage
+	assert.Len(t, stub.notifications, 3)
+	assert.Equal(t, Firing, stub.notifications[0].Status)
+	assert.NotContains(t, stub.notifications[0].Message, "(acknowledged)")
+	assert.Equal(t, Resolved, stub.notifications[1].Status)
+	assert.Contains(t, stub.notifications[1].Message, "(acknowledged)")
+	assert.Equal(t, Firing, stub.notifications[2].Status)
+	assert.NotContains(t, stub.notifications[2].Message, "(acknowledged)")
+}
+
+func TestDispatcherWithoutNotifiers(t *testing.T) {
+	d := Dispatcher{}
+	assert.Nil(t, d.Init())
+	assert.Nil(t, d.Run([]task.AlertState{{Name: "traffic", IsOn: true}}))
+	assert.Nil(t, d.Close())
+
+	assert.NotNil(t, d.Init(42))
+	assert.NotNil(t, d.Run(task.AlertState{}))
+}
@@ -0,0 +1,164 @@
+package notify
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SMTPConfig configures an email notifier
+type SMTPConfig struct {
+	Addr     string   // server address, host:port
+	From     string   // sender's address
+	To       []string // recipients' addresses
+	Username string   // the server is authenticated with if it is set (PLAIN)
+	Password string
+	Timeout  time.Duration // timeout of each email's delivery
+}
+
+// SMTP is a notifier sending the notifications by email. STARTTLS is used if the
+// server supports it.
+type SMTP struct {
+	addr    string
+	host    string
+	from    string   // From header
+	to      []string // To header
+	auth    smtp.Auth
+	timeout time.Duration
+	// sender and recipients are the envelope's addresses, without display names
+	sender     string
+	recipients []string
+}
+
+// NewSMTP returns the email notifier configured by conf
+func NewSMTP(conf SMTPConfig) (*SMTP, error) {
+	host, _, err := net.SplitHostPort(conf.Addr)
+	if err != nil || host == "" {
+		return nil, fmt.Errorf("invalid SMTP server address %q - it must be written as host:port", conf.Addr)
+	}
+
+	if len(conf.To) == 0 {
+		return nil, fmt.Errorf("invalid SMTP configuration - there is no recipient")
+	}
+	addresses := make([]string, 0, len(conf.To)+1)
+	for _, addr := range append([]string{conf.From}, conf.To...) {
+		parsed, err := mail.ParseAddress(addr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid email address %q - %v", addr, err)
+		}
+		addresses = append(addresses, parsed.Address)
+	}
+
+	if conf.Timeout <= 0 {
+		return nil, fmt.Errorf("invalid SMTP configuration - the timeout must be greater than 0")
+	}
+
+	var auth smtp.Auth
+	if conf.Username != "" {
+		auth = smtp.PlainAuth("", conf.Username, conf.Password, host)
+	}
+
+	return &SMTP{
+		addr:    conf.Addr,
+		host:    host,
+		from:    conf.From,
+		to:      conf.To,
+		auth:    auth,
+		timeout: conf.Timeout,
+
+		sender:     addresses[0],
+		recipients: addresses[1:],
+	}, nil
+}
+
+// Notify sends n to the recipients
+func (o *SMTP) Notify(n *Notification) error {
+	if err := o.send(o.message(n)); err != nil {
+		return fmt.Errorf("SMTP server %s - %v", o.addr, err)
+	}
+	return nil
+}
+
+func (o *SMTP) send(msg []byte) error {
+	conn, err := net.DialTimeout("tcp", o.addr, o.timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(o.timeout)); err != nil {
+		return err
+	}
+
+	c, err := smtp.NewClient(conn, o.host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: o.host}); err != nil {
+			return err
+		}
+	}
+
+	if o.auth != nil {
+		if err := c.Auth(o.auth); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Mail(o.sender); err != nil {
+		return err
+	}
+	for _, to := range o.recipients {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return c.Quit()
+}
+
+// message writes n as an email whose subject is the alert's name and status. The
+// subject is encoded so that names can't break the headers.
+func (o *SMTP) message(n *Notification) []byte {
+	a := &n.Alert
+	subject := mime.QEncoding.Encode("utf-8", fmt.Sprintf("[%s] %s", a.Name, n.Status))
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", o.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(o.to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject)
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("\r\n")
+
+	fmt.Fprintf(&msg, "%s\r\n\r\n", n.Message)
+	if a.Expr != "" {
+		fmt.Fprintf(&msg, "Expression: %s\r\n", a.Expr)
+	} else {
+		fmt.Fprintf(&msg, "Metric: %s\r\n", a.Metric)
+		fmt.Fprintf(&msg, "Threshold: %s %g %s\r\n", a.Comparison, a.Threshold, a.Unit)
+	}
+	fmt.Fprintf(&msg, "Date: %s\r\n", a.Date.Format(time.RFC3339))
+
+	return msg.Bytes()
+}
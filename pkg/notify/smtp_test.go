@@ -0,0 +1,176 @@
+package notify
+
+import (
+	"bufio"
+	"mime"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSMTPServer accepts a single email and sends it to received
+func fakeSMTPServer() (addr string, received chan []string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(err)
+	}
+
+	received = make(chan []string, 1)
+	go func() {
+		defer l.Close()
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+		var commands []string
+		reply("220 localhost ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			commands = append(commands, line)
+
+			switch {
+			case strings.HasPrefix(line, "EHLO"):
+				reply("250 localhost")
+			case line == "DATA":
+				reply("354 go ahead")
+				for {
+					data, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					data = strings.TrimRight(data, "\r\n")
+					if data == "." {
+						break
+					}
+					commands = append(commands, data)
+				}
+				reply("250 queued")
+			case line == "QUIT":
+				reply("221 bye")
+				received <- commands
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+
+	return l.Addr().String(), received
+}
+
+func TestSMTPSendsTheAlertByEmail(t *testing.T) {
+	// Setup stage
+	addr, received := fakeSMTPServer()
+	notifier, err := NewSMTP(SMTPConfig{
+		Addr:    addr,
+		From:    "monitor@example.com",
+		To:      []string{"oncall@example.com", "ops@example.com"},
+		Timeout: time.Second,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	// Exercise stage
+	err = notifier.Notify(NewNotification(firingAlert()))
+
+	// Validation stage
+	assert.Nil(t, err)
+
+	var commands []string
+	select {
+	case commands = <-received:
+	case <-time.After(time.Second):
+		t.Fatal("no email received")
+	}
+
+	assert.Contains(t, commands, "MAIL FROM:<monitor@example.com>")
+	assert.Contains(t, commands, "RCPT TO:<oncall@example.com>")
+	assert.Contains(t, commands, "RCPT TO:<ops@example.com>")
+	assert.Contains(t, commands, "To: oncall@example.com, ops@example.com")
+	assert.Contains(t, commands, "Subject: [traffic] firing")
+	assert.Contains(t, commands, "Threshold: > 10 req/s")
+}
+
+func TestSMTPSendsToBareAddressesAndEncodesTheSubject(t *testing.T) {
+	// Setup stage
+	addr, received := fakeSMTPServer()
+	notifier, err := NewSMTP(SMTPConfig{
+		Addr:    addr,
+		From:    "Monitor <monitor@example.com>",
+		To:      []string{"On call <oncall@example.com>"},
+		Timeout: time.Second,
+	})
+	if err != nil {
+		panic(err)
+	}
+	alert := firingAlert()
+	alert.Name = "café\r\nBcc: evil@example.com"
+
+	// Exercise stage
+	err = notifier.Notify(NewNotification(alert))
+
+	// Validation stage
+	assert.Nil(t, err)
+
+	var commands []string
+	select {
+	case commands = <-received:
+	case <-time.After(time.Second):
+		t.Fatal("no email received")
+	}
+
+	assert.Contains(t, commands, "MAIL FROM:<monitor@example.com>")
+	assert.Contains(t, commands, "RCPT TO:<oncall@example.com>")
+	assert.Contains(t, commands, "From: Monitor <monitor@example.com>")
+	assert.Contains(t, commands, "Subject: "+mime.QEncoding.Encode("utf-8", "[café\r\nBcc: evil@example.com] firing"))
+	// No header has been injected, the headers end with the first empty line
+	for _, line := range commands {
+		if line == "" {
+			break
+		}
+		assert.False(t, strings.HasPrefix(line, "Bcc:"), line)
+	}
+}
+
+func TestSMTPReportsUnreachableServers(t *testing.T) {
+	// Setup stage - nothing listens to the address anymore
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	notifier, err := NewSMTP(SMTPConfig{Addr: addr, From: "monitor@example.com", To: []string{"oncall@example.com"}, Timeout: time.Second})
+	if err != nil {
+		panic(err)
+	}
+
+	// Exercise & validation stages
+	assert.NotNil(t, notifier.Notify(NewNotification(firingAlert())))
+}
+
+func TestNewSMTPReturnsAnErrorOnInvalidConfigurations(t *testing.T) {
+	for _, conf := range []SMTPConfig{
+		{Addr: "localhost", From: "a@example.com", To: []string{"b@example.com"}, Timeout: time.Second},
+		{Addr: "localhost:25", From: "a@example.com", Timeout: time.Second},
+		{Addr: "localhost:25", From: "nobody", To: []string{"b@example.com"}, Timeout: time.Second},
+		{Addr: "localhost:25", From: "a@example.com", To: []string{"b@example.com"}},
+	} {
+		_, err := NewSMTP(conf)
+		assert.NotNil(t, err, conf)
+	}
+}
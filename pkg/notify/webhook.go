@@ -0,0 +1,145 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/url"
+	"text/template"
+	"time"
+)
+
+// DefaultWebhookTemplate is the preset used when no template is given
+const DefaultWebhookTemplate string = "json"
+
+// Presets are the predefined webhook templates by name. The json preset sends the
+// whole alert state, the slack and mattermost ones are incoming webhook payloads.
+var Presets = map[string]string{
+	"json": `{"name":{{json .Alert.Name}},"status":{{json .Status}},"metric":{{json .Alert.Metric}},` +
+		`"expr":{{json .Alert.Expr}},"comparison":{{json .Alert.Comparison.String}},"threshold":{{json .Alert.Threshold}},` +
+		`"value":{{json .Alert.Value}},"unit":{{json .Alert.Unit}},"date":{{json .Alert.Date}},"message":{{json .Message}},` +
+		`"suppressed":{{.Suppressed}}}`,
+	"slack":      `{"text":{{json .Message}}}`,
+	"mattermost": `{"text":{{json .Message}},"username":"http_log_monitor"}`,
+}
+
+// WebhookConfig configures a webhook
+type WebhookConfig struct {
+	URL string
+	// Template is the name of a preset (see Presets) or a text/template producing the
+	// request's body from a Notification. The json function writes a value in JSON.
+	Template string
+	Retries  int           // number of retries after a failed delivery
+	Backoff  time.Duration // delay before the first retry, doubled on every retry
+	Timeout  time.Duration // timeout of each request
+}
+
+// Webhook is a notifier POSTing the notifications to a URL in JSON. Deliveries failing
+// on a network error, a 5xx or a 429 status code are retried.
+type Webhook struct {
+	url      string
+	template *template.Template
+	retries  int
+	backoff  time.Duration
+	client   *http.Client
+	// sleep waits between the retries
+	sleep func(time.Duration)
+}
+
+// NewWebhook returns the webhook configured by conf
+func NewWebhook(conf WebhookConfig) (*Webhook, error) {
+	u, err := url.Parse(conf.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid webhook URL %q - it must be an http or https URL", conf.URL)
+	}
+
+	if conf.Retries < 0 || conf.Backoff < 0 {
+		return nil, fmt.Errorf("invalid webhook - the retries and the backoff must be positive")
+	}
+
+	if conf.Timeout <= 0 {
+		return nil, fmt.Errorf("invalid webhook - the timeout must be greater than 0")
+	}
+
+	src := conf.Template
+	if src == "" {
+		src = DefaultWebhookTemplate
+	}
+	if preset, ok := Presets[src]; ok {
+		src = preset
+	}
+
+	tmpl, err := newTemplate(src)
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook template - %v", err)
+	}
+
+	return &Webhook{
+		url:      conf.URL,
+		template: tmpl,
+		retries:  conf.Retries,
+		backoff:  conf.Backoff,
+		client:   &http.Client{Timeout: conf.Timeout},
+		sleep:    time.Sleep,
+	}, nil
+}
+
+// newTemplate parses a notification template, the json function writes a value in JSON
+func newTemplate(src string) (*template.Template, error) {
+	return template.New("notification").Funcs(template.FuncMap{"json": toJSON}).Parse(src)
+}
+
+// toJSON writes v in JSON, infinite and NaN numbers are written as null
+func toJSON(v interface{}) (string, error) {
+	if f, ok := v.(float64); ok && (math.IsInf(f, 0) || math.IsNaN(f)) {
+		return "null", nil
+	}
+
+	data, err := json.Marshal(v)
+	return string(data), err
+}
+
+// Notify sends n to the webhook, it returns the last error if every attempt failed
+func (o *Webhook) Notify(n *Notification) error {
+	var body bytes.Buffer
+	if err := o.template.Execute(&body, n); err != nil {
+		return fmt.Errorf("webhook %s - %v", o.url, err)
+	}
+
+	backoff := o.backoff
+	for attempt := 0; ; attempt++ {
+		retry, err := o.post(body.Bytes())
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt == o.retries {
+			return fmt.Errorf("webhook %s - %v (%d attempts)", o.url, err, attempt+1)
+		}
+
+		o.sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// post sends body, it returns whether a failed delivery can be retried
+func (o *Webhook) post(body []byte) (bool, error) {
+	resp, err := o.client.Post(o.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	// Read the body so that the connection can be reused
+	io.Copy(ioutil.Discard, resp.Body)
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests:
+		return true, fmt.Errorf("got status %s", resp.Status)
+	}
+	return false, fmt.Errorf("got status %s", resp.Status)
+}
@@ -0,0 +1,199 @@
+package notify
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Juli3nnicolas/http_log_monitor/pkg/task"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recorder is a webhook server answering with statuses, 200 once they have all been used
+type recorder struct {
+	mu       sync.Mutex
+	statuses []int
+	bodies   []string
+}
+
+func (o *recorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	body, _ := ioutil.ReadAll(r.Body)
+	o.bodies = append(o.bodies, string(body))
+
+	status := http.StatusOK
+	if len(o.statuses) > 0 {
+		status, o.statuses = o.statuses[0], o.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func firingAlert() task.AlertState {
+	return task.AlertState{
+		Name:      "traffic",
+		IsOn:      true,
+		Metric:    task.RequestRateMetricName,
+		Unit:      "req/s",
+		Threshold: 10,
+		Value:     12.5,
+		Date:      time.Date(2020, 2, 9, 16, 27, 0, 0, time.UTC),
+	}
+}
+
+func newTestWebhook(url, tmpl string, retries int) (*Webhook, *[]time.Duration) {
+	hook, err := NewWebhook(WebhookConfig{URL: url, Template: tmpl, Retries: retries, Backoff: time.Second, Timeout: time.Second})
+	if err != nil {
+		panic(err)
+	}
+
+	waits := &[]time.Duration{}
+	hook.sleep = func(d time.Duration) { *waits = append(*waits, d) }
+	return hook, waits
+}
+
+func TestWebhookPostsTheAlertInJSON(t *testing.T) {
+	// Setup stage
+	rec := &recorder{}
+	server := httptest.NewServer(rec)
+	defer server.Close()
+	hook, _ := newTestWebhook(server.URL, "", 0)
+
+	// Exercise stage
+	err := hook.Notify(NewNotification(firingAlert()))
+
+	// Validation stage
+	assert.Nil(t, err)
+	assert.Len(t, rec.bodies, 1)
+
+	var payload map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(rec.bodies[0]), &payload))
+	assert.Equal(t, "traffic", payload["name"])
+	assert.Equal(t, Firing, payload["status"])
+	assert.Equal(t, "req_rate", payload["metric"])
+	assert.Equal(t, ">", payload["comparison"])
+	assert.Equal(t, 10.0, payload["threshold"])
+	assert.Equal(t, 12.5, payload["value"])
+	assert.Equal(t, "2020-02-09T16:27:00Z", payload["date"])
+	assert.Contains(t, payload["message"], "[traffic] firing - req_rate > 10 req/s, value = 12.50 req/s")
+}
+
+func TestWebhookPresetsAndTemplates(t *testing.T) {
+	// Setup stage
+	rec := &recorder{}
+	server := httptest.NewServer(rec)
+	defer server.Close()
+
+	resolved := firingAlert()
+	resolved.IsOn = false
+	n := NewNotification(resolved)
+
+	// Exercise & validation stages
+	for tmpl, expected := range map[string]string{
+		"slack":      `{"text":` + toJSONString(n.Message) + `}`,
+		"mattermost": `{"text":` + toJSONString(n.Message) + `,"username":"http_log_monitor"}`,
+		`{"alert":{{json .Alert.Name}},"status":"{{.Status}}"}`: `{"alert":"traffic","status":"resolved"}`,
+	} {
+		rec.bodies = nil
+		hook, _ := newTestWebhook(server.URL, tmpl, 0)
+		assert.Nil(t, hook.Notify(n))
+		assert.Equal(t, []string{expected}, rec.bodies, tmpl)
+	}
+
+	// Infinite changes can't be written in JSON
+	change := firingAlert()
+	change.Comparison = task.ChangeBy
+	change.Value = math.Inf(1)
+	rec.bodies = nil
+	hook, _ := newTestWebhook(server.URL, `{{json .Alert.Value}}`, 0)
+	assert.Nil(t, hook.Notify(NewNotification(change)))
+	assert.Equal(t, []string{"null"}, rec.bodies)
+}
+
+func TestWebhookRetriesWithABackoff(t *testing.T) {
+	// Setup stage - the server fails twice
+	rec := &recorder{statuses: []int{http.StatusBadGateway, http.StatusTooManyRequests}}
+	server := httptest.NewServer(rec)
+	defer server.Close()
+	hook, waits := newTestWebhook(server.URL, "slack", 3)
+
+	// Exercise stage
+	err := hook.Notify(NewNotification(firingAlert()))
+
+	// Validation stage
+	assert.Nil(t, err)
+	assert.Len(t, rec.bodies, 3)
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second}, *waits)
+}
+
+func TestWebhookGivesUp(t *testing.T) {
+	// Setup stage
+	rec := &recorder{statuses: []int{500, 500, 500, 400}}
+	server := httptest.NewServer(rec)
+	defer server.Close()
+
+	// Exercise & validation stages
+
+	// Every attempt fails
+	hook, waits := newTestWebhook(server.URL, "slack", 2)
+	err := hook.Notify(NewNotification(firingAlert()))
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "3 attempts")
+	assert.Len(t, *waits, 2)
+
+	// Client errors aren't retried
+	rec.bodies = nil
+	err = hook.Notify(NewNotification(firingAlert()))
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "400")
+	assert.Len(t, rec.bodies, 1)
+}
+
+func TestWebhookTimesOut(t *testing.T) {
+	// Setup stage - the server answers after the timeout
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	hook, err := NewWebhook(WebhookConfig{URL: server.URL, Timeout: 50 * time.Millisecond})
+	if err != nil {
+		panic(err)
+	}
+
+	// Exercise stage
+	start := time.Now()
+	err = hook.Notify(NewNotification(firingAlert()))
+
+	// Validation stage
+	assert.NotNil(t, err)
+	assert.True(t, time.Since(start) < time.Second)
+}
+
+func TestNewWebhookReturnsAnErrorOnInvalidConfigurations(t *testing.T) {
+	for _, conf := range []WebhookConfig{
+		{URL: "", Timeout: time.Second},
+		{URL: "ftp://example.com", Timeout: time.Second},
+		{URL: "http://", Timeout: time.Second},
+		{URL: "http://example.com"},
+		{URL: "http://example.com", Timeout: time.Second, Retries: -1},
+		{URL: "http://example.com", Timeout: time.Second, Template: "{{.Unknown"},
+	} {
+		_, err := NewWebhook(conf)
+		assert.NotNil(t, err, conf)
+	}
+}
+
+func toJSONString(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data)
+}
@@ -14,7 +14,10 @@ type Async struct {
 	MinBufsize uint64
 	err        error
 	stop       chan bool
+	done       chan struct{} // closed once the reading process has stopped
 	buffer     []log.Info
+	// parseErrors counts the lines Reader couldn't parse
+	parseErrors ParseErrors
 }
 
 const defaultMinBufSize uint64 = 500
@@ -26,6 +29,7 @@ func (r *Async) init() {
 	r.buffer = make([]log.Info, 0, r.MinBufsize)
 	r.stop = make(chan bool)
 	r.err = nil
+	r.parseErrors = ParseErrors{}
 }
 
 // Open inits the sync reader and opens Reader.
@@ -47,16 +51,24 @@ func (r *Async) Read() ([]log.Info, error) {
 	return r.buffer, r.err
 }
 
+// ParseErrors returns the lines Reader couldn't parse since the last Flush.
+// Like Read, call Stop before to get accurate data.
+func (r *Async) ParseErrors() ParseErrors {
+	return r.parseErrors
+}
+
 // Start starts a new parallel reading process. Calls Reader to read.
 // At the moment only one parallel read is supported (One call to Start)
 // This call is possible though: r.Start(); r.Stop(); r.Start();
 func (r *Async) Start() {
-	go r.read()
+	r.done = make(chan struct{})
+	go r.read(r.done)
 }
 
-// Stop stops the reading process
+// Stop stops the reading process, it returns once the process has stopped
 func (r *Async) Stop() {
 	r.stop <- true
+	<-r.done
 }
 
 // Flush empties all internal buffers. Make sure to call Stop before otherwise
@@ -66,8 +78,11 @@ func (r *Async) Flush() {
 	r.init()
 }
 
-// read reads the file content asynchronously. It fills r.buffer.
-func (r *Async) read() {
+// read reads the file content asynchronously. It fills r.buffer and closes done
+// when it returns.
+func (r *Async) read(done chan struct{}) {
+	defer close(done)
+
 	for {
 		select {
 		case s, ok := <-r.stop:
@@ -77,6 +92,11 @@ func (r *Async) read() {
 			}
 		default:
 			output, err := r.Reader.Read()
+			if parseErr, ok := err.(*ParseError); ok {
+				// Bad lines are counted instead of being reported as errors
+				r.parseErrors.Add(*parseErr)
+				continue
+			}
 			if err != nil {
 				r.err = err
 				logger.Get().Errorln(err)
@@ -1,6 +1,7 @@
 package reader
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/Juli3nnicolas/http_log_monitor/pkg/log"
@@ -76,3 +77,47 @@ func TestStartWritesToBuffer(t *testing.T) {
 	assert.Equal(t, host1, ar.buffer[0].Host)
 	assert.Equal(t, host2, ar.buffer[1].Host)
 }
+
+func TestStartCountsParseErrorsInsteadOfFailing(t *testing.T) {
+	// Setup stage
+	sharedCount := 0
+	callCount := &sharedCount
+	done := make(chan bool)
+
+	reader := Stub{
+		OpenStub: func(...interface{}) error { return nil },
+		ReadStub: func() ([]log.Info, error) {
+			*callCount++
+			switch *callCount {
+			case 1:
+				return []log.Info{log.Info{Host: "host1"}}, nil
+			case 2, 3:
+				return nil, &ParseError{Line: "garbage" + fmt.Sprint(*callCount), Err: fmt.Errorf("bad line")}
+			case 4:
+				done <- true
+				close(done)
+			}
+			return nil, nil
+		},
+	}
+	ar := Async{Reader: &reader}
+
+	err := ar.Open(nil)
+	assert.Nil(t, err)
+
+	// Exercise stage
+	ar.Start()
+	<-done
+	ar.Stop()
+
+	// Validation stage
+	logs, err := ar.Read()
+	assert.Nil(t, err)
+	assert.Len(t, logs, 1)
+	errs := ar.ParseErrors()
+	assert.Equal(t, uint64(2), errs.Count)
+	assert.Equal(t, "garbage3", errs.Samples[1].Line)
+
+	ar.Flush()
+	assert.Equal(t, ParseErrors{}, ar.ParseErrors())
+}
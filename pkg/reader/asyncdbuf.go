@@ -15,6 +15,8 @@ type ASyncDBuf struct {
 	reader  Async
 	wBuf    uint8 // write buffer index
 	buffers [2][]log.Info
+	// parseErrors are the lines that couldn't be parsed, double-buffered like buffers
+	parseErrors [2]ParseErrors
 }
 
 // Open inits the reader to asynchronously read the file pointed to by path
@@ -71,6 +73,11 @@ func (o *ASyncDBuf) Read() ([]log.Info, error) {
 	return o.buffers[nextBuf(o.wBuf)], nil
 }
 
+// ParseErrors returns the lines that couldn't be parsed before Swap was called
+func (o *ASyncDBuf) ParseErrors() ParseErrors {
+	return o.parseErrors[nextBuf(o.wBuf)]
+}
+
 // Swap copies written data to the back buffer so that they can be Read.
 // The back buffer is swapped to the front position so that it can be read.
 // Consequently, the front buffer is swapped to the back position so that it
@@ -86,6 +93,7 @@ func (o *ASyncDBuf) Swap() error {
 	// Copy the current work to the front buffer
 	o.buffers[o.wBuf] = make([]log.Info, len(buf))
 	copy(o.buffers[o.wBuf], buf)
+	o.parseErrors[o.wBuf] = o.reader.ParseErrors()
 
 	// Swap front and back buffers
 	o.wBuf = nextBuf(o.wBuf)
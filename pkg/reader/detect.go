@@ -0,0 +1,215 @@
+package reader
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/Juli3nnicolas/http_log_monitor/pkg/log"
+)
+
+const (
+	// AutoFormat is the format name selecting the log format automatically (see Detector)
+	AutoFormat string = "auto"
+	// DefaultSampleSize is the default number of lines sampled to detect a log format
+	DefaultSampleSize int = 100
+)
+
+// Candidate is a log format a Detector can choose
+type Candidate struct {
+	Name   string
+	Parser Parser
+}
+
+// KnownFormats returns the predefined formats a Detector can choose from, the most
+// specific ones first. The custom formats (nginx log_format or Apache LogFormat
+// strings) are added to the list.
+func KnownFormats(custom ...string) ([]Candidate, error) {
+	candidates := make([]Candidate, 0, 8+len(custom))
+
+	for _, format := range custom {
+		parser, err := FormatParser(format)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, Candidate{Name: format, Parser: parser})
+	}
+
+	for _, name := range []string{"caddy", "traefik", "json", LoadBalancerLogFormat, CloudFrontLogFormat, W3CLogFormat, CombinedLogFormat, CommonLogFormat} {
+		parser, err := FormatParser(name)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, Candidate{Name: name, Parser: parser})
+	}
+
+	return candidates, nil
+}
+
+// Detection describes the format chosen by a Detector
+type Detection struct {
+	Name       string  // chosen format, empty if no line has been sampled yet
+	Confidence float64 // ratio of sampled lines the chosen format could parse (between 0 and 1)
+	NbLines    int     // number of sampled lines
+	Done       bool    // true once the format is definitely chosen
+}
+
+// Detector picks the format of a log file amongst candidates. It scores the first
+// lines it is given against all candidates, the candidate parsing the most lines
+// with the most fields wins. The choice is definitive once SampleSize lines
+// have been sampled. Until then, lines are parsed with the best candidate so far.
+// Detector.Parse is a Parser so a Detector can be given to any reader. Directive
+// lines (see log.ErrDirective) are given to all candidates but aren't sampled.
+// It is safe to call Result from another goroutine.
+type Detector struct {
+	candidates []Candidate
+	sampleSize int
+	mutex      sync.Mutex
+	scores     []float64
+	successes  []int
+	nbLines    int
+	best       int // index of the best candidate so far, -1 if none
+	done       bool
+}
+
+// NewDetector creates a detector choosing from candidates after sampling
+// sampleSize lines (DefaultSampleSize if sampleSize <= 0)
+func NewDetector(candidates []Candidate, sampleSize int) *Detector {
+	if sampleSize <= 0 {
+		sampleSize = DefaultSampleSize
+	}
+
+	return &Detector{
+		candidates: candidates,
+		sampleSize: sampleSize,
+		scores:     make([]float64, len(candidates)),
+		successes:  make([]int, len(candidates)),
+		best:       -1,
+	}
+}
+
+// SampleFile samples the first lines of the file located at path. Files having
+// less lines than the sample size can be sampled further by Parse.
+func (d *Detector) SampleFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for !d.Result().Done && scanner.Scan() {
+		d.Parse(scanner.Bytes())
+	}
+
+	return scanner.Err()
+}
+
+// Parse parses data with the chosen format. While the format isn't chosen yet,
+// data is sampled and parsed with the best candidate so far.
+func (d *Detector) Parse(data []byte) (log.Info, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.done {
+		return d.candidates[d.best].Parser(data)
+	}
+
+	if len(d.candidates) == 0 {
+		return log.Info{}, fmt.Errorf("reader.Detector error - no candidate format")
+	}
+
+	infos := make([]log.Info, len(d.candidates))
+	errs := make([]error, len(d.candidates))
+	directive := false
+	for i := range d.candidates {
+		infos[i], errs[i] = d.candidates[i].Parser(data)
+		directive = directive || errs[i] == log.ErrDirective
+	}
+
+	// Directives aren't logs, they aren't sampled
+	if directive {
+		return log.Info{}, log.ErrDirective
+	}
+
+	for i := range d.candidates {
+		if errs[i] == nil {
+			d.successes[i]++
+			d.scores[i] += 1 + richness(&infos[i])
+		}
+	}
+	d.nbLines++
+
+	// The first candidate wins ties as they are the most specific ones
+	d.best = 0
+	for i := range d.scores {
+		if d.scores[i] > d.scores[d.best] {
+			d.best = i
+		}
+	}
+	d.done = d.nbLines >= d.sampleSize
+
+	if errs[d.best] == nil {
+		return infos[d.best], nil
+	}
+
+	// Fallback on the best candidate able to parse this line
+	fallback := -1
+	for i := range d.candidates {
+		if errs[i] == nil && (fallback < 0 || d.scores[i] > d.scores[fallback]) {
+			fallback = i
+		}
+	}
+	if fallback >= 0 {
+		return infos[fallback], nil
+	}
+
+	return log.Info{}, errs[d.best]
+}
+
+// Result returns the format chosen so far
+func (d *Detector) Result() Detection {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.best < 0 {
+		return Detection{Done: d.done}
+	}
+
+	return Detection{
+		Name:       d.candidates[d.best].Name,
+		Confidence: float64(d.successes[d.best]) / float64(d.nbLines),
+		NbLines:    d.nbLines,
+		Done:       d.done,
+	}
+}
+
+// optionalFields lists the fields making a parsed line rich
+var optionalFields = []log.Field{
+	log.AuthUserField,
+	log.RequestField,
+	log.SizeField,
+	log.RefererField,
+	log.UserAgentField,
+	log.TimeField,
+	log.DurationField,
+}
+
+// richness returns the ratio (between 0 and 1) of optional fields a parsed line provides
+func richness(info *log.Info) float64 {
+	known := 0
+	for _, f := range optionalFields {
+		if info.Has(f) {
+			known++
+		}
+	}
+	if info.Host != "" {
+		known++
+	}
+	if info.Request.Code != 0 {
+		known++
+	}
+
+	return float64(known) / float64(len(optionalFields)+2)
+}
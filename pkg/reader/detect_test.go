@@ -0,0 +1,137 @@
+package reader
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectorPicksCombinedFormatFromFileSample(t *testing.T) {
+	// Setup stage
+	candidates, err := KnownFormats()
+	assert.Nil(t, err)
+	d := NewDetector(candidates, 5)
+
+	// Exercise stage
+	err = d.SampleFile("./file_test.log")
+
+	// Validation stage
+	assert.Nil(t, err)
+	res := d.Result()
+	assert.Equal(t, CombinedLogFormat, res.Name)
+	assert.Equal(t, float64(1), res.Confidence)
+	assert.Equal(t, 5, res.NbLines)
+	assert.True(t, res.Done)
+}
+
+func TestDetectorPicksCommonFormatAndToleratesBadLines(t *testing.T) {
+	candidates, err := KnownFormats()
+	assert.Nil(t, err)
+	d := NewDetector(candidates, 4)
+
+	_, err = d.Parse([]byte(`172.17.0.1 - - [09/Feb/2020:16:27:00 +0000] "GET / HTTP/1.1" 200 612`))
+	assert.Nil(t, err)
+	_, err = d.Parse([]byte(`garbage`))
+	assert.NotNil(t, err)
+	_, err = d.Parse([]byte(`172.17.0.1 - - [09/Feb/2020:16:27:01 +0000] "GET /a HTTP/1.1" 404 12`))
+	assert.Nil(t, err)
+	assert.False(t, d.Result().Done)
+	info, err := d.Parse([]byte(`172.17.0.1 - - [09/Feb/2020:16:27:02 +0000] "GET /b HTTP/1.1" 200 1`))
+	assert.Nil(t, err)
+	assert.Equal(t, "/b", info.Request.Route)
+
+	res := d.Result()
+	assert.Equal(t, CommonLogFormat, res.Name)
+	assert.Equal(t, 0.75, res.Confidence)
+	assert.True(t, res.Done)
+
+	// The choice is definitive, lines of other formats aren't parsed anymore
+	_, err = d.Parse([]byte(`{"status":200,"uri":"/"}`))
+	assert.NotNil(t, err)
+}
+
+func TestDetectorPicksJSONPresetsAndCustomFormats(t *testing.T) {
+	const custom string = `$remote_addr [$time_local] $status $request_time`
+	candidates, err := KnownFormats(custom)
+	assert.Nil(t, err)
+
+	lines := map[string]string{
+		`{"ts":1581265620.5,"request":{"remote_ip":"10.0.0.1","method":"GET","uri":"/"},"status":200,"size":1,"duration":0.1}`: "caddy",
+		`{"ClientHost":"10.0.0.1","DownstreamStatus":200,"RequestPath":"/","StartUTC":"2020-02-09T16:27:00Z"}`:                 "traefik",
+		`{"remote_addr":"10.0.0.1","status":200,"uri":"/","time":"2020-02-09T16:27:00Z"}`:                                      "json",
+		`10.0.0.1 [09/Feb/2020:16:27:00 +0000] 200 0.012`:                                                                      custom,
+	}
+
+	for line, format := range lines {
+		d := NewDetector(candidates, 1)
+
+		_, err := d.Parse([]byte(line))
+
+		assert.Nil(t, err, format)
+		assert.Equal(t, format, d.Result().Name)
+	}
+}
+
+func TestDetectorSampleFileDoesntFailOnEmptyFiles(t *testing.T) {
+	f, err := ioutil.TempFile("", "detect_test")
+	if err != nil {
+		panic(err)
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+
+	candidates, err := KnownFormats()
+	assert.Nil(t, err)
+	d := NewDetector(candidates, 0)
+
+	err = d.SampleFile(f.Name())
+
+	assert.Nil(t, err)
+	assert.Equal(t, Detection{}, d.Result())
+	assert.NotNil(t, d.SampleFile("./foo.bar"))
+}
+
+func TestDetectorPicksW3CFormatWithoutSamplingDirectives(t *testing.T) {
+	candidates, err := KnownFormats()
+	assert.Nil(t, err)
+	d := NewDetector(candidates, 0)
+
+	err = d.SampleFile("./w3c_test.log")
+
+	assert.Nil(t, err)
+	res := d.Result()
+	assert.Equal(t, W3CLogFormat, res.Name)
+	assert.Equal(t, float64(1), res.Confidence)
+	assert.Equal(t, 3, res.NbLines)
+	assert.False(t, res.Done)
+}
+
+func TestDetectorPicksCloudLoadBalancerAndCDNFormats(t *testing.T) {
+	lines := map[string][]string{
+		LoadBalancerLogFormat: {`http 2018-07-02T22:23:00.186641Z app/my-lb/50dc6c495c0c9188 192.168.131.39:2817 10.0.0.1:80 0.000 0.001 0.000 200 200 34 366 "GET http://www.example.com:80/ HTTP/1.1" "curl/7.46.0" - - arn:aws:elasticloadbalancing:us-east-2:123456789012:targetgroup/my-targets/73e2d6bc24d8a067 "Root=1-58337262-36d228ad5d99923122bbe354" "-" "-" 0 2018-07-02T22:22:48.364000Z "forward" "-" "-" "10.0.0.1:80" "200" "-" "-"`},
+		CloudFrontLogFormat: {
+			"#Version: 1.0",
+			"#Fields: date time x-edge-location sc-bytes c-ip cs-method cs-uri-stem sc-status time-taken",
+			"2019-12-04\t21:02:31\tLAX1\t392\t192.0.2.100\tGET\t/index.html\t200\t0.001",
+		},
+		W3CLogFormat: {
+			"#Fields: date time c-ip cs-method cs-uri-stem sc-status sc-bytes time-taken",
+			"2019-12-04 21:02:31 192.0.2.100 GET /index.html 200 392 1",
+		},
+	}
+
+	for format, sample := range lines {
+		candidates, err := KnownFormats()
+		assert.Nil(t, err)
+		d := NewDetector(candidates, 1)
+
+		for _, line := range sample {
+			_, err = d.Parse([]byte(line))
+		}
+
+		assert.Nil(t, err, format)
+		assert.Equal(t, format, d.Result().Name)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/Juli3nnicolas/http_log_monitor/pkg/log"
 )
@@ -27,7 +28,111 @@ func CommonLogFormatParser() Parser {
 }
 
 func commonLogFormatParser(data []byte) (log.Info, error) {
-	return log.Parse(string(data))
+	return log.ParseBytes(data)
+}
+
+// CombinedLogFormatParser reads logs following the combined log format used by
+// Apache's httpd and nginx. It is the common log format followed by the referer
+// and the user-agent.
+func CombinedLogFormatParser() Parser {
+	return combinedLogFormatParser
+}
+
+func combinedLogFormatParser(data []byte) (log.Info, error) {
+	return log.ParseCombinedBytes(data)
+}
+
+// W3CParser reads W3C Extended logs such as IIS ones. The parser is stateful,
+// it keeps track of the #Fields directives of the file so a new parser must be
+// created for each file.
+func W3CParser() Parser {
+	return log.NewW3C().Parse
+}
+
+// CloudFrontParser reads AWS CloudFront standard logs. Like W3CParser, the parser
+// is stateful so a new parser must be created for each file.
+func CloudFrontParser() Parser {
+	return log.NewCloudFront().Parse
+}
+
+// LoadBalancerParser reads AWS Application (ALB) and Classic (ELB) Load Balancer logs
+func LoadBalancerParser() Parser {
+	return loadBalancerParser
+}
+
+func loadBalancerParser(data []byte) (log.Info, error) {
+	return log.ParseLoadBalancer(data)
+}
+
+const (
+	// CommonLogFormat is the name of the format read by CommonLogFormatParser
+	CommonLogFormat string = "common"
+	// CombinedLogFormat is the name of the format read by CombinedLogFormatParser
+	CombinedLogFormat string = "combined"
+	// W3CLogFormat is the name of the format read by W3CParser
+	W3CLogFormat string = "w3c"
+	// CloudFrontLogFormat is the name of the format read by CloudFrontParser
+	CloudFrontLogFormat string = "cloudfront"
+	// LoadBalancerLogFormat is the name of the format read by LoadBalancerParser
+	LoadBalancerLogFormat string = "alb"
+)
+
+// FormatParser returns the parser for a log format. The format is either :
+// - the name of a predefined format (common, combined, w3c or its alias iis,
+// cloudfront, alb or its alias elb)
+// - the name of a JSON-lines format (json, caddy, traefik) optionally followed by
+// field-key overrides, for instance "json:uri=request.path,time=@timestamp"
+// (see log.JSONFields.Override)
+// - a custom nginx log_format or Apache LogFormat string such as `%h %l %u %t "%r" %>s %b %D`
+func FormatParser(format string) (Parser, error) {
+	switch format {
+	case CommonLogFormat, "clf", "":
+		return CommonLogFormatParser(), nil
+	case CombinedLogFormat:
+		return CombinedLogFormatParser(), nil
+	case W3CLogFormat, "iis":
+		return W3CParser(), nil
+	case CloudFrontLogFormat:
+		return CloudFrontParser(), nil
+	case LoadBalancerLogFormat, "elb":
+		return LoadBalancerParser(), nil
+	}
+
+	preset, overrides := format, ""
+	if i := strings.IndexByte(format, ':'); i >= 0 {
+		preset, overrides = format[:i], format[i+1:]
+	}
+	if fields, ok := log.JSONPreset(preset); ok {
+		fields, err := fields.Override(overrides)
+		if err != nil {
+			return nil, err
+		}
+		return JSONParser(fields), nil
+	}
+
+	f, err := log.NewFormat(format)
+	if err != nil {
+		return nil, err
+	}
+	return f.Parse, nil
+}
+
+// ProxyAwareParser wraps parse so that the client of the requests sent by trusted
+// proxies is read from the X-Forwarded-For header (see log.TrustedProxies)
+func ProxyAwareParser(parse Parser, trusted log.TrustedProxies) Parser {
+	return func(data []byte) (log.Info, error) {
+		info, err := parse(data)
+		if err == nil {
+			trusted.ResolveClient(&info)
+		}
+		return info, err
+	}
+}
+
+// JSONParser reads JSON-lines access logs, fields gives the keys of the
+// log.Info fields in the JSON objects
+func JSONParser(fields log.JSONFields) Parser {
+	return log.NewJSONFormat(fields).Parse
 }
 
 // Open opens a file in read mode
@@ -56,13 +161,17 @@ func (r *File) Open(path ...interface{}) error {
 	return nil
 }
 
-// Read reads a file content line by line
-// Returns a nil slice when reaching EOF
+// Read reads a file content line by line, directive lines are skipped
+// Returns a nil slice when reaching EOF. A *ParseError is returned when a line
+// can't be parsed, the next call reads the following line.
 func (r *File) Read() ([]log.Info, error) {
-	if r.scanner.Scan() {
-		v, err := r.Parse([]byte(r.scanner.Text()))
+	for r.scanner.Scan() {
+		v, err := r.Parse(r.scanner.Bytes())
+		if err == log.ErrDirective {
+			continue
+		}
 		if err != nil {
-			return nil, err
+			return nil, &ParseError{Line: r.scanner.Text(), Err: err}
 		}
 		return []log.Info{v}, nil
 	}
@@ -82,3 +191,33 @@ func (r *File) Close() {
 	}
 	r.scanner = nil
 }
+
+// HasDirectives tells if the logs of format declare their fields with directives
+// (W3C Extended and CloudFront logs)
+func HasDirectives(format string) bool {
+	switch format {
+	case W3CLogFormat, "iis", CloudFrontLogFormat:
+		return true
+	}
+	return false
+}
+
+// ReadDirectives gives the directive lines (starting with '#') of the file located
+// at path to parse. It lets stateful parsers such as W3CParser know the fields
+// of a file that is read from its end.
+func ReadDirectives(path string, parse Parser) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := scanner.Bytes(); len(line) > 0 && line[0] == '#' {
+			parse(line)
+		}
+	}
+
+	return scanner.Err()
+}
@@ -1,6 +1,7 @@
 package reader
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
 	"strings"
@@ -112,3 +113,139 @@ func TestReadOutputAllLines(t *testing.T) {
 	// Validation
 	assert.Equal(t, expectedResp, responses)
 }
+
+func TestReadWithCombinedLogFormatParserFillsUserAgent(t *testing.T) {
+	r := File{Parse: CombinedLogFormatParser()}
+	err := r.Open("./file_test.log")
+	defer r.Close()
+	assert.Nil(t, err)
+
+	resp, err := r.Read()
+
+	assert.Nil(t, err)
+	assert.Len(t, resp, 1)
+	assert.Equal(t, "curl/7.54.0", resp[0].UserAgent)
+	assert.Equal(t, "", resp[0].Referer)
+}
+
+func TestFormatParserReturnsPredefinedAndCustomParsers(t *testing.T) {
+	const line string = `172.17.0.1 - - [09/Feb/2020:16:27:00 +0000] "GET / HTTP/1.1" 200 612 "-" "curl/7.54.0"`
+
+	for _, format := range []string{CommonLogFormat, CombinedLogFormat, `%h %l %u %t "%r" %>s %b "%{Referer}i" "%{User-Agent}i"`} {
+		parse, err := FormatParser(format)
+		assert.Nil(t, err, format)
+
+		info, err := parse([]byte(line))
+		assert.Nil(t, err, format)
+		assert.Equal(t, uint32(200), info.Request.Code, format)
+	}
+
+	_, err := FormatParser("foo")
+	assert.NotNil(t, err)
+}
+
+func TestFormatParserReturnsJSONParsersWithOverrides(t *testing.T) {
+	parse, err := FormatParser("json:uri=path,status=code")
+	assert.Nil(t, err)
+
+	info, err := parse([]byte(`{"path":"/a","code":503}`))
+
+	assert.Nil(t, err)
+	assert.Equal(t, "/a", info.Request.Route)
+	assert.Equal(t, uint32(503), info.Request.Code)
+
+	_, err = FormatParser("caddy:foo=bar")
+	assert.NotNil(t, err)
+}
+
+func TestReadSkipsW3CDirectives(t *testing.T) {
+	// Setup
+	r := File{Parse: W3CParser()}
+	err := r.Open("./w3c_test.log")
+	defer r.Close()
+	assert.Nil(t, err)
+
+	// Exercise
+	var routes []string
+	resp, err := r.Read()
+	for resp != nil && err == nil {
+		routes = append(routes, resp[0].Request.Route)
+		resp, err = r.Read()
+	}
+
+	// Validation
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"/index.html", "/img/logo.png", "/api/users"}, routes)
+}
+
+func TestReadDirectivesGivesTheLastFieldsToTheParser(t *testing.T) {
+	parse, err := FormatParser("iis")
+	assert.Nil(t, err)
+
+	err = ReadDirectives("./w3c_test.log", parse)
+
+	assert.Nil(t, err)
+	info, err := parse([]byte("2020-02-09 16:27:03 192.168.1.3 GET /api/users 200 1024 8"))
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1024), info.Request.Size)
+	assert.NotNil(t, ReadDirectives("./foo.bar", parse))
+}
+
+func TestHasDirectives(t *testing.T) {
+	for _, format := range []string{"w3c", "iis", "cloudfront"} {
+		assert.True(t, HasDirectives(format), format)
+	}
+	for _, format := range []string{"", "common", "combined", "alb", "json", AutoFormat} {
+		assert.False(t, HasDirectives(format), format)
+	}
+}
+
+func TestReadReturnsParseErrorsAndGoesOn(t *testing.T) {
+	// Setup
+	r := File{Parse: func(data []byte) (log.Info, error) {
+		if strings.Contains(string(data), "/index.html") {
+			return log.Info{}, fmt.Errorf("bad line")
+		}
+		return log.Info{Host: string(data)}, nil
+	}}
+	err := r.Open("./w3c_test.log")
+	defer r.Close()
+	assert.Nil(t, err)
+
+	// Exercise
+	var parsed, bad int
+	var parseErr *ParseError
+	resp, err := r.Read()
+	for resp != nil || err != nil {
+		if err != nil {
+			bad++
+			var ok bool
+			parseErr, ok = err.(*ParseError)
+			assert.True(t, ok)
+		} else {
+			parsed++
+		}
+		resp, err = r.Read()
+	}
+
+	// Validation
+	assert.Equal(t, 7, parsed)
+	assert.Equal(t, 1, bad)
+	assert.Equal(t, "bad line", parseErr.Err.Error())
+	assert.True(t, strings.HasPrefix(parseErr.Line, "2020-02-09 16:27:00"))
+}
+
+func TestProxyAwareParserResolvesTheClient(t *testing.T) {
+	trusted, err := log.ParseTrustedProxies([]string{"10.0.0.0/8"})
+	assert.Nil(t, err)
+	parse, err := FormatParser(`$remote_addr [$time_local] $status "$http_x_forwarded_for"`)
+	assert.Nil(t, err)
+	parse = ProxyAwareParser(parse, trusted)
+
+	info, err := parse([]byte(`10.0.0.2 [09/Feb/2020:16:27:00 +0000] 200 "203.0.113.7, 10.0.0.1"`))
+
+	assert.Nil(t, err)
+	assert.Equal(t, "203.0.113.7", info.Host)
+	_, err = parse([]byte(`garbage`))
+	assert.NotNil(t, err)
+}
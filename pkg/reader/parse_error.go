@@ -0,0 +1,50 @@
+package reader
+
+import "fmt"
+
+// MaxParseErrorSamples is the number of bad lines kept by ParseErrors
+const MaxParseErrorSamples int = 5
+
+// ParseError is returned by readers when a line can't be parsed. Reading can
+// go on after such an error.
+type ParseError struct {
+	Line string // line that couldn't be parsed
+	Err  error  // error returned by the parser
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("cannot parse line %q - %v", e.Line, e.Err)
+}
+
+// ParseErrors counts the lines that couldn't be parsed and keeps the last ones
+type ParseErrors struct {
+	Count   uint64
+	Samples []ParseError // last MaxParseErrorSamples bad lines, the most recent last
+}
+
+// Add counts e and keeps it as a sample
+func (o *ParseErrors) Add(e ParseError) {
+	o.Count++
+	o.Samples = appendSample(o.Samples, e)
+}
+
+// Merge adds the errors counted by other, its samples are considered more recent
+func (o *ParseErrors) Merge(other *ParseErrors) {
+	o.Count += other.Count
+	for _, e := range other.Samples {
+		o.Samples = appendSample(o.Samples, e)
+	}
+}
+
+// appendSample appends e to samples, dropping the oldest sample if there are
+// more than MaxParseErrorSamples. A new slice is returned so that previously
+// returned samples are never modified.
+func appendSample(samples []ParseError, e ParseError) []ParseError {
+	if len(samples) >= MaxParseErrorSamples {
+		samples = samples[len(samples)-MaxParseErrorSamples+1:]
+	}
+
+	s := make([]ParseError, len(samples), len(samples)+1)
+	copy(s, samples)
+	return append(s, e)
+}
@@ -0,0 +1,32 @@
+package reader
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseErrorsKeepsTheLastSamples(t *testing.T) {
+	// Setup stage
+	errs := ParseErrors{}
+
+	// Exercise stage
+	for i := 0; i < MaxParseErrorSamples+2; i++ {
+		errs.Add(ParseError{Line: strconv.Itoa(i), Err: fmt.Errorf("bad line")})
+	}
+	kept := errs.Samples
+	other := ParseErrors{}
+	other.Add(ParseError{Line: "new"})
+	errs.Merge(&other)
+
+	// Validation stage
+	assert.Equal(t, uint64(MaxParseErrorSamples+3), errs.Count)
+	assert.Len(t, errs.Samples, MaxParseErrorSamples)
+	assert.Equal(t, "3", errs.Samples[0].Line)
+	assert.Equal(t, "new", errs.Samples[MaxParseErrorSamples-1].Line)
+	// Previously returned samples aren't modified
+	assert.Equal(t, "2", kept[0].Line)
+	assert.Equal(t, "6", kept[MaxParseErrorSamples-1].Line)
+}
@@ -6,7 +6,6 @@ import (
 	"time"
 
 	"github.com/Juli3nnicolas/http_log_monitor/pkg/log"
-	"github.com/Juli3nnicolas/http_log_monitor/pkg/logger"
 	"github.com/papertrail/go-tail/follower"
 )
 
@@ -54,6 +53,7 @@ func (r *Tail) Open(args ...interface{}) error {
 
 // Read reads a file content line by line
 // Sleeps wawaiting for data when io.EOF is reached
+// A *ParseError is returned when a line can't be parsed
 func (r *Tail) Read() ([]log.Info, error) {
 
 	select {
@@ -71,10 +71,12 @@ func (r *Tail) parseLine(line follower.Line) ([]log.Info, error) {
 	}
 
 	parsedLine, err := r.Parse(line.Bytes())
-	if err != nil {
-		logger.Get().Warn(err)
+	if err == log.ErrDirective {
 		return nil, nil
 	}
+	if err != nil {
+		return nil, &ParseError{Line: line.String(), Err: err}
+	}
 
 	return []log.Info{parsedLine}, nil
 }
@@ -2,31 +2,45 @@ package task
 
 import (
 	"fmt"
+	"math"
 	"time"
 
 	"github.com/Juli3nnicolas/http_log_monitor/pkg/timer"
 )
 
 // Alert is a task to alert users that a specific threshold has been exceeded
-// At the moment, thresolds can only be defined on an average request-per-duration.
-// Where duration is the time interval the alert is going to monitor the traffic.
-// It would require a small amount of work to consider other metrics (global or
-// per frame) as run already receives a task.Rates struct.
+// Thresolds are defined on a metric's average over a duration (the request-rate
+// by default, see Metric). Where duration is the time interval the alert is going
+// to monitor the traffic.
 // Example : If an alert has a duration of 2 minutes and a threshold of 10 req/s,
 // the alert is triggered if the average request per second during those 2 minutes
 // is greater than 10 req/s.
 // Conversely, the alert recovers if during 2 minutes, the traffic is below 10 req/s.
+// The comparison can be reversed to catch drops (see Comparison).
+// Hysteresis keeps a metric hovering around the threshold from making the alert flap:
+// the alert can resolve past a distinct threshold, be pending for several periods
+// before it fires and fire for a minimum time.
 type Alert struct {
 	// time when the monitoring session starts
 	start time.Time
 	// time when the monitoring session ends
 	duration time.Duration
-	// average request-per-duration
-	avgReq uint64
-	// number of conducted measures, used to compute avgReq
+	// monitored metric
+	metric Metric
+	// metric's average value over the duration
+	avg float64
+	// number of conducted measures, used to compute avg
 	nbMeasures uint64
 	// threshold value, above the alert is triggered
-	threshold uint64
+	threshold float64
+	// comparison of the metric's average to the threshold
+	comparison Comparison
+	// hysteresis delays the alert's transitions
+	hysteresis Hysteresis
+	// previous is the metric's average over the previous duration period, used by ChangeBy
+	previous float64
+	// hasPrevious is true once a whole duration period has been measured
+	hasPrevious bool
 	// nbReqs is the number of requests that occured during a duration period
 	nbReqs float64
 	// done is true if the task has finished measuring for the current frame
@@ -35,33 +49,142 @@ type Alert struct {
 	state AlertState
 }
 
+// Comparison is how an alert compares its metric to its threshold
+type Comparison int
+
+const (
+	// Above triggers the alert when the metric's average is greater than or equal to the threshold
+	Above Comparison = iota
+	// Below triggers the alert when the metric's average is lower than the threshold
+	Below
+	// ChangeBy triggers the alert when the metric's average has changed by at least
+	// threshold percent compared to the previous duration period (increase or decrease)
+	ChangeBy
+)
+
+// String returns the comparison's operator
+func (c Comparison) String() string {
+	switch c {
+	case Below:
+		return "<"
+	case ChangeBy:
+		return "change"
+	}
+	return ">"
+}
+
+// ParseComparison reads a comparison operator, > (above), < (below) or change (%)
+func ParseComparison(op string) (Comparison, error) {
+	switch op {
+	case ">", "above":
+		return Above, nil
+	case "<", "below":
+		return Below, nil
+	case "change", "%":
+		return ChangeBy, nil
+	}
+	return Above, fmt.Errorf("unknown comparison %q - it must be >, < or change", op)
+}
+
+// Hysteresis delays an alert's transitions so that it doesn't flap
+type Hysteresis struct {
+	// ResolveThreshold is the value the metric's average must go back past for a
+	// firing alert to resolve (below for Above and ChangeBy, above for Below).
+	// The threshold is used if it is 0.
+	ResolveThreshold float64
+	// PendingWindows is the number of consecutive periods the threshold must be
+	// exceeded on before the alert fires, 1 if it is 0
+	PendingWindows uint64
+	// MinFiring is the minimum time an alert fires before it can resolve
+	MinFiring time.Duration
+}
+
+// AlertStatus is the stage an alert is at
+type AlertStatus int
+
+const (
+	// Inactive alerts haven't exceeded their threshold on the last period
+	Inactive AlertStatus = iota
+	// Pending alerts have exceeded their threshold but not on enough periods to fire yet
+	Pending
+	// Firing alerts are on
+	Firing
+)
+
+// String returns the status' name
+func (s AlertStatus) String() string {
+	switch s {
+	case Pending:
+		return "pending"
+	case Firing:
+		return "firing"
+	}
+	return "inactive"
+}
+
 // AlertState is a struct describing an alert's state
 type AlertState struct {
-	// IsOn is true when the alert is active.
+	// Name is the name of the alert's rule, empty if the alert isn't part of an AlertManager
+	Name string
+	// Expr is the expression the alert is evaluated on (see package expr), empty for
+	// the alerts comparing a Metric to a Threshold
+	Expr string
+	// IsOn is true when the alert is active (Status == Firing).
 	// It is false if there wasn't any alert or the system recovered
 	IsOn bool
+	// Status is the alert's stage, a pending alert hasn't fired yet
+	Status AlertStatus
 	// Duration is the time spent to check the alert, and the time it takes to cool down
 	Duration time.Duration
-	// Threshold is the value triggering the alert if the average rate is greater on a whole Duration-period
-	Threshold uint64
-	// Avg is the average req/s the alert was triggered at (always 0 if IsOn)
+	// Metric is the name of the monitored metric
+	Metric string
+	// Unit is the unit of the monitored metric
+	Unit string
+	// Threshold is the value triggering the alert if the metric's average is greater on a whole Duration-period
+	Threshold float64
+	// Comparison is how the metric's average is compared to Threshold
+	Comparison Comparison
+	// ResolveThreshold is the value the metric's average must go back past for the alert to resolve
+	ResolveThreshold float64
+	// PendingWindows is the number of consecutive periods Threshold must be exceeded on to fire
+	PendingWindows uint64
+	// NbPending is the number of consecutive periods Threshold has been exceeded on while pending
+	NbPending uint64
+	// MinFiring is the minimum time the alert fires before it can resolve
+	MinFiring time.Duration
+	// Value is the metric's average the alert was triggered at (always 0 if !IsOn),
+	// for a ChangeBy comparison it is the change's percentage
+	Value float64
+	// Current is the value the alert was last evaluated on, whatever its status
+	Current float64
+	// Avg is Value truncated to an integer, for the request-rate it is the average req/s
+	// the alert was triggered at (always 0 if !IsOn)
 	Avg uint64
 	// NbReqs is number of requests that triggered the alert. Always equals 0 when IsOn == false
 	NbReqs uint64
 	// Date is the time the alert has been switched on or off. It has a default value
 	// in case the alert has never been activated.
 	Date time.Time
+	// Silenced is true until SilencedUntil, the alert is evaluated but not notified
+	// (see AlertManager.Apply)
+	Silenced      bool
+	SilencedUntil time.Time
+	// Acknowledged is true once the firing alert is being taken care of, until it resolves
+	Acknowledged bool
 }
 
 // Init sets up the task, it needs in order :
 // - duration time.Duration : monitoring interval, if you give it 2 minutes
 // the traffic will be monitored in time-slices of 2 minutes.
-// - threshold uint64 : average req/s value above which the alert is triggerd.
+// - threshold uint64 or float64 : average metric value above which the alert is triggerd.
 // The alert is in recover-state if it goes below. For an alert to be triggered,
 // the threshold must be exceeded on average during "duration" time.
+// - metric Metric (optional) : monitored metric, the request-rate (req/s) by default
+// - comparison Comparison (optional) : Above by default
+// - hysteresis Hysteresis (optional) : the alert fires and resolves on the threshold by default
 func (o *Alert) Init(args ...interface{}) error {
-	if len(args) != 2 {
-		return fmt.Errorf("wrong parameters - the following parameters are needed (duration time.Duration, reqPSecThreshold uint64)")
+	if len(args) < 2 || len(args) > 5 {
+		return fmt.Errorf("wrong parameters - the following parameters are needed (duration time.Duration, threshold uint64 [, metric Metric [, comparison Comparison [, hysteresis Hysteresis]]])")
 	}
 
 	var duration time.Duration
@@ -70,16 +193,68 @@ func (o *Alert) Init(args ...interface{}) error {
 		return fmt.Errorf("type error - got %T instead of %T", args[0], duration)
 	}
 
-	var threshold uint64
-	threshold, ok = args[1].(uint64)
-	if !ok {
-		return fmt.Errorf("type error - got %T instead of %T", args[1], threshold)
+	var threshold float64
+	switch t := args[1].(type) {
+	case uint64:
+		threshold = float64(t)
+	case float64:
+		threshold = t
+	default:
+		return fmt.Errorf("type error - got %T instead of uint64 or float64", args[1])
+	}
+
+	metric := RequestRateMetric()
+	if len(args) >= 3 {
+		metric, ok = args[2].(Metric)
+		if !ok || metric.Value == nil {
+			return fmt.Errorf("type error - got %T instead of a valid %T", args[2], metric)
+		}
+	}
+
+	comparison := Above
+	if len(args) >= 4 {
+		comparison, ok = args[3].(Comparison)
+		if !ok {
+			return fmt.Errorf("type error - got %T instead of %T", args[3], comparison)
+		}
+	}
+
+	var hysteresis Hysteresis
+	if len(args) == 5 {
+		hysteresis, ok = args[4].(Hysteresis)
+		if !ok {
+			return fmt.Errorf("type error - got %T instead of %T", args[4], hysteresis)
+		}
+	}
+	if hysteresis.ResolveThreshold == 0 {
+		hysteresis.ResolveThreshold = threshold
+	}
+	if hysteresis.PendingWindows == 0 {
+		hysteresis.PendingWindows = 1
+	}
+	if hysteresis.MinFiring < 0 {
+		return fmt.Errorf("wrong parameters - the minimum firing duration must be positive, got %v", hysteresis.MinFiring)
+	}
+	if comparison == Below && hysteresis.ResolveThreshold < threshold {
+		return fmt.Errorf("wrong parameters - the resolve threshold must be greater than or equal to the threshold, got %g < %g", hysteresis.ResolveThreshold, threshold)
+	}
+	if comparison != Below && hysteresis.ResolveThreshold > threshold {
+		return fmt.Errorf("wrong parameters - the resolve threshold must be lower than or equal to the threshold, got %g > %g", hysteresis.ResolveThreshold, threshold)
 	}
 
 	o.duration = duration
 	o.threshold = threshold
+	o.metric = metric
+	o.comparison = comparison
+	o.hysteresis = hysteresis
+	o.state.Comparison = comparison
+	o.state.ResolveThreshold = hysteresis.ResolveThreshold
+	o.state.PendingWindows = hysteresis.PendingWindows
+	o.state.MinFiring = hysteresis.MinFiring
 	o.state.Duration = duration
 	o.state.Threshold = threshold
+	o.state.Metric = metric.Name
+	o.state.Unit = metric.Unit
 
 	return nil
 }
@@ -97,21 +272,26 @@ func (o *Alert) BeforeRun(...interface{}) error {
 
 // Run executes the monitoring process, triggers or recovers the alert
 // Parameters :
-// - rates task.Rates : traffic information used to trigger the alert
-// (only task.Rates.Frame.ReqPerS is used at the moment)
+// - metrics task.Metrics : frame measures used to trigger the alert. A task.Rates
+// is accepted as well when the request-rate is monitored.
 // - timer : a Timer interface, only uses Timer.Now()
 func (o *Alert) Run(args ...interface{}) error {
 
 	argsLen := len(args)
 	if argsLen != 2 {
-		return fmt.Errorf("wrong parameters -  this function expects a task.Rates parameter and a Timer struct")
+		return fmt.Errorf("wrong parameters -  this function expects a task.Metrics parameter and a Timer struct")
 	}
 
-	var rates Rates
-	rates, ok := args[0].(Rates)
-	if !ok {
-		return fmt.Errorf("type error - got %T instead of %T", args[0], rates)
+	var metrics Metrics
+	switch m := args[0].(type) {
+	case Metrics:
+		metrics = m
+	case Rates:
+		metrics = Metrics{Rates: m}
+	default:
+		return fmt.Errorf("type error - got %T instead of %T", args[0], metrics)
 	}
+	rates := metrics.Rates
 
 	t, ok := args[1].(timer.Timer)
 	if !ok {
@@ -121,30 +301,22 @@ func (o *Alert) Run(args ...interface{}) error {
 	now := t.Now()
 
 	// floats are used to be sure to work out the exact value (to avoid decimal-part-truncation)
-	o.avgReq = (o.avgReq*o.nbMeasures + rates.Frame.ReqPerS) / (o.nbMeasures + 1)
+	o.avg = (o.avg*float64(o.nbMeasures) + o.metric.Value(&metrics)) / float64(o.nbMeasures+1)
 	o.nbMeasures++
 
 	// Count ongoing requests in current time-frame
-	o.nbReqs += float64(rates.Frame.ReqPerS) * float64(rates.Frame.Duration)
+	o.nbReqs += rates.Frame.Rate * float64(rates.Frame.Duration)
 
 	if now.Sub(o.start) >= o.duration {
-		if !o.state.IsOn && o.avgReq >= o.threshold {
-			o.state.IsOn = true
-			o.state.Date = now
-			o.state.NbReqs = uint64(o.nbReqs)
-			o.state.Avg = o.avgReq
-		}
-
-		if o.state.IsOn && o.avgReq < o.threshold {
-			o.state.IsOn = false
-			o.state.Date = now
-			o.state.NbReqs = 0
-			o.state.Avg = 0
+		if value, known := o.evaluate(); known {
+			o.transition(value, now)
 		}
 
 		// Restart a new monitoring process
+		o.previous = o.avg
+		o.hasPrevious = true
 		o.start = now
-		o.avgReq = 0
+		o.avg = 0
 		o.nbMeasures = 0
 		o.nbReqs = 0
 	}
@@ -153,6 +325,86 @@ func (o *Alert) Run(args ...interface{}) error {
 	return nil
 }
 
+// transition moves the alert to its next status given the period's value
+func (o *Alert) transition(value float64, now time.Time) {
+	s := &o.state
+	s.Current = value
+
+	if s.Status == Firing {
+		if !o.resolves(value) || now.Sub(s.Date) < o.hysteresis.MinFiring {
+			return
+		}
+
+		s.Status = Inactive
+		s.IsOn = false
+		s.Date = now
+		s.NbReqs = 0
+		s.Value = 0
+		s.Avg = 0
+		return
+	}
+
+	if !o.fires(value) {
+		s.Status = Inactive
+		s.NbPending = 0
+		return
+	}
+
+	s.NbPending++
+	if s.NbPending < o.hysteresis.PendingWindows {
+		s.Status = Pending
+		return
+	}
+
+	s.Status = Firing
+	s.IsOn = true
+	s.NbPending = 0
+	s.Date = now
+	s.NbReqs = uint64(o.nbReqs)
+	s.Value = value
+	s.Avg = uint64(o.avg)
+}
+
+// fires is true if value exceeds the threshold
+func (o *Alert) fires(value float64) bool {
+	if o.comparison == Below {
+		return value < o.threshold
+	}
+	return value >= o.threshold
+}
+
+// resolves is true if value has gone back past the resolve threshold
+func (o *Alert) resolves(value float64) bool {
+	if o.comparison == Below {
+		return value >= o.hysteresis.ResolveThreshold
+	}
+	return value < o.hysteresis.ResolveThreshold
+}
+
+// evaluate returns the value compared to the thresholds, the metric's average over
+// the period or its change, and false if it can't be evaluated yet (ChangeBy needs
+// a previous period).
+func (o *Alert) evaluate() (value float64, known bool) {
+	if o.comparison != ChangeBy {
+		return o.avg, true
+	}
+
+	if !o.hasPrevious {
+		return 0, false
+	}
+
+	change := math.Abs(o.avg - o.previous)
+	if o.previous == 0 {
+		// Any change from 0 is infinite
+		if change == 0 {
+			return 0, true
+		}
+		return math.Inf(1), true
+	}
+
+	return change / math.Abs(o.previous) * 100, true
+}
+
 // AfterRun does nothing, implements the Task interface
 func (o *Alert) AfterRun() error {
 	return nil
@@ -0,0 +1,142 @@
+package task
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// AlertAction is an operator's action on an alert (see AlertControl)
+type AlertAction int
+
+const (
+	// Silence mutes an alert's notifications until its silence expires
+	Silence AlertAction = iota
+	// Unsilence lifts an alert's silence
+	Unsilence
+	// Acknowledge flags a firing alert as being taken care of until it resolves
+	Acknowledge
+)
+
+// String returns the action's command
+func (a AlertAction) String() string {
+	switch a {
+	case Unsilence:
+		return "unsilence"
+	case Acknowledge:
+		return "ack"
+	}
+	return "silence"
+}
+
+// AlertControl is an action on the alert called Name, Duration is how long a silence lasts
+type AlertControl struct {
+	Action   AlertAction
+	Name     string
+	Duration time.Duration
+}
+
+// String returns the control's command (see ParseAlertControl)
+func (c AlertControl) String() string {
+	if c.Action == Silence {
+		return fmt.Sprintf("%s %s %v", c.Action, c.Name, c.Duration)
+	}
+	return fmt.Sprintf("%s %s", c.Action, c.Name)
+}
+
+// ParseAlertControl reads a command acting on an alert, it is one of
+// silence <name> <duration> (silence traffic 2h), unsilence <name> or ack <name>
+func ParseAlertControl(cmd string) (AlertControl, error) {
+	fields := strings.Fields(cmd)
+	if len(fields) < 2 {
+		return AlertControl{}, fmt.Errorf("invalid command %q - it must be silence <alert> <duration>, unsilence <alert> or ack <alert>", cmd)
+	}
+
+	c := AlertControl{Name: fields[1]}
+	switch fields[0] {
+	case "silence":
+		if len(fields) != 3 {
+			return AlertControl{}, fmt.Errorf("invalid command %q - it must be silence <alert> <duration>", cmd)
+		}
+		d, err := time.ParseDuration(fields[2])
+		if err != nil || d <= 0 {
+			return AlertControl{}, fmt.Errorf("invalid command %q - the silence's duration must be positive such as 30m or 2h", cmd)
+		}
+		c.Action = Silence
+		c.Duration = d
+
+	case "unsilence":
+		c.Action = Unsilence
+	case "ack", "acknowledge":
+		c.Action = Acknowledge
+	default:
+		return AlertControl{}, fmt.Errorf("unknown command %q - it must be silence, unsilence or ack", fields[0])
+	}
+
+	if c.Action != Silence && len(fields) != 2 {
+		return AlertControl{}, fmt.Errorf("invalid command %q - it must be %s <alert>", cmd, c.Action)
+	}
+
+	return c, nil
+}
+
+// Apply carries out c at now, alerts are controlled by name. Only firing alerts can be acknowledged.
+func (o *AlertManager) Apply(c AlertControl, now time.Time) error {
+	var state *AlertState
+	for _, alert := range o.alerts {
+		if s := alert.Result(); s.Name == c.Name {
+			state = &s
+			break
+		}
+	}
+	if state == nil {
+		return fmt.Errorf("unknown alert %q", c.Name)
+	}
+
+	switch c.Action {
+	case Silence:
+		if c.Duration <= 0 {
+			return fmt.Errorf("wrong parameters - the silence's duration must be positive, got %v", c.Duration)
+		}
+		o.silences[c.Name] = now.Add(c.Duration)
+
+	case Unsilence:
+		delete(o.silences, c.Name)
+
+	case Acknowledge:
+		if !state.IsOn {
+			return fmt.Errorf("alert %q isn't firing", c.Name)
+		}
+		o.acks[c.Name] = true
+	}
+
+	return nil
+}
+
+// expireSilences lifts the silences expired at now
+func (o *AlertManager) expireSilences(now time.Time) {
+	for name, until := range o.silences {
+		if !now.Before(until) {
+			delete(o.silences, name)
+		}
+	}
+}
+
+// expireAcks lifts the acknowledgements of the alerts that aren't firing. It is called
+// before the alerts are evaluated so that an alert resolves acknowledged.
+func (o *AlertManager) expireAcks() {
+	for _, alert := range o.alerts {
+		if s := alert.Result(); !s.IsOn {
+			delete(o.acks, s.Name)
+		}
+	}
+}
+
+// control sets the silence and the acknowledgement of s
+func (o *AlertManager) control(s *AlertState) {
+	if until, ok := o.silences[s.Name]; ok {
+		s.Silenced = true
+		s.SilencedUntil = until
+	}
+	s.Acknowledged = o.acks[s.Name]
+}
@@ -0,0 +1,110 @@
+package task
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Juli3nnicolas/http_log_monitor/pkg/timer"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAlertControlReadsCommands(t *testing.T) {
+	c, err := ParseAlertControl("silence traffic 2h")
+	assert.Nil(t, err)
+	assert.Equal(t, AlertControl{Action: Silence, Name: "traffic", Duration: 2 * time.Hour}, c)
+	assert.Equal(t, "silence traffic 2h0m0s", c.String())
+
+	c, err = ParseAlertControl("  unsilence   traffic ")
+	assert.Nil(t, err)
+	assert.Equal(t, AlertControl{Action: Unsilence, Name: "traffic"}, c)
+
+	c, err = ParseAlertControl("acknowledge slow")
+	assert.Nil(t, err)
+	assert.Equal(t, AlertControl{Action: Acknowledge, Name: "slow"}, c)
+	assert.Equal(t, "ack slow", c.String())
+
+	for _, cmd := range []string{
+		"",
+		"silence",
+		"silence traffic",
+		"silence traffic 2",
+		"silence traffic -2h",
+		"unsilence traffic now",
+		"ack",
+		"mute traffic",
+	} {
+		_, err = ParseAlertControl(cmd)
+		assert.NotNil(t, err, cmd)
+	}
+}
+
+func TestAlertManagerSilencesAndAcknowledgesAlerts(t *testing.T) {
+	// Setup stage
+	const frameDuration time.Duration = time.Minute
+
+	manager := AlertManager{}
+	err := manager.Init([]AlertRule{
+		{Name: "traffic", Metric: RequestRateMetric(), Duration: frameDuration, Threshold: 10},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	if err := manager.BeforeRun(); err != nil {
+		panic(err)
+	}
+	now := time.Now()
+	t1 := &timer.TimeStub{NowStub: func() time.Time { return now }}
+
+	rate := func(r uint64) Metrics {
+		return Metrics{Rates: Rates{Frame: FrameRates{Duration: 60, ReqPerS: r, Rate: float64(r)}}}
+	}
+	frame := func(r uint64) AlertState {
+		now = now.Add(frameDuration)
+		if err := manager.Run(rate(r), t1); err != nil {
+			panic(err)
+		}
+		return manager.Result()[0]
+	}
+
+	// Exercise & validation stages
+
+	// Unknown alerts can't be controlled, resolved ones can't be acknowledged
+	assert.NotNil(t, manager.Apply(AlertControl{Action: Silence, Name: "slow", Duration: time.Hour}, now))
+	assert.NotNil(t, manager.Apply(AlertControl{Action: Silence, Name: "traffic"}, now))
+	assert.NotNil(t, manager.Apply(AlertControl{Action: Acknowledge, Name: "traffic"}, now))
+
+	// The silence lasts 2 frames
+	assert.Nil(t, manager.Apply(AlertControl{Action: Silence, Name: "traffic", Duration: 2 * frameDuration}, now))
+	s := manager.Result()[0]
+	assert.True(t, s.Silenced)
+	assert.Equal(t, now.Add(2*frameDuration), s.SilencedUntil)
+
+	s = frame(20)
+	assert.True(t, s.IsOn)
+	assert.True(t, s.Silenced)
+	assert.False(t, s.Acknowledged)
+
+	s = frame(20)
+	assert.False(t, s.Silenced)
+
+	// The acknowledgement lasts until the alert resolves, the resolved state is
+	// still acknowledged so that the resolution is notified as such
+	assert.Nil(t, manager.Apply(AlertControl{Action: Acknowledge, Name: "traffic"}, now))
+	assert.True(t, manager.Result()[0].Acknowledged)
+	assert.True(t, frame(20).Acknowledged)
+
+	s = frame(1)
+	assert.False(t, s.IsOn)
+	assert.True(t, s.Acknowledged)
+
+	s = frame(1)
+	assert.False(t, s.Acknowledged)
+	assert.NotNil(t, manager.Apply(AlertControl{Action: Acknowledge, Name: "traffic"}, now))
+
+	// Silences can be lifted before they expire
+	assert.Nil(t, manager.Apply(AlertControl{Action: Silence, Name: "traffic", Duration: time.Hour}, now))
+	assert.Nil(t, manager.Apply(AlertControl{Action: Unsilence, Name: "traffic"}, now))
+	assert.False(t, frame(1).Silenced)
+}
@@ -0,0 +1,215 @@
+package task
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Juli3nnicolas/http_log_monitor/pkg/timer"
+)
+
+// AlertRule describes an alert: Name's alert is triggered if Metric's average over
+// Duration compares to Threshold (see Alert and Comparison)
+type AlertRule struct {
+	Name       string
+	Metric     Metric
+	Duration   time.Duration
+	Threshold  float64
+	Comparison Comparison
+	Hysteresis Hysteresis
+}
+
+// ParseAlertRule reads a rule written as comma-separated key=value pairs, for instance
+// name=slow,metric=latency_p99,threshold=250,duration=5m. The name and the threshold
+// are required, the metric is the request-rate, the duration is defaultDuration and
+// the comparison (op=>, op=< or op=change) is > if they aren't given.
+// The hysteresis is set by resolve (threshold), pending (number of periods) and
+// min_firing (duration), the alert fires and resolves on the threshold by default.
+func ParseAlertRule(spec string, defaultDuration time.Duration) (AlertRule, error) {
+	rule := AlertRule{Metric: RequestRateMetric(), Duration: defaultDuration}
+	hasThreshold := false
+
+	for _, pair := range strings.Split(spec, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return AlertRule{}, fmt.Errorf("invalid alert rule %q - %q isn't a key=value pair", spec, pair)
+		}
+		key, value := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+
+		var err error
+		switch key {
+		case "name":
+			rule.Name = value
+		case "metric":
+			rule.Metric, err = ParseMetric(value)
+		case "threshold":
+			rule.Threshold, err = strconv.ParseFloat(value, 64)
+			hasThreshold = true
+		case "duration":
+			rule.Duration, err = time.ParseDuration(value)
+		case "op":
+			rule.Comparison, err = ParseComparison(value)
+		case "resolve":
+			rule.Hysteresis.ResolveThreshold, err = strconv.ParseFloat(value, 64)
+		case "pending":
+			rule.Hysteresis.PendingWindows, err = strconv.ParseUint(value, 10, 64)
+		case "min_firing":
+			rule.Hysteresis.MinFiring, err = time.ParseDuration(value)
+		default:
+			err = fmt.Errorf("unknown key %q", key)
+		}
+		if err != nil {
+			return AlertRule{}, fmt.Errorf("invalid alert rule %q - %v", spec, err)
+		}
+	}
+
+	if rule.Name == "" || !hasThreshold {
+		return AlertRule{}, fmt.Errorf("invalid alert rule %q - a name and a threshold are required", spec)
+	}
+	if rule.Duration <= 0 {
+		return AlertRule{}, fmt.Errorf("invalid alert rule %q - the duration must be positive", spec)
+	}
+
+	return rule, nil
+}
+
+// Alerter is an alert evaluated by an AlertManager. Its Run method takes the
+// same parameters as Alert.Run.
+type Alerter interface {
+	Task
+	Result() AlertState
+}
+
+// AlertManager is a task evaluating several alerts, one per rule, every frame.
+// Alerts can be silenced and acknowledged by name (see Apply).
+type AlertManager struct {
+	alerts   []Alerter
+	silences map[string]time.Time // silences' expiry by alert name
+	acks     map[string]bool      // acknowledged alerts by name
+	done     bool
+}
+
+// Init sets up an alert per rule, it needs :
+// - rules []AlertRule : the alerts' rules
+// - alerts []Alerter (optional) : other alerts, they are initialised without parameters
+// The alerts' names must be unique.
+func (o *AlertManager) Init(args ...interface{}) error {
+	if len(args) != 1 && len(args) != 2 {
+		return fmt.Errorf("wrong parameters - in order parameters must be (rules []AlertRule[, alerts []Alerter])")
+	}
+
+	rules, ok := args[0].([]AlertRule)
+	if !ok {
+		return fmt.Errorf("type error - got %T instead of []AlertRule", args[0])
+	}
+
+	var others []Alerter
+	if len(args) == 2 {
+		others, ok = args[1].([]Alerter)
+		if !ok {
+			return fmt.Errorf("type error - got %T instead of []Alerter", args[1])
+		}
+	}
+
+	o.silences = make(map[string]time.Time)
+	o.acks = make(map[string]bool)
+	o.alerts = make([]Alerter, 0, len(rules)+len(others))
+	for _, rule := range rules {
+		alert := &Alert{}
+		if err := alert.Init(rule.Duration, rule.Threshold, rule.Metric, rule.Comparison, rule.Hysteresis); err != nil {
+			return err
+		}
+		alert.state.Name = rule.Name
+		o.alerts = append(o.alerts, alert)
+	}
+
+	for _, alert := range others {
+		if err := alert.Init(); err != nil {
+			return err
+		}
+		o.alerts = append(o.alerts, alert)
+	}
+
+	names := make(map[string]bool, len(o.alerts))
+	for _, alert := range o.alerts {
+		name := alert.Result().Name
+		if names[name] {
+			return fmt.Errorf("wrong parameters - several alert rules are named %q", name)
+		}
+		names[name] = true
+	}
+
+	return nil
+}
+
+// BeforeRun inits the monitoring timer of every alert
+func (o *AlertManager) BeforeRun(args ...interface{}) error {
+	o.done = false
+	for _, alert := range o.alerts {
+		if err := alert.BeforeRun(args...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Run evaluates every alert (see Alert.Run), it takes the same parameters:
+// - metrics task.Metrics : frame measures used to trigger the alerts
+// - timer : a Timer interface, only uses Timer.Now()
+// The expired silences are lifted, and so are the acknowledgements of the alerts
+// resolved during the previous run (the resolution is notified as acknowledged).
+func (o *AlertManager) Run(args ...interface{}) error {
+	if len(args) != 2 {
+		return fmt.Errorf("wrong parameters - this function expects a task.Metrics parameter and a Timer struct")
+	}
+
+	t, ok := args[1].(timer.Timer)
+	if !ok {
+		return fmt.Errorf("type error - got %T instead of timer.Timer", args[1])
+	}
+
+	o.expireAcks()
+	for _, alert := range o.alerts {
+		if err := alert.Run(args...); err != nil {
+			return err
+		}
+	}
+	o.expireSilences(t.Now())
+	o.done = true
+
+	return nil
+}
+
+// AfterRun does nothing, implements the Task interface
+func (o *AlertManager) AfterRun() error {
+	return nil
+}
+
+// Result returns the state of every alert, in the rules' order
+func (o *AlertManager) Result() []AlertState {
+	states := make([]AlertState, 0, len(o.alerts))
+	for _, alert := range o.alerts {
+		s := alert.Result()
+		o.control(&s)
+		states = append(states, s)
+	}
+	return states
+}
+
+// IsDone is true once all the alerts have been evaluated for the current frame
+func (o *AlertManager) IsDone() bool {
+	return o.done
+}
+
+// Close closes every alert and wipes the object's content. Call Init to use it again.
+func (o *AlertManager) Close() error {
+	for _, alert := range o.alerts {
+		if err := alert.Close(); err != nil {
+			return err
+		}
+	}
+	*o = AlertManager{}
+	return nil
+}
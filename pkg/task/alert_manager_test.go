@@ -0,0 +1,101 @@
+package task
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Juli3nnicolas/http_log_monitor/pkg/timer"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAlertRuleReadsKeyValuePairs(t *testing.T) {
+	rule, err := ParseAlertRule("name=slow, metric=latency_p99,threshold=250,duration=5m", time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, "slow", rule.Name)
+	assert.Equal(t, LatencyP99MetricName, rule.Metric.Name)
+	assert.Equal(t, 250.0, rule.Threshold)
+	assert.Equal(t, 5*time.Minute, rule.Duration)
+
+	rule, err = ParseAlertRule("name=traffic,threshold=10", time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, RequestRateMetricName, rule.Metric.Name)
+	assert.Equal(t, time.Minute, rule.Duration)
+	assert.Equal(t, Above, rule.Comparison)
+
+	rule, err = ParseAlertRule("name=outage,op=<,threshold=1", time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, Below, rule.Comparison)
+
+	rule, err = ParseAlertRule("name=traffic,threshold=10,resolve=8,pending=3,min_firing=10m", time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, Hysteresis{ResolveThreshold: 8, PendingWindows: 3, MinFiring: 10 * time.Minute}, rule.Hysteresis)
+
+	for _, spec := range []string{
+		"threshold=10",
+		"name=traffic",
+		"name=traffic,threshold=ten",
+		"name=traffic,threshold=10,metric=unknown",
+		"name=traffic,threshold=10,duration=-1m",
+		"name=traffic,threshold=10,color=red",
+		"name=traffic;threshold=10",
+		"name=traffic,threshold=10,op=>=",
+		"name=traffic,threshold=10,pending=-1",
+		"name=traffic,threshold=10,min_firing=10",
+	} {
+		_, err = ParseAlertRule(spec, time.Minute)
+		assert.NotNil(t, err, spec)
+	}
+}
+
+func TestAlertManagerEvaluatesEveryRule(t *testing.T) {
+	// Setup stage
+	const frameDuration time.Duration = time.Second
+
+	manager := AlertManager{}
+	err := manager.Init([]AlertRule{
+		{Name: "traffic", Metric: RequestRateMetric(), Duration: frameDuration, Threshold: 10},
+		{Name: "slow", Metric: metrics[LatencyP99MetricName], Duration: frameDuration, Threshold: 100},
+	})
+	if err != nil {
+		panic(err)
+	}
+	if err := manager.BeforeRun(); err != nil {
+		panic(err)
+	}
+
+	now := time.Now()
+	t1 := &timer.TimeStub{
+		NowStub: func() time.Time {
+			now = now.Add(frameDuration)
+			return now
+		},
+	}
+	slow := Metrics{
+		Rates:   Rates{Frame: FrameRates{Duration: 1, ReqPerS: 2, Rate: 2}},
+		Latency: Latency{Frame: LatencyStats{P99: 250 * time.Millisecond}},
+	}
+
+	// Exercise stage
+	err = manager.Run(slow, t1)
+
+	// Validation stage
+	assert.Nil(t, err)
+	assert.True(t, manager.IsDone())
+	res := manager.Result()
+	assert.Len(t, res, 2)
+	assert.Equal(t, "traffic", res[0].Name)
+	assert.False(t, res[0].IsOn)
+	assert.Equal(t, "slow", res[1].Name)
+	assert.True(t, res[1].IsOn)
+	assert.Equal(t, 250.0, res[1].Value)
+}
+
+func TestAlertManagerInitRejectsDuplicateNames(t *testing.T) {
+	manager := AlertManager{}
+	rule := AlertRule{Name: "traffic", Metric: RequestRateMetric(), Duration: time.Minute, Threshold: 10}
+
+	assert.NotNil(t, manager.Init([]AlertRule{rule, rule}))
+	assert.NotNil(t, manager.Init(rule))
+	assert.Nil(t, manager.Init([]AlertRule{}))
+}
@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/Juli3nnicolas/http_log_monitor/pkg/log"
 	"github.com/Juli3nnicolas/http_log_monitor/pkg/timer"
 
 	"github.com/stretchr/testify/assert"
@@ -27,11 +28,13 @@ func TestRunTurnTheAlertOnAndOffCorrectly(t *testing.T) {
 		Frame: FrameRates{
 			Duration: uint64(frameDuration.Seconds()),
 			ReqPerS:  5,
+			Rate:     5,
 		},
 	}
 	alertOff := Rates{
 		Frame: FrameRates{
 			ReqPerS: 2,
+			Rate:    2,
 		},
 	}
 
@@ -93,11 +96,13 @@ func TestRunTurnTheAlertOnAndOffCorrectlyWithMeasuresSpanningOnTwoTimeFrames(t *
 		Frame: FrameRates{
 			Duration: uint64(frameDuration.Seconds()),
 			ReqPerS:  15,
+			Rate:     15,
 		},
 	}
 	alertOff := Rates{
 		Frame: FrameRates{
 			ReqPerS: 2,
+			Rate:    2,
 		},
 	}
 
@@ -177,6 +182,7 @@ func TestRunReturnsFalseForIsOnIfThresholdIsNotReachedButTimeLimitIs(t *testing.
 	alertOff := Rates{
 		Frame: FrameRates{
 			ReqPerS: 2,
+			Rate:    2,
 		},
 	}
 
@@ -223,6 +229,7 @@ func TestRunReturnsFalseForIsOnIfThresholdIsReachedButTimeLimitIsNot(t *testing.
 		Frame: FrameRates{
 			Duration: uint64(frameDuration.Seconds()),
 			ReqPerS:  15,
+			Rate:     15,
 		},
 	}
 
@@ -250,3 +257,400 @@ func TestRunReturnsFalseForIsOnIfThresholdIsReachedButTimeLimitIsNot(t *testing.
 	assert.Equal(t, uint64(0), res.NbReqs)
 	assert.Equal(t, time.Time{}, res.Date)
 }
+
+func TestRunMonitorsTheLatencyWhenGivenALatencyMetric(t *testing.T) {
+	// Setup stage
+	const frameDuration time.Duration = time.Second
+
+	metric, err := ParseMetric(LatencyP99MetricName)
+	if err != nil {
+		panic(err)
+	}
+
+	alert := Alert{}
+	if err := alert.Init(frameDuration, float64(100), metric); err != nil {
+		panic(err)
+	}
+
+	if err := alert.BeforeRun(); err != nil {
+		panic(err)
+	}
+
+	slow := Metrics{Latency: Latency{Frame: LatencyStats{P99: 250 * time.Millisecond}}}
+	fast := Metrics{Latency: Latency{Frame: LatencyStats{P99: 20 * time.Millisecond}}}
+
+	now := time.Now()
+	t1 := &timer.TimeStub{
+		NowStub: func() time.Time {
+			now = now.Add(frameDuration)
+			return now
+		},
+	}
+
+	// Exercise & validation stages
+
+	// The p99 latency is 250ms > 100ms so the alert is switched on
+	err = alert.Run(slow, t1)
+	assert.Nil(t, err)
+	res := alert.Result()
+	assert.True(t, res.IsOn)
+	assert.Equal(t, LatencyP99MetricName, res.Metric)
+	assert.Equal(t, "ms", res.Unit)
+	assert.Equal(t, float64(250), res.Value)
+	assert.Equal(t, now, res.Date)
+
+	// The p99 latency dived to 20ms so the alert is switched off
+	err = alert.Run(fast, t1)
+	assert.Nil(t, err)
+	res = alert.Result()
+	assert.False(t, res.IsOn)
+	assert.Equal(t, float64(0), res.Value)
+}
+
+func TestRunMonitorsTheParseErrorRate(t *testing.T) {
+	// Setup stage
+	const frameDuration time.Duration = time.Second
+
+	alert := Alert{}
+	if err := alert.Init(frameDuration, float64(50), ParseErrorRateMetric()); err != nil {
+		panic(err)
+	}
+
+	if err := alert.BeforeRun(); err != nil {
+		panic(err)
+	}
+
+	mismatch := Metrics{ParseErrors: ParseErrors{Frame: 90, NbLines: 100, Total: 90}}
+	match := Metrics{ParseErrors: ParseErrors{Frame: 1, NbLines: 100, Total: 91}}
+	idle := Metrics{ParseErrors: ParseErrors{Total: 91}}
+
+	now := time.Now()
+	t1 := &timer.TimeStub{
+		NowStub: func() time.Time {
+			now = now.Add(frameDuration)
+			return now
+		},
+	}
+
+	// Exercise & validation stages
+
+	// 90% of the lines can't be parsed so the alert is switched on
+	err := alert.Run(mismatch, t1)
+	assert.Nil(t, err)
+	res := alert.Result()
+	assert.True(t, res.IsOn)
+	assert.Equal(t, ParseErrorRateMetricName, res.Metric)
+	assert.Equal(t, "%", res.Unit)
+	assert.Equal(t, float64(90), res.Value)
+
+	// 1% of the lines can't be parsed so the alert is switched off
+	err = alert.Run(match, t1)
+	assert.Nil(t, err)
+	assert.False(t, alert.Result().IsOn)
+
+	// Frames without lines don't trigger the alert
+	err = alert.Run(idle, t1)
+	assert.Nil(t, err)
+	assert.False(t, alert.Result().IsOn)
+}
+
+func TestRunTriggersBelowAlertsWhenTheTrafficDrops(t *testing.T) {
+	// Setup stage
+	const frameDuration time.Duration = time.Second
+
+	alert := Alert{}
+	if err := alert.Init(frameDuration, float64(1), RequestRateMetric(), Below); err != nil {
+		panic(err)
+	}
+
+	if err := alert.BeforeRun(); err != nil {
+		panic(err)
+	}
+
+	outage := Rates{Frame: FrameRates{Duration: 1, Rate: 0.2}}
+	normal := Rates{Frame: FrameRates{Duration: 1, ReqPerS: 5, Rate: 5}}
+
+	now := time.Now()
+	t1 := &timer.TimeStub{
+		NowStub: func() time.Time {
+			now = now.Add(frameDuration)
+			return now
+		},
+	}
+
+	// Exercise & validation stages
+
+	// 0.2 req/s < 1 req/s so the alert is switched on
+	err := alert.Run(outage, t1)
+	assert.Nil(t, err)
+	res := alert.Result()
+	assert.True(t, res.IsOn)
+	assert.Equal(t, Below, res.Comparison)
+	assert.Equal(t, 0.2, res.Value)
+
+	// 5 req/s >= 1 req/s so the alert is switched off
+	err = alert.Run(normal, t1)
+	assert.Nil(t, err)
+	assert.False(t, alert.Result().IsOn)
+}
+
+func TestRunTriggersChangeByAlertsComparedToThePreviousPeriod(t *testing.T) {
+	// Setup stage
+	const frameDuration time.Duration = time.Second
+
+	alert := Alert{}
+	if err := alert.Init(frameDuration, float64(50), RequestRateMetric(), ChangeBy); err != nil {
+		panic(err)
+	}
+
+	if err := alert.BeforeRun(); err != nil {
+		panic(err)
+	}
+
+	rates := func(rate float64) Rates {
+		return Rates{Frame: FrameRates{Duration: 1, Rate: rate}}
+	}
+
+	now := time.Now()
+	t1 := &timer.TimeStub{
+		NowStub: func() time.Time {
+			now = now.Add(frameDuration)
+			return now
+		},
+	}
+
+	// Exercise & validation stages
+
+	// The first period has nothing to be compared to
+	assert.Nil(t, alert.Run(rates(10), t1))
+	assert.False(t, alert.Result().IsOn)
+
+	// 10 req/s to 12 req/s is a 20% change
+	assert.Nil(t, alert.Run(rates(12), t1))
+	assert.False(t, alert.Result().IsOn)
+
+	// 12 req/s to 3 req/s is a 75% drop so the alert is switched on
+	assert.Nil(t, alert.Run(rates(3), t1))
+	res := alert.Result()
+	assert.True(t, res.IsOn)
+	assert.Equal(t, float64(75), res.Value)
+
+	// The traffic is stable again so the alert is switched off
+	assert.Nil(t, alert.Run(rates(3), t1))
+	assert.False(t, alert.Result().IsOn)
+}
+
+func TestRunResolvesWhenTheLogsStopInEventTime(t *testing.T) {
+	// Setup stage - 5 req/s measured in event time, the alert fires above 2 req/s
+	const frame uint64 = 10
+
+	alert := Alert{}
+	if err := alert.Init(20*time.Second, float64(2), RequestRateMetric(), Above); err != nil {
+		panic(err)
+	}
+	if err := alert.BeforeRun(); err != nil {
+		panic(err)
+	}
+
+	rates := MeasureRates{}
+	if err := rates.Init(true); err != nil {
+		panic(err)
+	}
+
+	now := time.Now()
+	clock := &timer.TimeStub{NowStub: func() time.Time { return now }}
+	update := func(logs []log.Info) {
+		now = now.Add(time.Duration(frame) * time.Second)
+		assert.Nil(t, rates.Run(logs, frame, clock))
+		assert.Nil(t, alert.Run(rates.Result(), clock))
+	}
+
+	for i := 0; i < 4; i++ {
+		var logs []log.Info
+		for j := 0; j < 50; j++ {
+			logs = append(logs, log.Info{LocalTime: now.Add(time.Second), Request: log.HTTP{Code: 200}})
+		}
+		update(logs)
+	}
+	assert.True(t, alert.Result().IsOn)
+
+	// Exercise stage - the logs stop
+	for i := 0; i < 4; i++ {
+		update([]log.Info{})
+	}
+
+	// Validation stage
+	assert.False(t, alert.Result().IsOn)
+}
+
+func TestRunResolvesPastTheResolveThreshold(t *testing.T) {
+	// Setup stage - fires at 10 req/s, resolves below 5 req/s
+	const frameDuration time.Duration = time.Second
+
+	alert := Alert{}
+	hysteresis := Hysteresis{ResolveThreshold: 5}
+	if err := alert.Init(frameDuration, float64(10), RequestRateMetric(), Above, hysteresis); err != nil {
+		panic(err)
+	}
+
+	if err := alert.BeforeRun(); err != nil {
+		panic(err)
+	}
+
+	rates := func(rate float64) Rates {
+		return Rates{Frame: FrameRates{Duration: 1, Rate: rate}}
+	}
+
+	now := time.Now()
+	t1 := &timer.TimeStub{
+		NowStub: func() time.Time {
+			now = now.Add(frameDuration)
+			return now
+		},
+	}
+
+	// Exercise & validation stages
+
+	// 11 req/s >= 10 req/s so the alert fires
+	assert.Nil(t, alert.Run(rates(11), t1))
+	res := alert.Result()
+	assert.True(t, res.IsOn)
+	assert.Equal(t, Firing, res.Status)
+	assert.Equal(t, float64(5), res.ResolveThreshold)
+
+	// The traffic hovers below the threshold but above the resolve threshold
+	assert.Nil(t, alert.Run(rates(7), t1))
+	assert.True(t, alert.Result().IsOn)
+	assert.Nil(t, alert.Run(rates(9), t1))
+	assert.True(t, alert.Result().IsOn)
+
+	// 4 req/s < 5 req/s so the alert resolves
+	assert.Nil(t, alert.Run(rates(4), t1))
+	res = alert.Result()
+	assert.False(t, res.IsOn)
+	assert.Equal(t, Inactive, res.Status)
+	assert.Equal(t, now, res.Date)
+
+	// 7 req/s doesn't exceed the threshold so the alert stays inactive
+	assert.Nil(t, alert.Run(rates(7), t1))
+	assert.False(t, alert.Result().IsOn)
+}
+
+func TestRunIsPendingUntilTheThresholdIsExceededOnEnoughPeriods(t *testing.T) {
+	// Setup stage - fires once the threshold is exceeded on 3 periods in a row
+	const frameDuration time.Duration = time.Second
+
+	alert := Alert{}
+	hysteresis := Hysteresis{PendingWindows: 3}
+	if err := alert.Init(frameDuration, float64(10), RequestRateMetric(), Above, hysteresis); err != nil {
+		panic(err)
+	}
+
+	if err := alert.BeforeRun(); err != nil {
+		panic(err)
+	}
+
+	rates := func(rate float64) Rates {
+		return Rates{Frame: FrameRates{Duration: 1, Rate: rate}}
+	}
+
+	now := time.Now()
+	t1 := &timer.TimeStub{
+		NowStub: func() time.Time {
+			now = now.Add(frameDuration)
+			return now
+		},
+	}
+
+	// Exercise & validation stages
+
+	// The threshold is exceeded on 2 periods, the alert is pending
+	assert.Nil(t, alert.Run(rates(12), t1))
+	assert.Nil(t, alert.Run(rates(12), t1))
+	res := alert.Result()
+	assert.False(t, res.IsOn)
+	assert.Equal(t, Pending, res.Status)
+	assert.Equal(t, uint64(2), res.NbPending)
+	assert.Equal(t, uint64(3), res.PendingWindows)
+
+	// The traffic drops so the count starts over
+	assert.Nil(t, alert.Run(rates(2), t1))
+	res = alert.Result()
+	assert.Equal(t, Inactive, res.Status)
+	assert.Equal(t, uint64(0), res.NbPending)
+
+	// The threshold is exceeded on 3 periods in a row so the alert fires
+	assert.Nil(t, alert.Run(rates(12), t1))
+	assert.Nil(t, alert.Run(rates(12), t1))
+	assert.False(t, alert.Result().IsOn)
+	assert.Nil(t, alert.Run(rates(12), t1))
+	res = alert.Result()
+	assert.True(t, res.IsOn)
+	assert.Equal(t, Firing, res.Status)
+	assert.Equal(t, now, res.Date)
+}
+
+func TestRunFiresForTheMinimumFiringDuration(t *testing.T) {
+	// Setup stage - fires for 3 seconds at least
+	const frameDuration time.Duration = time.Second
+
+	alert := Alert{}
+	hysteresis := Hysteresis{MinFiring: 3 * time.Second}
+	if err := alert.Init(frameDuration, float64(1), RequestRateMetric(), Below, hysteresis); err != nil {
+		panic(err)
+	}
+
+	if err := alert.BeforeRun(); err != nil {
+		panic(err)
+	}
+
+	rates := func(rate float64) Rates {
+		return Rates{Frame: FrameRates{Duration: 1, Rate: rate}}
+	}
+
+	now := time.Now()
+	t1 := &timer.TimeStub{
+		NowStub: func() time.Time {
+			now = now.Add(frameDuration)
+			return now
+		},
+	}
+
+	// Exercise & validation stages
+
+	// The traffic drops so the alert fires
+	assert.Nil(t, alert.Run(rates(0), t1))
+	tFired := now
+	assert.True(t, alert.Result().IsOn)
+
+	// The traffic recovers but the alert has only fired for 1 and 2 seconds
+	assert.Nil(t, alert.Run(rates(5), t1))
+	assert.True(t, alert.Result().IsOn)
+	assert.Nil(t, alert.Run(rates(5), t1))
+	res := alert.Result()
+	assert.True(t, res.IsOn)
+	assert.Equal(t, tFired, res.Date)
+
+	// It has fired for 3 seconds so it resolves
+	assert.Nil(t, alert.Run(rates(5), t1))
+	res = alert.Result()
+	assert.False(t, res.IsOn)
+	assert.Equal(t, now, res.Date)
+}
+
+func TestInitReturnsAnErrorOnInvalidParameters(t *testing.T) {
+	alert := Alert{}
+
+	assert.NotNil(t, alert.Init(time.Second))
+	assert.NotNil(t, alert.Init(time.Second, 10))
+	assert.NotNil(t, alert.Init(time.Second, uint64(10), "req_rate"))
+	assert.NotNil(t, alert.Init(time.Second, uint64(10), Metric{Name: "nil"}))
+	assert.NotNil(t, alert.Init(time.Second, uint64(10), RequestRateMetric(), "<"))
+	assert.NotNil(t, alert.Init(time.Second, uint64(10), RequestRateMetric(), Above, 5.0))
+	assert.NotNil(t, alert.Init(time.Second, uint64(10), RequestRateMetric(), Above, Hysteresis{ResolveThreshold: 12}))
+	assert.NotNil(t, alert.Init(time.Second, uint64(10), RequestRateMetric(), Below, Hysteresis{ResolveThreshold: 8}))
+	assert.NotNil(t, alert.Init(time.Second, uint64(10), RequestRateMetric(), Above, Hysteresis{MinFiring: -time.Second}))
+
+	_, err := ParseMetric("foo")
+	assert.NotNil(t, err)
+}
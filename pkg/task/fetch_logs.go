@@ -6,10 +6,33 @@ import (
 )
 
 // FetchLogs asynchronously reads a log-file and gets its content.
+// Lines that can't be parsed are counted (see ParseErrors).
 type FetchLogs struct {
-	logs []log.Info
-	dbuf reader.ASyncDBuf
-	done bool
+	logs        []log.Info
+	parseErrors ParseErrors
+	dbuf        reader.ASyncDBuf
+	done        bool
+}
+
+// ParseErrors counts the lines of the log-file that couldn't be parsed
+type ParseErrors struct {
+	// Frame is the number of bad lines read during the time-frame
+	Frame uint64
+	// NbLines is the number of lines (parsed or not) read during the time-frame
+	NbLines uint64
+	// Total is the number of bad lines read since the task started
+	Total uint64
+	// Samples are the last bad lines, the most recent last. They can come
+	// from previous time-frames.
+	Samples []reader.ParseError
+}
+
+// Rate returns the percentage of the time-frame's lines that couldn't be parsed
+func (o *ParseErrors) Rate() float64 {
+	if o.NbLines == 0 {
+		return 0
+	}
+	return float64(o.Frame) / float64(o.NbLines) * 100
 }
 
 // Init sets up the async loader for reading the log file
@@ -43,6 +66,16 @@ func (o *FetchLogs) Run(...interface{}) error {
 		return err
 	}
 
+	frameErrors := o.dbuf.ParseErrors()
+	all := reader.ParseErrors{Count: o.parseErrors.Total, Samples: o.parseErrors.Samples}
+	all.Merge(&frameErrors)
+	o.parseErrors = ParseErrors{
+		Frame:   frameErrors.Count,
+		NbLines: frameErrors.Count + uint64(len(o.logs)),
+		Total:   all.Count,
+		Samples: all.Samples,
+	}
+
 	o.done = true
 
 	return err
@@ -55,6 +88,11 @@ func (o *FetchLogs) Fetch() []log.Info {
 	return o.logs
 }
 
+// ParseErrors returns the count of lines that couldn't be parsed
+func (o *FetchLogs) ParseErrors() ParseErrors {
+	return o.parseErrors
+}
+
 // AfterRun ceases reading and prepares a new buffer for reading data during
 // the next time-frame
 func (o *FetchLogs) AfterRun() error {
@@ -69,6 +107,7 @@ func (o *FetchLogs) IsDone() bool {
 // Close closes the task. Call Init to use it again.
 func (o *FetchLogs) Close() error {
 	o.done = false
+	o.parseErrors = ParseErrors{}
 	o.dbuf.Close()
 
 	return nil
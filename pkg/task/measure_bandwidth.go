@@ -0,0 +1,185 @@
+package task
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/Juli3nnicolas/http_log_monitor/pkg/log"
+)
+
+// SizeBuckets are the upper bounds (exclusive, in bytes) of the response-size
+// histogram's buckets. The histogram has an extra bucket for larger responses.
+var SizeBuckets = []uint64{1 << 10, 10 << 10, 100 << 10, 1 << 20, 10 << 20}
+
+// MeasureBandwidth is a task measuring the throughput of the served content.
+// Only the logs providing a response size (log.SizeField) are taken into account.
+type MeasureBandwidth struct {
+	depth     int
+	done      bool
+	bandwidth Bandwidth
+}
+
+// Bandwidth contains all the throughput measures taken by the task
+type Bandwidth struct {
+	Global   GlobalBandwidth
+	Frame    FrameBandwidth
+	Sections []SectionBandwidth // bytes served per section, the largest first
+	Sizes    []uint64           // response-size histogram, Sizes[i] counts the responses smaller than SizeBuckets[i]
+}
+
+// GlobalBandwidth global measures taking into account the whole log file
+type GlobalBandwidth struct {
+	AvgBytesPerS uint64 // Average throughput since the app is on
+	nbMeasures   uint64 // Total number of measures, used to compute AvgBytesPerS
+	MaxBytesPerS uint64 // Maximum throughput since app startup
+}
+
+// FrameBandwidth measures related to the current time-frame
+type FrameBandwidth struct {
+	Duration  uint64 // Frame's duration expressed in seconds
+	BytesPerS uint64 // Frame's throughput (B/s)
+	NbBytes   uint64 // Number of bytes served during the frame
+	NbSamples uint64 // Number of requests having a known size
+}
+
+// SectionBandwidth is the number of bytes served for a website section (see FindMostHitSections)
+type SectionBandwidth struct {
+	Section string
+	NbBytes uint64
+}
+
+// Init sets up the task, it accepts an optional parameter :
+// - depth int : number of directories making a section (DefaultSectionDepth if
+// not given, see FindMostHitSections)
+func (o *MeasureBandwidth) Init(args ...interface{}) error {
+	depth, err := initSectionDepth(args)
+	if err != nil {
+		return err
+	}
+	o.depth = depth
+
+	return nil
+}
+
+// BeforeRun flags the task as not done.
+func (o *MeasureBandwidth) BeforeRun(...interface{}) error {
+	o.done = false
+
+	return nil
+}
+
+// Run computes the measures present in Bandwidth. It takes two parameters:
+// logs []log.Info : slice of logs to base the computing on
+// frame uint64 : time-frame duration in seconds
+func (o *MeasureBandwidth) Run(args ...interface{}) error {
+	if len(args) != 2 {
+		return fmt.Errorf("wrong parameters - in order parameters must be (logs []log.Info, frameDurationInSeconds uint64)")
+	}
+
+	logs, ok := args[0].([]log.Info)
+	if !ok {
+		return fmt.Errorf("type error - got %T instead of []log.Info", args[0])
+	}
+
+	frame, ok := args[1].(uint64)
+	if !ok {
+		return fmt.Errorf("type error - got %T instead of uint64", args[1])
+	}
+	if frame == 0 {
+		return fmt.Errorf("wrong parameters - the frame duration must be greater than 0")
+	}
+
+	o.computeFrameBandwidth(logs, frame)
+	o.computeGlobalBandwidth()
+
+	o.done = true
+	return nil
+}
+
+func (o *MeasureBandwidth) computeFrameBandwidth(logs []log.Info, frame uint64) {
+	f := FrameBandwidth{Duration: frame}
+	sizes := make([]uint64, len(SizeBuckets)+1)
+	sections := make(map[string]uint64)
+	depth := sectionDepth(o.depth)
+
+	for i := range logs {
+		if !logs[i].Has(log.SizeField) {
+			continue
+		}
+
+		size := logs[i].Request.Size
+		f.NbBytes += size
+		f.NbSamples++
+		sizes[sizeBucket(size)]++
+
+		if !logs[i].Has(log.RequestField) {
+			continue
+		}
+		if section := extractSectionAt(logs[i].Request.Route, depth); section != "" {
+			sections[section] += size
+		}
+	}
+	f.BytesPerS = f.NbBytes / frame
+
+	o.bandwidth.Frame = f
+	o.bandwidth.Sizes = sizes
+	o.bandwidth.Sections = make([]SectionBandwidth, 0, len(sections))
+	for section, nbBytes := range sections {
+		o.bandwidth.Sections = append(o.bandwidth.Sections, SectionBandwidth{Section: section, NbBytes: nbBytes})
+	}
+
+	s := o.bandwidth.Sections
+	sort.Slice(s, func(i, j int) bool {
+		if s[i].NbBytes != s[j].NbBytes {
+			return s[i].NbBytes > s[j].NbBytes
+		}
+		return s[i].Section < s[j].Section
+	})
+}
+
+func (o *MeasureBandwidth) computeGlobalBandwidth() {
+	f := &o.bandwidth.Frame
+	g := &o.bandwidth.Global
+
+	if f.BytesPerS > g.MaxBytesPerS {
+		g.MaxBytesPerS = f.BytesPerS
+	}
+
+	if g.nbMeasures >= 1 {
+		g.AvgBytesPerS = (g.AvgBytesPerS*g.nbMeasures + f.BytesPerS) / (g.nbMeasures + 1)
+	} else {
+		g.AvgBytesPerS = f.BytesPerS
+	}
+	g.nbMeasures++
+}
+
+// sizeBucket returns the index of the histogram's bucket size falls in
+func sizeBucket(size uint64) int {
+	for i, bound := range SizeBuckets {
+		if size < bound {
+			return i
+		}
+	}
+	return len(SizeBuckets)
+}
+
+// AfterRun does nothing, implements the Task interface
+func (o *MeasureBandwidth) AfterRun() error {
+	return nil
+}
+
+// Result returns a copy of the measure-data
+func (o *MeasureBandwidth) Result() Bandwidth {
+	return o.bandwidth
+}
+
+// IsDone returns true if the task has complted its work. False otherwise.
+func (o *MeasureBandwidth) IsDone() bool {
+	return o.done
+}
+
+// Close wipes the object's content. Call Init to use it again.
+func (o *MeasureBandwidth) Close() error {
+	o.bandwidth = Bandwidth{}
+	return nil
+}
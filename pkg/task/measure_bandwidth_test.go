@@ -0,0 +1,81 @@
+package task
+
+import (
+	"testing"
+
+	"github.com/Juli3nnicolas/http_log_monitor/pkg/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMeasureBandwidthComputesThroughputSectionsAndSizes(t *testing.T) {
+	// Setup stage
+	logs := []log.Info{
+		{Request: log.HTTP{Route: "/static/app.js", Size: 2 << 20}},
+		{Request: log.HTTP{Route: "/api/users", Size: 512}},
+		{Request: log.HTTP{Route: "/api/orders", Size: 20 << 10}},
+		{Request: log.HTTP{Route: "/api/orders"}, Missing: log.SizeField},
+		{Request: log.HTTP{Size: 1 << 10}, Missing: log.RequestField},
+	}
+
+	task := MeasureBandwidth{}
+	if err := task.BeforeRun(); err != nil {
+		panic(err)
+	}
+
+	// Exercise stage
+	err := task.Run(logs, uint64(2))
+
+	// Validation stage
+	assert.Nil(t, err)
+	assert.True(t, task.IsDone())
+
+	res := task.Result()
+	total := uint64(2<<20 + 512 + 20<<10 + 1<<10)
+	assert.Equal(t, FrameBandwidth{Duration: 2, BytesPerS: total / 2, NbBytes: total, NbSamples: 4}, res.Frame)
+	assert.Equal(t, []SectionBandwidth{
+		{Section: "/static", NbBytes: 2 << 20},
+		{Section: "/api", NbBytes: 512 + 20<<10},
+	}, res.Sections)
+	assert.Equal(t, []uint64{1, 1, 1, 0, 1, 0}, res.Sizes)
+}
+
+func TestMeasureBandwidthGroupsSectionsAtTheConfiguredDepth(t *testing.T) {
+	// Setup stage
+	logs := []log.Info{
+		{Request: log.HTTP{Route: "/api/v1/users", Size: 100}},
+		{Request: log.HTTP{Route: "/api/v2/users", Size: 300}},
+		{Request: log.HTTP{Route: "/api/v1/orders", Size: 50}},
+	}
+
+	task := MeasureBandwidth{}
+	if err := task.Init(2); err != nil {
+		panic(err)
+	}
+	if err := task.BeforeRun(); err != nil {
+		panic(err)
+	}
+
+	// Exercise stage
+	err := task.Run(logs, uint64(1))
+
+	// Validation stage
+	assert.Nil(t, err)
+	assert.Equal(t, []SectionBandwidth{
+		{Section: "/api/v2", NbBytes: 300},
+		{Section: "/api/v1", NbBytes: 150},
+	}, task.Result().Sections)
+	assert.NotNil(t, task.Init(0))
+	assert.NotNil(t, task.Init("2"))
+}
+
+func TestMeasureBandwidthKeepsTheGlobalAverageAndMax(t *testing.T) {
+	task := MeasureBandwidth{}
+
+	assert.Nil(t, task.Run([]log.Info{{Request: log.HTTP{Size: 300}}}, uint64(1)))
+	assert.Nil(t, task.Run([]log.Info{{Request: log.HTTP{Size: 100}}}, uint64(1)))
+
+	g := task.Result().Global
+	assert.Equal(t, uint64(200), g.AvgBytesPerS)
+	assert.Equal(t, uint64(300), g.MaxBytesPerS)
+	assert.NotNil(t, task.Run([]log.Info{}, uint64(0)))
+}
@@ -0,0 +1,159 @@
+package task
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Juli3nnicolas/http_log_monitor/pkg/log"
+)
+
+// MeasureLatency is a task measuring the requests' latency percentiles. Only
+// the logs providing a request duration (log.DurationField) are taken into account.
+type MeasureLatency struct {
+	depth   int
+	done    bool
+	latency Latency
+}
+
+// Latency contains the latency measures of a time-frame
+type Latency struct {
+	Frame    LatencyStats     // latency of all the frame's requests
+	Sections []SectionLatency // latency per section, the slowest (on P99) first
+}
+
+// LatencyStats are latency percentiles computed on a set of requests
+type LatencyStats struct {
+	NbSamples uint64 // Number of requests having a known duration
+	P50       time.Duration
+	P90       time.Duration
+	P99       time.Duration
+	Max       time.Duration
+}
+
+// SectionLatency is the latency of a website section (see FindMostHitSections)
+type SectionLatency struct {
+	Section string
+	LatencyStats
+}
+
+// Init sets up the task, it accepts an optional parameter :
+// - depth int : number of directories making a section (DefaultSectionDepth if
+// not given, see FindMostHitSections)
+func (o *MeasureLatency) Init(args ...interface{}) error {
+	depth, err := initSectionDepth(args)
+	if err != nil {
+		return err
+	}
+	o.depth = depth
+
+	return nil
+}
+
+// BeforeRun flags the task as not done.
+func (o *MeasureLatency) BeforeRun(...interface{}) error {
+	o.done = false
+
+	return nil
+}
+
+// Run computes the latency percentiles of the frame and of every section
+// logs []log.Info : slice of logs to base the computing on
+func (o *MeasureLatency) Run(args ...interface{}) error {
+	if len(args) != 1 {
+		return fmt.Errorf("wrong parameters - the only required parameter is (logs []log.Info)")
+	}
+
+	logs, ok := args[0].([]log.Info)
+	if !ok {
+		return fmt.Errorf("type error - got %T instead of []log.Info", args[0])
+	}
+
+	all := make([]time.Duration, 0, len(logs))
+	sections := make(map[string][]time.Duration)
+	depth := sectionDepth(o.depth)
+
+	for i := range logs {
+		if !logs[i].Has(log.DurationField) {
+			continue
+		}
+
+		d := logs[i].Request.Duration
+		all = append(all, d)
+
+		if !logs[i].Has(log.RequestField) {
+			continue
+		}
+		if section := extractSectionAt(logs[i].Request.Route, depth); section != "" {
+			sections[section] = append(sections[section], d)
+		}
+	}
+
+	o.latency.Frame = computeLatencyStats(all)
+	o.latency.Sections = make([]SectionLatency, 0, len(sections))
+	for section, durations := range sections {
+		o.latency.Sections = append(o.latency.Sections, SectionLatency{
+			Section:      section,
+			LatencyStats: computeLatencyStats(durations),
+		})
+	}
+
+	sort.Slice(o.latency.Sections, func(i, j int) bool {
+		si, sj := &o.latency.Sections[i], &o.latency.Sections[j]
+		if si.P99 != sj.P99 {
+			return si.P99 > sj.P99
+		}
+		return si.Section < sj.Section
+	})
+
+	o.done = true
+	return nil
+}
+
+// computeLatencyStats works out the percentiles of durations. The slice is sorted in place.
+func computeLatencyStats(durations []time.Duration) LatencyStats {
+	n := len(durations)
+	if n == 0 {
+		return LatencyStats{}
+	}
+
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+
+	return LatencyStats{
+		NbSamples: uint64(n),
+		P50:       percentile(durations, 50),
+		P90:       percentile(durations, 90),
+		P99:       percentile(durations, 99),
+		Max:       durations[n-1],
+	}
+}
+
+// percentile returns the p-th percentile of sorted durations using the nearest-rank method
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// AfterRun does nothing, implements the Task interface
+func (o *MeasureLatency) AfterRun() error {
+	return nil
+}
+
+// Result returns a copy of the latency measures
+func (o *MeasureLatency) Result() Latency {
+	return o.latency
+}
+
+// IsDone returns true if the task has completed its work. False otherwise.
+func (o *MeasureLatency) IsDone() bool {
+	return o.done
+}
+
+// Close wipes the object's content. Call Init to use it again.
+func (o *MeasureLatency) Close() error {
+	o.latency = Latency{}
+	return nil
+}
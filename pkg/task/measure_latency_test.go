@@ -0,0 +1,100 @@
+package task
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Juli3nnicolas/http_log_monitor/pkg/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPercentileUsesTheNearestRank(t *testing.T) {
+	sorted := make([]time.Duration, 0, 10)
+	for i := 1; i <= 10; i++ {
+		sorted = append(sorted, time.Duration(i)*time.Millisecond)
+	}
+
+	assert.Equal(t, 5*time.Millisecond, percentile(sorted, 50))
+	assert.Equal(t, 9*time.Millisecond, percentile(sorted, 90))
+	assert.Equal(t, 10*time.Millisecond, percentile(sorted, 99))
+	assert.Equal(t, time.Millisecond, percentile(sorted, 0))
+	assert.Equal(t, 7*time.Millisecond, percentile([]time.Duration{7 * time.Millisecond}, 50))
+}
+
+func TestMeasureLatencyComputesFrameAndSectionPercentiles(t *testing.T) {
+	// Setup stage
+	logs := []log.Info{
+		{Request: log.HTTP{Route: "/api/a", Duration: 10 * time.Millisecond}},
+		{Request: log.HTTP{Route: "/api/b", Duration: 30 * time.Millisecond}},
+		{Request: log.HTTP{Route: "/static/c", Duration: time.Millisecond}},
+		{Request: log.HTTP{Route: "/static/d"}, Missing: log.DurationField},
+		{Request: log.HTTP{Duration: 50 * time.Millisecond}, Missing: log.RequestField},
+	}
+
+	task := MeasureLatency{}
+	if err := task.BeforeRun(); err != nil {
+		panic(err)
+	}
+
+	// Exercise stage
+	err := task.Run(logs)
+
+	// Validation stage
+	assert.Nil(t, err)
+	assert.True(t, task.IsDone())
+
+	res := task.Result()
+	assert.Equal(t, LatencyStats{
+		NbSamples: 4,
+		P50:       10 * time.Millisecond,
+		P90:       50 * time.Millisecond,
+		P99:       50 * time.Millisecond,
+		Max:       50 * time.Millisecond,
+	}, res.Frame)
+
+	assert.Len(t, res.Sections, 2)
+	assert.Equal(t, "/api", res.Sections[0].Section)
+	assert.Equal(t, uint64(2), res.Sections[0].NbSamples)
+	assert.Equal(t, 10*time.Millisecond, res.Sections[0].P50)
+	assert.Equal(t, 30*time.Millisecond, res.Sections[0].P99)
+	assert.Equal(t, "/static", res.Sections[1].Section)
+	assert.Equal(t, uint64(1), res.Sections[1].NbSamples)
+}
+
+func TestMeasureLatencyGroupsSectionsAtTheConfiguredDepth(t *testing.T) {
+	// Setup stage
+	logs := []log.Info{
+		{Request: log.HTTP{Route: "/api/v1/users", Duration: 10 * time.Millisecond}},
+		{Request: log.HTTP{Route: "/api/v2/users", Duration: 30 * time.Millisecond}},
+		{Request: log.HTTP{Route: "/api/v1/orders", Duration: 20 * time.Millisecond}},
+	}
+
+	task := MeasureLatency{}
+	if err := task.Init(2); err != nil {
+		panic(err)
+	}
+	if err := task.BeforeRun(); err != nil {
+		panic(err)
+	}
+
+	// Exercise stage
+	err := task.Run(logs)
+
+	// Validation stage
+	assert.Nil(t, err)
+	res := task.Result()
+	assert.Len(t, res.Sections, 2)
+	assert.Equal(t, "/api/v2", res.Sections[0].Section)
+	assert.Equal(t, "/api/v1", res.Sections[1].Section)
+	assert.Equal(t, uint64(2), res.Sections[1].NbSamples)
+	assert.NotNil(t, task.Init(1, 2))
+}
+
+func TestMeasureLatencyReturnsEmptyStatsWithoutDurations(t *testing.T) {
+	task := MeasureLatency{}
+
+	err := task.Run([]log.Info{{Missing: log.DurationField}})
+
+	assert.Nil(t, err)
+	assert.Equal(t, Latency{Frame: LatencyStats{}, Sections: []SectionLatency{}}, task.Result())
+}
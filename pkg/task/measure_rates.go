@@ -2,20 +2,78 @@ package task
 
 import (
 	"fmt"
+	"sort"
+	"time"
 
 	"github.com/Juli3nnicolas/http_log_monitor/pkg/log"
+	"github.com/Juli3nnicolas/http_log_monitor/pkg/timer"
 )
 
-// MeasureRates is a task measuring different rates and measures usefull for the whole app
+// MeasureRates is a task measuring different rates and measures usefull for the whole app.
+// By default (processing time), a frame's requests are the lines read during the frame.
+// In event-time mode, logs are bucketed into frames using their own timestamp
+// (log.Info.LocalTime) so that buffered writes or a lagging file don't skew the rates.
+// The request-rate is also measured over trailing windows (DefaultRateWindows) on
+// the logs' timestamps, with a one-second resolution.
 type MeasureRates struct {
 	done  bool
 	rates Rates
+	// eventTime is set when the event-time mode is on
+	eventTime *eventTimeFrames
+	// counter counts the requests per second to measure the windowed rates
+	counter *RateCounter
+}
+
+// eventTimeFrames buckets logs into frames aligned on their timestamp. A frame is
+// measured once the watermark (the current event time minus the allowed lateness)
+// has passed its end, logs older than the measured frames are late and dropped.
+type eventTimeFrames struct {
+	lateness  time.Duration
+	latest    time.Time             // latest timestamp read
+	latestAt  time.Time             // time latest has been read at
+	frames    map[int64]*eventFrame // open frames by start (unix seconds)
+	closedEnd int64                 // end of the last measured frame (unix seconds), 0 if none
+}
+
+// current returns the event time at now: the latest timestamp read moved forward by
+// the time elapsed since it was read, so that frames keep being measured when the
+// traffic stops. It is zero if no timestamp has been read yet.
+func (e *eventTimeFrames) current(now time.Time) time.Time {
+	if e.latest.IsZero() {
+		return e.latest
+	}
+	if idle := now.Sub(e.latestAt); idle > 0 {
+		return e.latest.Add(idle)
+	}
+	return e.latest
+}
+
+type eventFrame struct {
+	nbRequests uint64
+	nbSuccess  uint64
 }
 
 // Rates contains all type of rates and measures taken by the task
 type Rates struct {
-	Global GlobalRates
-	Frame  FrameRates
+	Global  GlobalRates
+	Frame   FrameRates
+	Windows []WindowRate // request-rates over DefaultRateWindows, the shortest window first
+}
+
+// WindowRate is the request-rate over a trailing window
+type WindowRate struct {
+	Window  time.Duration
+	ReqPerS float64
+}
+
+// Window returns the request-rate over window, 0 if it isn't measured
+func (o *Rates) Window(window time.Duration) float64 {
+	for _, w := range o.Windows {
+		if w.Window == window {
+			return w.ReqPerS
+		}
+	}
+	return 0
 }
 
 // GlobalRates global measures taking into account the whole log file
@@ -27,15 +85,54 @@ type GlobalRates struct {
 
 // FrameRates measures related to the current time-frame
 type FrameRates struct {
-	Duration   uint64 // Frame's duration expressed in seconds
-	ReqPerS    uint64 // Frame's request-rate (req/s)
-	NbRequests uint64 // Number of requests recorded during the frame's execution
-	NbSuccess  uint64 // Number of successful requests recorded during the frame's execution
-	NbFailures uint64 // Number of failed requests recorded during the frame's execution
+	Duration   uint64  // Frame's duration expressed in seconds (see Start in event-time mode)
+	ReqPerS    uint64  // Frame's request-rate (req/s), rounded down
+	Rate       float64 // Frame's request-rate with its fractional part (req/s)
+	NbRequests uint64  // Number of requests recorded during the frame's execution
+	NbSuccess  uint64  // Number of successful requests recorded during the frame's execution
+	NbFailures uint64  // Number of failed requests recorded during the frame's execution
+	// Start is the frame's beginning in event-time mode, zero otherwise. The frame
+	// spans all the frames measured by the update, it is empty (zero measures and
+	// Start) if none has been measured.
+	Start time.Time
+	// NbLate is the number of logs dropped in event-time mode because their frame
+	// had already been measured when they were read
+	NbLate uint64
 }
 
-// Init does nothing, implements the task interface
+// Init selects how the logs are bucketed into frames, it accepts optional parameters :
+// - eventTime bool : if true, logs are bucketed by their timestamp (processing time by default)
+// - lateness time.Duration : how late a log can be read in event-time mode (0 by default)
 func (o *MeasureRates) Init(args ...interface{}) error {
+	if len(args) > 2 {
+		return fmt.Errorf("wrong parameters - in order optional parameters must be (eventTime bool, lateness time.Duration)")
+	}
+
+	o.eventTime = nil
+	if len(args) == 0 {
+		return nil
+	}
+
+	eventTime, ok := args[0].(bool)
+	if !ok {
+		return fmt.Errorf("type error - got %T instead of bool", args[0])
+	}
+
+	var lateness time.Duration
+	if len(args) == 2 {
+		lateness, ok = args[1].(time.Duration)
+		if !ok {
+			return fmt.Errorf("type error - got %T instead of time.Duration", args[1])
+		}
+		if lateness < 0 {
+			return fmt.Errorf("wrong parameters - the allowed lateness must be positive, got %v", lateness)
+		}
+	}
+
+	if eventTime {
+		o.eventTime = &eventTimeFrames{lateness: lateness, frames: make(map[int64]*eventFrame)}
+	}
+
 	return nil
 }
 
@@ -46,12 +143,13 @@ func (o *MeasureRates) BeforeRun(...interface{}) error {
 	return nil
 }
 
-// Run computes the measures present in Rates. It takes two parameters:
+// Run computes the measures present in Rates. It takes two parameters and an optional one:
 // logs []log.Info : slice of logs to base the computing on
 // frame uint64 : time-frame duration in seconds
+// t timer.Timer : clock the windowed rates and the event time follow (the system's by default)
 func (o *MeasureRates) Run(args ...interface{}) error {
-	if len(args) != 2 {
-		return fmt.Errorf("wrong parameters - in order parameters must be (logs []log.Info, frameDurationInSeconds uint64)")
+	if len(args) != 2 && len(args) != 3 {
+		return fmt.Errorf("wrong parameters - in order parameters must be (logs []log.Info, frameDurationInSeconds uint64[, t timer.Timer])")
 	}
 
 	logs, ok := args[0].([]log.Info)
@@ -64,8 +162,30 @@ func (o *MeasureRates) Run(args ...interface{}) error {
 		return fmt.Errorf("type error - got %T instead of uint64", args[1])
 	}
 
-	o.computeFrameRates(logs, frame)
-	o.computeGlobalRates(logs)
+	if frame == 0 {
+		return fmt.Errorf("wrong parameters - the frame duration must be greater than 0")
+	}
+
+	var clock timer.Timer = &timer.Time{}
+	if len(args) == 3 {
+		clock, ok = args[2].(timer.Timer)
+		if !ok {
+			return fmt.Errorf("type error - got %T instead of timer.Timer", args[2])
+		}
+	}
+
+	if o.counter == nil {
+		o.counter = NewRateCounter(DefaultRateWindows[len(DefaultRateWindows)-1])
+	}
+
+	if o.eventTime != nil {
+		o.measureEventTimeFrames(logs, frame, clock.Now())
+	} else {
+		o.computeFrameRates(logs, frame)
+		o.computeGlobalRates(logs)
+		o.countRequests(logs, clock.Now())
+	}
+	o.computeWindowRates()
 
 	o.done = true
 	return nil
@@ -75,9 +195,11 @@ func (o *MeasureRates) computeFrameRates(logs []log.Info, frame uint64) {
 	losgLen := len(logs)
 	f := &o.rates.Frame
 
-	// All log lines always correspond to HTTP requests
+	// All log lines always correspond to HTTP requests (even those whose
+	// request line is unknown, the server still answered them)
 	f.NbRequests = uint64(losgLen)
 	f.ReqPerS = f.NbRequests / frame
+	f.Rate = float64(f.NbRequests) / float64(frame)
 	f.Duration = frame
 
 	var nbSuccess uint64
@@ -90,20 +212,150 @@ func (o *MeasureRates) computeFrameRates(logs []log.Info, frame uint64) {
 	f.NbFailures = f.NbRequests - nbSuccess
 }
 
+// countRequests counts the requests read in processing time at their timestamp,
+// the logs without one are counted at now. So are the logs older than the counter's
+// capacity (a lagging file, a replay) or in the future (clock skew, wrong time zone),
+// they would otherwise be dropped or move the counter ahead of now. The counter
+// follows the clock so that the rates drop when the traffic stops.
+func (o *MeasureRates) countRequests(logs []log.Info, now time.Time) {
+	oldest := now.Add(-o.counter.Capacity())
+	for i := range logs {
+		t := now
+		if logs[i].Has(log.TimeField) && !logs[i].LocalTime.IsZero() {
+			t = logs[i].LocalTime
+		}
+		if !t.After(oldest) || t.After(now) {
+			t = now
+		}
+		o.counter.Add(t, 1)
+	}
+	o.counter.Advance(now)
+}
+
+func (o *MeasureRates) computeWindowRates() {
+	o.rates.Windows = make([]WindowRate, 0, len(DefaultRateWindows))
+	for _, w := range DefaultRateWindows {
+		o.rates.Windows = append(o.rates.Windows, WindowRate{Window: w, ReqPerS: o.counter.Rate(w)})
+	}
+}
+
 func (o *MeasureRates) computeGlobalRates(logs []log.Info) {
-	f := &o.rates.Frame
+	o.addGlobalMeasures(o.rates.Frame.ReqPerS, 1)
+}
+
+// addGlobalMeasures takes n frames having a reqPerS request-rate into account
+func (o *MeasureRates) addGlobalMeasures(reqPerS uint64, n uint64) {
 	g := &o.rates.Global
+	if n == 0 {
+		return
+	}
 
-	if f.ReqPerS > g.MaxReqPerS {
-		g.MaxReqPerS = f.ReqPerS
+	if reqPerS > g.MaxReqPerS {
+		g.MaxReqPerS = reqPerS
 	}
 
-	if g.nbMeasures >= 1 {
-		g.AvgReqPerS = (g.AvgReqPerS*g.nbMeasures + f.ReqPerS) / (g.nbMeasures + 1)
-	} else {
-		g.AvgReqPerS = f.ReqPerS
+	g.AvgReqPerS = (g.AvgReqPerS*g.nbMeasures + reqPerS*n) / (g.nbMeasures + n)
+	g.nbMeasures += n
+}
+
+// measureEventTimeFrames buckets logs by timestamp and measures the frames the
+// watermark has passed at now. The task's frame adds them up, it is empty if no
+// frame has been measured.
+func (o *MeasureRates) measureEventTimeFrames(logs []log.Info, frame uint64, now time.Time) {
+	e := o.eventTime
+	var nbLate uint64
+
+	for i := range logs {
+		// Logs without a timestamp are read as if they were the latest ones
+		current := e.current(now)
+		t := current
+		if logs[i].Has(log.TimeField) {
+			t = logs[i].LocalTime
+		}
+		if t.IsZero() {
+			nbLate++
+			continue
+		}
+
+		start := t.Unix() - t.Unix()%int64(frame)
+		if start < e.closedEnd {
+			nbLate++
+			continue
+		}
+		if t.After(current) {
+			e.latest, e.latestAt = t, now
+		}
+
+		o.counter.Add(t, 1)
+
+		bucket, found := e.frames[start]
+		if !found {
+			bucket = &eventFrame{}
+			e.frames[start] = bucket
+		}
+		bucket.nbRequests++
+		if logs[i].Request.Code < 400 {
+			bucket.nbSuccess++
+		}
+	}
+
+	o.rates.Frame = FrameRates{Duration: frame, NbLate: nbLate}
+	current := e.current(now)
+	if current.IsZero() {
+		return
+	}
+	o.counter.Advance(current)
+
+	starts := make([]int64, 0, len(e.frames))
+	for start := range e.frames {
+		starts = append(starts, start)
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i] < starts[j] })
+
+	// The frames from the end of the last measured one (or the first frame) to the
+	// watermark are measured
+	from := e.closedEnd
+	if from == 0 && len(starts) > 0 {
+		from = starts[0]
+	}
+	watermark := current.Add(-e.lateness).Unix()
+	to := watermark - watermark%int64(frame)
+	if from == 0 || to <= from {
+		return
+	}
+
+	var nbRequests, nbSuccess uint64
+	end := from
+	for _, start := range starts {
+		if start >= to {
+			break
+		}
+
+		// Frames without any log have had a 0 req/s rate
+		o.addGlobalMeasures(0, uint64(start-end)/frame)
+
+		bucket := e.frames[start]
+		o.addGlobalMeasures(bucket.nbRequests/frame, 1)
+		nbRequests += bucket.nbRequests
+		nbSuccess += bucket.nbSuccess
+
+		delete(e.frames, start)
+		end = start + int64(frame)
+	}
+	o.addGlobalMeasures(0, uint64(to-end)/frame)
+	e.closedEnd = to
+
+	duration := uint64(to - from)
+	o.rates.Frame = FrameRates{
+		Duration:   duration,
+		ReqPerS:    nbRequests / duration,
+		Rate:       float64(nbRequests) / float64(duration),
+		NbRequests: nbRequests,
+		NbSuccess:  nbSuccess,
+		NbFailures: nbRequests - nbSuccess,
+		Start:      time.Unix(from, 0).In(e.latest.Location()),
+		NbLate:     nbLate,
 	}
-	g.nbMeasures++
 }
 
 // AfterRun does nothing, implements the Task interface
@@ -125,5 +377,8 @@ func (o *MeasureRates) IsDone() bool {
 func (o *MeasureRates) Close() error {
 	o.rates.Global = GlobalRates{}
 	o.rates.Frame = FrameRates{}
+	o.rates.Windows = nil
+	o.eventTime = nil
+	o.counter = nil
 	return nil
 }
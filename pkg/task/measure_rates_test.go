@@ -0,0 +1,203 @@
+package task
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Juli3nnicolas/http_log_monitor/pkg/log"
+	"github.com/Juli3nnicolas/http_log_monitor/pkg/timer"
+	"github.com/stretchr/testify/assert"
+)
+
+// logsAt returns a log per code, all of them at the same time
+func logsAt(t time.Time, codes ...uint32) []log.Info {
+	logs := make([]log.Info, 0, len(codes))
+	for _, code := range codes {
+		logs = append(logs, log.Info{LocalTime: t, Request: log.HTTP{Code: code}})
+	}
+	return logs
+}
+
+func TestMeasureRatesCountsTheLinesReadInProcessingTime(t *testing.T) {
+	rates := MeasureRates{}
+	assert.Nil(t, rates.Init())
+
+	assert.Nil(t, rates.Run(logsAt(time.Time{}, 200, 200, 500, 404), uint64(2)))
+
+	f := rates.Result().Frame
+	assert.Equal(t, FrameRates{Duration: 2, ReqPerS: 2, Rate: 2, NbRequests: 4, NbSuccess: 2, NbFailures: 2}, f)
+}
+
+func TestMeasureRatesBucketsLogsByTimestampInEventTime(t *testing.T) {
+	// Setup stage
+	start := time.Date(2020, 2, 9, 16, 27, 0, 0, time.UTC)
+	clock := &timer.TimeStub{NowStub: func() time.Time { return start }}
+	rates := MeasureRates{}
+	if err := rates.Init(true, 2*time.Second); err != nil {
+		panic(err)
+	}
+
+	// Exercise stage - the frame [0s, 10s[ can't be measured until a log
+	// written 2s (the lateness) after its end has been read
+	logs := append(logsAt(start.Add(time.Second), 200, 200, 500), logsAt(start.Add(11*time.Second), 200)...)
+	err := rates.Run(logs, uint64(10), clock)
+
+	// Validation stage
+	assert.Nil(t, err)
+	assert.Equal(t, FrameRates{Duration: 10}, rates.Result().Frame)
+
+	// Exercise stage - a late log still counts in its frame
+	logs = append(logsAt(start.Add(9*time.Second), 404), logsAt(start.Add(12*time.Second), 200)...)
+	err = rates.Run(logs, uint64(10), clock)
+
+	// Validation stage
+	assert.Nil(t, err)
+	assert.Equal(t, FrameRates{Duration: 10, Rate: 0.4, NbRequests: 4, NbSuccess: 2, NbFailures: 2, Start: start}, rates.Result().Frame)
+
+	// Exercise stage - logs of a measured frame are dropped, the frames measured
+	// at once are added up and empty frames count in the global average
+	var burst []log.Info
+	for i := 0; i < 30; i++ {
+		burst = append(burst, logsAt(start.Add(35*time.Second), 200)...)
+	}
+	logs = append(logsAt(start.Add(5*time.Second), 200), burst...)
+	logs = append(logs, logsAt(start.Add(42*time.Second), 200)...)
+	err = rates.Run(logs, uint64(10), clock)
+
+	// Validation stage
+	assert.Nil(t, err)
+	res := rates.Result()
+	assert.Equal(t, FrameRates{Duration: 30, ReqPerS: 1, Rate: 32.0 / 30, NbRequests: 32, NbSuccess: 32, Start: start.Add(10 * time.Second), NbLate: 1}, res.Frame)
+	assert.Equal(t, uint64(3), res.Global.MaxReqPerS)
+	assert.Equal(t, uint64(4), res.Global.nbMeasures) // [0s, 10s[, [10s, 20s[, [20s, 30s[ and [30s, 40s[
+	assert.Equal(t, uint64(0), res.Global.AvgReqPerS)
+}
+
+func TestMeasureRatesMeasuresEmptyFramesWhenTheTrafficStopsInEventTime(t *testing.T) {
+	// Setup stage
+	start := time.Date(2020, 2, 9, 16, 27, 0, 0, time.UTC)
+	now := start.Add(time.Hour) // the logs are read an hour after being written
+	clock := &timer.TimeStub{NowStub: func() time.Time { return now }}
+	rates := MeasureRates{}
+	if err := rates.Init(true, 2*time.Second); err != nil {
+		panic(err)
+	}
+
+	var logs []log.Info
+	for i := 0; i < 50; i++ {
+		logs = append(logs, logsAt(start.Add(5*time.Second), 200)...)
+	}
+	assert.Nil(t, rates.Run(logs, uint64(10), clock))
+	assert.Equal(t, FrameRates{Duration: 10}, rates.Result().Frame)
+
+	// Exercise stage - the event time follows the clock once the logs stop
+	now = now.Add(10 * time.Second)
+	err := rates.Run([]log.Info{}, uint64(10), clock)
+
+	// Validation stage
+	assert.Nil(t, err)
+	res := rates.Result()
+	assert.Equal(t, FrameRates{Duration: 10, ReqPerS: 5, Rate: 5, NbRequests: 50, NbSuccess: 50, Start: start}, res.Frame)
+
+	// Exercise stage - the frames without logs are measured at 0 req/s
+	now = now.Add(10 * time.Second)
+	err = rates.Run([]log.Info{}, uint64(10), clock)
+
+	// Validation stage
+	assert.Nil(t, err)
+	res = rates.Result()
+	assert.Equal(t, FrameRates{Duration: 10, Start: start.Add(10 * time.Second)}, res.Frame)
+	assert.Equal(t, uint64(2), res.Global.nbMeasures)
+	assert.Equal(t, 0.0, res.Window(10*time.Second))
+
+	// Exercise stage - no frame has ended since the last update
+	err = rates.Run([]log.Info{}, uint64(10), clock)
+
+	// Validation stage
+	assert.Nil(t, err)
+	assert.Equal(t, FrameRates{Duration: 10}, rates.Result().Frame)
+}
+
+func TestMeasureRatesMeasuresTrailingWindowsWithFractionalPrecision(t *testing.T) {
+	// Setup stage - 9 requests over the last 10s and a 1s spike, without any
+	// timestamp for the spike
+	now := time.Date(2020, 2, 9, 16, 27, 10, 0, time.UTC)
+	clock := &timer.TimeStub{NowStub: func() time.Time { return now }}
+
+	var logs []log.Info
+	for i := 1; i <= 9; i++ {
+		logs = append(logs, logsAt(now.Add(-time.Duration(i)*time.Second), 200)...)
+	}
+	for i := 0; i < 5; i++ {
+		logs = append(logs, log.Info{Request: log.HTTP{Code: 200}, Missing: log.TimeField})
+	}
+
+	rates := MeasureRates{}
+	if err := rates.Init(); err != nil {
+		panic(err)
+	}
+
+	// Exercise stage
+	err := rates.Run(logs, uint64(10), clock)
+
+	// Validation stage
+	assert.Nil(t, err)
+	res := rates.Result()
+	assert.Equal(t, 1.4, res.Frame.Rate)
+	assert.Equal(t, 5.0, res.Window(time.Second))
+	assert.Equal(t, 1.4, res.Window(10*time.Second))
+	assert.InDelta(t, 14.0/60, res.Window(time.Minute), 1e-9)
+	assert.Len(t, res.Windows, len(DefaultRateWindows))
+
+	// Exercise stage - the rates drop when the traffic stops
+	now = now.Add(30 * time.Second)
+	err = rates.Run([]log.Info{}, uint64(10), clock)
+
+	// Validation stage
+	assert.Nil(t, err)
+	res = rates.Result()
+	assert.Equal(t, 0.0, res.Window(10*time.Second))
+	assert.InDelta(t, 14.0/60, res.Window(time.Minute), 1e-9)
+	assert.NotNil(t, rates.Run([]log.Info{}, uint64(10), now))
+}
+
+func TestMeasureRatesCountsOutOfRangeTimestampsAtNow(t *testing.T) {
+	// Setup stage - logs written an hour ago (a lagging file) and an hour ahead (a wrong time zone)
+	now := time.Date(2020, 2, 9, 16, 27, 10, 0, time.UTC)
+	clock := &timer.TimeStub{NowStub: func() time.Time { return now }}
+
+	rates := MeasureRates{}
+	if err := rates.Init(); err != nil {
+		panic(err)
+	}
+
+	// Exercise stage
+	logs := append(logsAt(now.Add(-time.Hour), 200, 200, 200), logsAt(now.Add(time.Hour), 200, 200)...)
+	err := rates.Run(logs, uint64(10), clock)
+
+	// Validation stage
+	assert.Nil(t, err)
+	res := rates.Result()
+	assert.Equal(t, 5.0, res.Window(time.Second))
+	assert.Equal(t, 0.5, res.Window(10*time.Second))
+
+	// Exercise stage - the counter hasn't moved past now, the next logs are counted
+	now = now.Add(time.Second)
+	err = rates.Run(logsAt(now, 200, 200), uint64(10), clock)
+
+	// Validation stage
+	assert.Nil(t, err)
+	res = rates.Result()
+	assert.Equal(t, 2.0, res.Window(time.Second))
+	assert.Equal(t, 0.7, res.Window(10*time.Second))
+}
+
+func TestMeasureRatesInitChecksTheEventTimeParameters(t *testing.T) {
+	rates := MeasureRates{}
+
+	assert.NotNil(t, rates.Init("true"))
+	assert.NotNil(t, rates.Init(true, 5))
+	assert.NotNil(t, rates.Init(true, -time.Second))
+	assert.Nil(t, rates.Init(false, time.Second))
+	assert.NotNil(t, rates.Run([]log.Info{}, uint64(0)))
+}
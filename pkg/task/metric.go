@@ -0,0 +1,227 @@
+package task
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Metrics gathers the results of the tasks measuring a time-frame. It is what
+// alerts are evaluated against.
+type Metrics struct {
+	Rates       Rates
+	Latency     Latency
+	Bandwidth   Bandwidth
+	ParseErrors ParseErrors
+	Codes       map[uint32]uint64 // number of requests per HTTP code (see CountHTTPCodes)
+	Hits        []Hit             // hits per section (see FindMostHitSections)
+}
+
+// Metric is a value computed from a frame's Metrics that alerts can monitor
+type Metric struct {
+	Name  string                   // name used to select the metric (see ParseMetric)
+	Unit  string                   // unit the value is expressed in
+	Value func(m *Metrics) float64 // extracts the metric's value from a frame's measures
+}
+
+const (
+	// RequestRateMetricName is the name of the frame's request-rate metric
+	RequestRateMetricName string = "req_rate"
+	// RequestRate1sMetricName is the name of the request-rate over the last second
+	RequestRate1sMetricName string = "req_rate_1s"
+	// RequestRate10sMetricName is the name of the request-rate over the last 10 seconds
+	RequestRate10sMetricName string = "req_rate_10s"
+	// RequestRate1mMetricName is the name of the request-rate over the last minute
+	RequestRate1mMetricName string = "req_rate_1m"
+	// RequestRate5mMetricName is the name of the request-rate over the last 5 minutes
+	RequestRate5mMetricName string = "req_rate_5m"
+	// RequestRate15mMetricName is the name of the request-rate over the last 15 minutes
+	RequestRate15mMetricName string = "req_rate_15m"
+	// LatencyP50MetricName is the name of the frame's median latency metric
+	LatencyP50MetricName string = "latency_p50"
+	// LatencyP90MetricName is the name of the frame's 90th percentile latency metric
+	LatencyP90MetricName string = "latency_p90"
+	// LatencyP99MetricName is the name of the frame's 99th percentile latency metric
+	LatencyP99MetricName string = "latency_p99"
+	// LatencyMaxMetricName is the name of the frame's maximum latency metric
+	LatencyMaxMetricName string = "latency_max"
+	// BandwidthMetricName is the name of the frame's throughput metric
+	BandwidthMetricName string = "bandwidth"
+	// ErrorRateMetricName is the name of the frame's percentage of server errors (5xx codes)
+	ErrorRateMetricName string = "error_rate"
+	// StatusMetricPrefix prefixes the name of the metrics counting an HTTP code's
+	// requests during the frame (status:503)
+	StatusMetricPrefix string = "status:"
+	// SectionMetricPrefix prefixes the name of the metrics measuring a section's
+	// hit rate (section:/api)
+	SectionMetricPrefix string = "section:"
+	// ParseErrorRateMetricName is the name of the frame's percentage of unparseable lines
+	ParseErrorRateMetricName string = "parse_error_rate"
+)
+
+// metrics lists all metrics selectable by name
+var metrics = map[string]Metric{
+	RequestRateMetricName: {
+		Name:  RequestRateMetricName,
+		Unit:  "req/s",
+		Value: func(m *Metrics) float64 { return m.Rates.Frame.Rate },
+	},
+	RequestRate1sMetricName:  windowRateMetric(RequestRate1sMetricName, time.Second),
+	RequestRate10sMetricName: windowRateMetric(RequestRate10sMetricName, 10*time.Second),
+	RequestRate1mMetricName:  windowRateMetric(RequestRate1mMetricName, time.Minute),
+	RequestRate5mMetricName:  windowRateMetric(RequestRate5mMetricName, 5*time.Minute),
+	RequestRate15mMetricName: windowRateMetric(RequestRate15mMetricName, 15*time.Minute),
+	LatencyP50MetricName:     latencyMetric(LatencyP50MetricName, func(l *LatencyStats) time.Duration { return l.P50 }),
+	LatencyP90MetricName:     latencyMetric(LatencyP90MetricName, func(l *LatencyStats) time.Duration { return l.P90 }),
+	LatencyP99MetricName:     latencyMetric(LatencyP99MetricName, func(l *LatencyStats) time.Duration { return l.P99 }),
+	LatencyMaxMetricName:     latencyMetric(LatencyMaxMetricName, func(l *LatencyStats) time.Duration { return l.Max }),
+	BandwidthMetricName: {
+		Name:  BandwidthMetricName,
+		Unit:  "B/s",
+		Value: func(m *Metrics) float64 { return float64(m.Bandwidth.Frame.BytesPerS) },
+	},
+	ErrorRateMetricName: {
+		Name:  ErrorRateMetricName,
+		Unit:  "%",
+		Value: errorRate,
+	},
+	ParseErrorRateMetricName: {
+		Name:  ParseErrorRateMetricName,
+		Unit:  "%",
+		Value: func(m *Metrics) float64 { return m.ParseErrors.Rate() },
+	},
+}
+
+// NbRequests returns the frame's number of requests (the sum of Codes)
+func (m *Metrics) NbRequests() uint64 {
+	var total uint64
+	for _, count := range m.Codes {
+		total += count
+	}
+	return total
+}
+
+// SectionHits returns the number of hits of section, it can be a subsection if the
+// section depth makes it available (see FindMostHitSections)
+func (m *Metrics) SectionHits(section string) uint64 {
+	if hit := findHit(m.Hits, section); hit != nil {
+		return hit.Total
+	}
+	return 0
+}
+
+// windowRateMetric returns a metric expressing the request-rate over a trailing window (see MeasureRates)
+func windowRateMetric(name string, window time.Duration) Metric {
+	return Metric{
+		Name:  name,
+		Unit:  "req/s",
+		Value: func(m *Metrics) float64 { return m.Rates.Window(window) },
+	}
+}
+
+// errorRate returns the frame's percentage of requests answered with a 5xx code
+func errorRate(m *Metrics) float64 {
+	var total, errors uint64
+	for code, count := range m.Codes {
+		total += count
+		if code >= 500 && code < 600 {
+			errors += count
+		}
+	}
+
+	if total == 0 {
+		return 0
+	}
+	return float64(errors) / float64(total) * 100
+}
+
+// StatusMetric returns the metric counting the requests answered with code during the frame
+func StatusMetric(code uint32) Metric {
+	return Metric{
+		Name:  StatusMetricPrefix + strconv.FormatUint(uint64(code), 10),
+		Unit:  "req",
+		Value: func(m *Metrics) float64 { return float64(m.Codes[code]) },
+	}
+}
+
+// SectionMetric returns the metric measuring section's hit rate during the frame (req/s).
+// Subsections can be monitored if the section depth makes them available (see FindMostHitSections).
+func SectionMetric(section string) Metric {
+	return Metric{
+		Name: SectionMetricPrefix + section,
+		Unit: "req/s",
+		Value: func(m *Metrics) float64 {
+			hits := m.SectionHits(section)
+			if d := m.Rates.Frame.Duration; d > 0 {
+				return float64(hits) / float64(d)
+			}
+			return float64(hits)
+		},
+	}
+}
+
+// findHit looks for section's hit in hits and their subsections, nil if it isn't found
+func findHit(hits []Hit, section string) *Hit {
+	for i := range hits {
+		if hits[i].Section == section {
+			return &hits[i]
+		}
+		if strings.HasPrefix(section, hits[i].Section) {
+			if hit := findHit(hits[i].Subsections, section); hit != nil {
+				return hit
+			}
+		}
+	}
+	return nil
+}
+
+// latencyMetric returns a metric expressing a frame's latency percentile in milliseconds
+func latencyMetric(name string, get func(l *LatencyStats) time.Duration) Metric {
+	return Metric{
+		Name: name,
+		Unit: "ms",
+		Value: func(m *Metrics) float64 {
+			return float64(get(&m.Latency.Frame)) / float64(time.Millisecond)
+		},
+	}
+}
+
+// RequestRateMetric returns the frame's request-rate metric (req/s), the one
+// monitored by default by alerts
+func RequestRateMetric() Metric {
+	return metrics[RequestRateMetricName]
+}
+
+// ParseErrorRateMetric returns the frame's percentage of unparseable lines (%)
+func ParseErrorRateMetric() Metric {
+	return metrics[ParseErrorRateMetricName]
+}
+
+// ParseMetric returns the metric called name. Available metrics are req_rate,
+// req_rate_1s, req_rate_10s, req_rate_1m, req_rate_5m, req_rate_15m, latency_p50,
+// latency_p90, latency_p99, latency_max, bandwidth, error_rate, parse_error_rate,
+// status:<code> (status:503) and section:<section> (section:/api).
+func ParseMetric(name string) (Metric, error) {
+	switch {
+	case strings.HasPrefix(name, StatusMetricPrefix):
+		code, err := strconv.ParseUint(strings.TrimPrefix(name, StatusMetricPrefix), 10, 32)
+		if err != nil || code < 100 || code > 999 {
+			return Metric{}, fmt.Errorf("invalid metric %q - the status must be an HTTP code", name)
+		}
+		return StatusMetric(uint32(code)), nil
+
+	case strings.HasPrefix(name, SectionMetricPrefix):
+		section := strings.TrimPrefix(name, SectionMetricPrefix)
+		if !strings.HasPrefix(section, "/") {
+			return Metric{}, fmt.Errorf("invalid metric %q - the section must start with /", name)
+		}
+		return SectionMetric(section), nil
+	}
+
+	m, ok := metrics[name]
+	if !ok {
+		return Metric{}, fmt.Errorf("unknown metric %q", name)
+	}
+	return m, nil
+}
@@ -0,0 +1,59 @@
+package task
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsMeasureErrorsStatusesAndSections(t *testing.T) {
+	// Setup stage
+	m := Metrics{
+		Rates: Rates{Frame: FrameRates{Duration: 10}},
+		Codes: map[uint32]uint64{200: 70, 404: 10, 500: 15, 503: 5},
+		Hits: []Hit{
+			{Section: "/api", Total: 50, Subsections: []Hit{
+				{Section: "/api/users", Total: 40},
+				{Section: "/api/orders", Total: 10},
+			}},
+			{Section: "/static", Total: 20},
+		},
+	}
+
+	values := map[string]float64{
+		ErrorRateMetricName:  20,
+		"status:404":         10,
+		"status:418":         0,
+		"section:/api":       5,
+		"section:/api/users": 4,
+		"section:/static":    2,
+		"section:/blog":      0,
+	}
+
+	for name, expected := range values {
+		// Exercise stage
+		metric, err := ParseMetric(name)
+
+		// Validation stage
+		assert.Nil(t, err, name)
+		assert.Equal(t, name, metric.Name)
+		assert.Equal(t, expected, metric.Value(&m), name)
+	}
+
+	assert.Equal(t, float64(0), metrics[ErrorRateMetricName].Value(&Metrics{}))
+}
+
+func TestParseMetricRejectsInvalidParameters(t *testing.T) {
+	for _, name := range []string{"status:", "status:abc", "status:42", "section:api", "sections:/api"} {
+		_, err := ParseMetric(name)
+		assert.NotNil(t, err, name)
+	}
+
+	for op, expected := range map[string]Comparison{">": Above, "below": Below, "change": ChangeBy} {
+		c, err := ParseComparison(op)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, c)
+	}
+	_, err := ParseComparison(">=")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,154 @@
+package task
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/Juli3nnicolas/http_log_monitor/pkg/log"
+)
+
+// FindMostHitRoutes reads the logs and agregate them by templated routes (see
+// RouteNormalizer). /users/123 and /users/456 are both hits of /users/:id.
+type FindMostHitRoutes struct {
+	normalizer *RouteNormalizer
+	routeHits  []RouteHit
+	done       bool
+}
+
+// RouteHit is a structure representing the requests made to a templated route
+type RouteHit struct {
+	Route   string            // templated route, /users/:id for instance
+	Total   uint64            // number of route occurences
+	Errors  uint64            // number of requests answered with an error (4xx and 5xx codes)
+	Methods map[string]uint64 // a map of method count (i.e. POSTcount := Methods["POST"])
+}
+
+// set is used to properly fill a RouteHit. It requires a templated route and a request
+func (o *RouteHit) set(route string, req *log.HTTP) {
+	if o.Methods == nil {
+		o.Methods = make(map[string]uint64)
+	}
+	o.Route = route
+	o.Total++
+	o.Methods[req.Method]++
+	if req.Code >= 400 {
+		o.Errors++
+	}
+}
+
+// Init sets up the route normalizer, it accepts an optional parameter :
+// - patterns []string : user-configured route patterns such as /api/v1/orders/:id/items
+func (o *FindMostHitRoutes) Init(args ...interface{}) error {
+	if len(args) > 1 {
+		return fmt.Errorf("wrong parameters - the only optional parameter is (patterns []string)")
+	}
+
+	var patterns []string
+	if len(args) == 1 {
+		var ok bool
+		patterns, ok = args[0].([]string)
+		if !ok {
+			return fmt.Errorf("type error - got %T instead of %T", args[0], patterns)
+		}
+	}
+
+	normalizer, err := NewRouteNormalizer(patterns...)
+	if err != nil {
+		return err
+	}
+	o.normalizer = normalizer
+
+	return nil
+}
+
+// BeforeRun sets the task as not done. IsDone is going to return to false.
+func (o *FindMostHitRoutes) BeforeRun(...interface{}) error {
+	o.done = false
+
+	return nil
+}
+
+// Run parses a []log.Info and aggregates its content by templated routes.
+// IMPORTANT : this method expects a []log.Info input to function
+func (o *FindMostHitRoutes) Run(args ...interface{}) error {
+	if len(args) != 1 {
+		return fmt.Errorf("wrong parameters - only one parameter is supported, it must be a []log.Info")
+	}
+
+	logs, ok := args[0].([]log.Info)
+	if !ok {
+		return fmt.Errorf("type error - got %T instead of []log.Info", args[0])
+	}
+
+	if o.normalizer == nil {
+		o.normalizer = &RouteNormalizer{}
+	}
+
+	hitMap := make(map[string]int)
+	o.routeHits = make([]RouteHit, 0, 1)
+
+	for i := range logs {
+		// Lines without a request line (logged as "-") can't be attributed to a route
+		if !logs[i].Has(log.RequestField) {
+			continue
+		}
+
+		route := o.normalizer.Normalize(logs[i].Request.Route)
+		j, found := hitMap[route]
+		if !found {
+			o.routeHits = append(o.routeHits, RouteHit{})
+			j = len(o.routeHits) - 1
+			hitMap[route] = j
+		}
+		o.routeHits[j].set(route, &logs[i].Request)
+	}
+
+	sort.Sort(routeHits(o.routeHits))
+	o.done = true
+
+	return nil
+}
+
+type routeHits []RouteHit
+
+func (s routeHits) Len() int {
+	return len(s)
+}
+
+func (s routeHits) Less(i, j int) bool {
+	if s[i].Total != s[j].Total {
+		return s[i].Total > s[j].Total
+	}
+	return s[i].Route < s[j].Route
+}
+
+func (s routeHits) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+// Result returns the result of the work carried out by the task if the task is done. Returns nil otherwise.
+// Returns a slice of route-hits in decreasing-order on RouteHit.Total
+func (o *FindMostHitRoutes) Result() []RouteHit {
+	if o.IsDone() {
+		return o.routeHits
+	}
+
+	return nil
+}
+
+// AfterRun does nothing, must be implemented to implement to task interface
+func (o *FindMostHitRoutes) AfterRun() error {
+	return nil
+}
+
+// IsDone is true when the task has completed
+func (o *FindMostHitRoutes) IsDone() bool {
+	return o.done
+}
+
+// Close closes the task. Call Init to use it again.
+func (o *FindMostHitRoutes) Close() error {
+	o.done = false
+	o.normalizer = nil
+	return nil
+}
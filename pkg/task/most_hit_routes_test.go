@@ -0,0 +1,90 @@
+package task
+
+import (
+	"testing"
+
+	"github.com/Juli3nnicolas/http_log_monitor/pkg/log"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeCollapsesIDsUUIDsAndHashes(t *testing.T) {
+	normalizer, err := NewRouteNormalizer()
+	assert.Nil(t, err)
+
+	routes := map[string]string{
+		"/":                                "/",
+		"/users/123":                       "/users/:id",
+		"/users/456/":                      "/users/:id/",
+		"/users/123/orders/9?sort=asc#top": "/users/:id/orders/:id",
+		"/files/123e4567-E89B-12d3-a456-426614174000": "/files/:uuid",
+		"/blobs/9f86d081884c7d659a2feaa0c55ad015":     "/blobs/:hash",
+		"/blog/deadbeefcafebabe":                      "/blog/deadbeefcafebabe",
+		"/v2/about-us":                                "/v2/about-us",
+		"index.html":                                  "index.html",
+	}
+
+	for route, expected := range routes {
+		assert.Equal(t, expected, normalizer.Normalize(route), route)
+	}
+}
+
+func TestNormalizeReplacesRoutesMatchingPatterns(t *testing.T) {
+	normalizer, err := NewRouteNormalizer("/api/v1/orders/:id/items", "/static/*", "/users/:name")
+	assert.Nil(t, err)
+
+	routes := map[string]string{
+		"/api/v1/orders/A-12/items":  "/api/v1/orders/:id/items",
+		"/api/v1/orders/A-12/status": "/api/v1/orders/A-12/status",
+		"/static/css/main.css":       "/static/*",
+		"/static":                    "/static",
+		"/users/julien":              "/users/:name",
+		"/users/julien/42":           "/users/julien/:id",
+	}
+
+	for route, expected := range routes {
+		assert.Equal(t, expected, normalizer.Normalize(route), route)
+	}
+
+	_, err = NewRouteNormalizer("api/orders")
+	assert.NotNil(t, err)
+	_, err = NewRouteNormalizer("/api/*/orders")
+	assert.NotNil(t, err)
+}
+
+func TestRunAggregatesHitsByTemplatedRoutes(t *testing.T) {
+	// Setup stage
+	logs := []log.Info{
+		{Request: log.HTTP{Method: "GET", Route: "/users/123", Code: 200}},
+		{Request: log.HTTP{Method: "DELETE", Route: "/users/456", Code: 404}},
+		{Request: log.HTTP{Method: "GET", Route: "/users/789?fields=name", Code: 500}},
+		{Request: log.HTTP{Method: "GET", Route: "/orders/1/items", Code: 200}},
+		{Request: log.HTTP{Code: 400}, Missing: log.RequestField},
+	}
+
+	hits := FindMostHitRoutes{}
+	if err := hits.Init([]string{"/orders/:order/items"}); err != nil {
+		panic(err)
+	}
+	if err := hits.BeforeRun(); err != nil {
+		panic(err)
+	}
+
+	// Exercise stage
+	err := hits.Run(logs)
+
+	// Validation stage
+	assert.Nil(t, err)
+	res := hits.Result()
+	assert.Len(t, res, 2)
+	assert.Equal(t, RouteHit{Route: "/users/:id", Total: 3, Errors: 2, Methods: map[string]uint64{"GET": 2, "DELETE": 1}}, res[0])
+	assert.Equal(t, RouteHit{Route: "/orders/:order/items", Total: 1, Methods: map[string]uint64{"GET": 1}}, res[1])
+}
+
+func TestInitReturnsAnErrorOnInvalidRoutePatterns(t *testing.T) {
+	hits := FindMostHitRoutes{}
+
+	assert.NotNil(t, hits.Init("/users/:id"))
+	assert.NotNil(t, hits.Init([]string{"users"}))
+	assert.Nil(t, hits.Init())
+}
@@ -10,7 +10,11 @@ import (
 
 // FindMostHitSections reads the logs and agregate them by sections. A log occurence is called a hit.
 // This task returns information on hits (how many times they were found, how were they called...).
+// A section is made of the first directories of a route, their number is the
+// section depth (1 by default). Hits can be drilled down from sections to
+// subsections (one directory deeper) and then to routes.
 type FindMostHitSections struct {
+	depth       int
 	sectionHits []Hit
 	done        bool
 }
@@ -20,8 +24,15 @@ type Hit struct {
 	Section string            // URL section in /compute/create/..., compute is the section
 	Total   uint64            // number of section occurences
 	Methods map[string]uint64 // a map of method count (i.e. POSTcount := Methods["POST"])
+	// Subsections are the hits of the section's subsections, in decreasing-order
+	// on Total. The subsections of a subsection are the requested routes (their
+	// own Subsections are nil).
+	Subsections []Hit
 }
 
+// DefaultSectionDepth is the default number of directories making a section
+const DefaultSectionDepth int = 1
+
 // set is used to properly fill a Hit. It requires a url section and an HTTP method
 func (o *Hit) set(section, method string) {
 	if o.Methods == nil {
@@ -32,11 +43,48 @@ func (o *Hit) set(section, method string) {
 	o.Methods[method]++
 }
 
-// Init does nothing, implemented to comply with the task interface.
+// Init sets up the task, it accepts an optional parameter :
+// - depth int : number of directories making a section (DefaultSectionDepth if
+// not given), /api/v1/users is in section /api/v1 for a depth of 2
 func (o *FindMostHitSections) Init(args ...interface{}) error {
+	depth, err := initSectionDepth(args)
+	if err != nil {
+		return err
+	}
+	o.depth = depth
+
 	return nil
 }
 
+// initSectionDepth reads the optional section depth given to the Init method of
+// the tasks grouping logs by sections. It returns DefaultSectionDepth if not given.
+func initSectionDepth(args []interface{}) (int, error) {
+	if len(args) > 1 {
+		return 0, fmt.Errorf("wrong parameters - the only optional parameter is (depth int)")
+	}
+	if len(args) == 0 {
+		return DefaultSectionDepth, nil
+	}
+
+	depth, ok := args[0].(int)
+	if !ok {
+		return 0, fmt.Errorf("type error - got %T instead of int", args[0])
+	}
+	if depth < 1 {
+		return 0, fmt.Errorf("invalid section depth %d - it must be at least 1", depth)
+	}
+
+	return depth, nil
+}
+
+// sectionDepth returns depth, DefaultSectionDepth if the task hasn't been initialised
+func sectionDepth(depth int) int {
+	if depth == 0 {
+		return DefaultSectionDepth
+	}
+	return depth
+}
+
 // BeforeRun sets the task as not done. IsDone is going to return to false.
 func (o *FindMostHitSections) BeforeRun(...interface{}) error {
 	o.done = false
@@ -58,31 +106,81 @@ func (o *FindMostHitSections) Run(args ...interface{}) error {
 		return fmt.Errorf("type error - got %T instead of []log.Info", args[0])
 	}
 
-	hitMap := make(map[string]int)
-	o.sectionHits = make([]Hit, 0, 1)
+	depth := sectionDepth(o.depth)
 
+	root := hitNode{}
 	for i := range logs {
-		section := extractSection(logs[i].Request.Route)
-		if section != "" {
-			j, found := hitMap[section]
-
-			if found {
-				o.sectionHits[j].set(section, logs[i].Request.Method)
-			} else {
-				hit := Hit{}
-				hit.set(section, logs[i].Request.Method)
-				o.sectionHits = append(o.sectionHits, hit)
-				hitMap[section] = len(o.sectionHits) - 1
-			}
+		// Lines without a request line (logged as "-") can't be attributed to a section
+		if !logs[i].Has(log.RequestField) {
+			continue
 		}
+
+		route := logs[i].Request.Route
+		section := extractSectionAt(route, depth)
+		if section == "" {
+			continue
+		}
+
+		// Routes having no more than depth directories are direct children of their section
+		path := []string{section}
+		if subsection := extractSectionAt(route, depth+1); subsection != section {
+			path = append(path, subsection)
+		}
+		root.add(append(path, route), logs[i].Request.Method)
 	}
 
-	sort.Sort(sectionHits(o.sectionHits))
+	o.sectionHits = root.hits()
+	if o.sectionHits == nil {
+		o.sectionHits = make([]Hit, 0)
+	}
 	o.done = true
 
 	return nil
 }
 
+// hitNode counts the hits of a section and of its subsections
+type hitNode struct {
+	hit      Hit
+	children map[string]*hitNode
+}
+
+// add counts a hit in the child named path[0], then in its own children for path[1:]
+func (n *hitNode) add(path []string, method string) {
+	if len(path) == 0 {
+		return
+	}
+
+	if n.children == nil {
+		n.children = make(map[string]*hitNode)
+	}
+	child, found := n.children[path[0]]
+	if !found {
+		child = &hitNode{}
+		n.children[path[0]] = child
+	}
+
+	child.hit.set(path[0], method)
+	child.add(path[1:], method)
+}
+
+// hits returns the hits of the node's children in decreasing-order on Hit.Total,
+// nil if the node has no children
+func (n *hitNode) hits() []Hit {
+	if len(n.children) == 0 {
+		return nil
+	}
+
+	hits := make([]Hit, 0, len(n.children))
+	for _, child := range n.children {
+		hit := child.hit
+		hit.Subsections = child.hits()
+		hits = append(hits, hit)
+	}
+	sort.Sort(sectionHits(hits))
+
+	return hits
+}
+
 type sectionHits []Hit
 
 func (s sectionHits) Len() int {
@@ -90,7 +188,10 @@ func (s sectionHits) Len() int {
 }
 
 func (s sectionHits) Less(i, j int) bool {
-	return s[i].Total > s[j].Total
+	if s[i].Total != s[j].Total {
+		return s[i].Total > s[j].Total
+	}
+	return s[i].Section < s[j].Section
 }
 
 func (s sectionHits) Swap(i, j int) {
@@ -130,6 +231,14 @@ func (o *FindMostHitSections) Close() error {
 // Note -  I think it is useful to log "/" as redirections to proxies or
 // other services might occur (especially with rather complex web server configs).
 func extractSection(url string) string {
+	return extractSectionAt(url, 1)
+}
+
+// extractSectionAt returns the url-part made of the first depth directories
+// (/api/v1 for /api/v1/users and a depth of 2). Routes having less directories
+// are in their parent directory's section. It returns / for routes at the root
+// and nil in case no "/" is found.
+func extractSectionAt(url string, depth int) string {
 	parts := strings.Split(url, "/")
 	partsLen := len(parts)
 
@@ -141,5 +250,11 @@ func extractSection(url string) string {
 		return ""
 	}
 
-	return "/" + parts[1]
+	// The last part is the requested resource, the others are directories
+	dirs := parts[1 : partsLen-1]
+	if len(dirs) > depth {
+		dirs = dirs[:depth]
+	}
+
+	return "/" + strings.Join(dirs, "/")
 }
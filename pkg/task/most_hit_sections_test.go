@@ -3,6 +3,8 @@ package task
 import (
 	"testing"
 
+	"github.com/Juli3nnicolas/http_log_monitor/pkg/log"
+
 	"github.com/stretchr/testify/assert"
 )
 
@@ -35,3 +37,126 @@ func TestExtractSectionReturnsAnEmptyStringWhenGivenAnEmptyString(t *testing.T)
 	section := extractSection("index.html")
 	assert.Equal(t, "", section)
 }
+
+func TestRunAggregatesHitsBySectionAndIgnoresUnknownRequests(t *testing.T) {
+	// Setup stage
+	logs := []log.Info{
+		{Request: log.HTTP{Method: "GET", Route: "/api/users"}},
+		{Request: log.HTTP{Method: "POST", Route: "/api/users/12"}},
+		{Request: log.HTTP{Method: "GET", Route: "/index.html"}},
+		{Request: log.HTTP{Code: 400}, Missing: log.RequestField},
+	}
+
+	hits := FindMostHitSections{}
+	if err := hits.BeforeRun(); err != nil {
+		panic(err)
+	}
+
+	// Exercise stage
+	err := hits.Run(logs)
+
+	// Validation stage
+	assert.Nil(t, err)
+	res := hits.Result()
+	assert.Len(t, res, 2)
+	assert.Equal(t, "/api", res[0].Section)
+	assert.Equal(t, uint64(2), res[0].Total)
+	assert.Equal(t, map[string]uint64{"GET": 1, "POST": 1}, res[0].Methods)
+	assert.Equal(t, "/", res[1].Section)
+	assert.Equal(t, uint64(1), res[1].Total)
+}
+
+func TestExtractSectionAtKeepsTheFirstDirectories(t *testing.T) {
+	sections := map[string]string{
+		"/api/v1/users/12": "/api/v1",
+		"/api/v1/users":    "/api/v1",
+		"/api/v1/":         "/api/v1",
+		"/api/users":       "/api",
+		"/index.html":      "/",
+		"index.html":       "",
+	}
+
+	for url, expected := range sections {
+		assert.Equal(t, expected, extractSectionAt(url, 2), url)
+	}
+}
+
+func TestRunAggregatesHitsBySectionDepthAndDrillsDownToRoutes(t *testing.T) {
+	// Setup stage
+	logs := []log.Info{
+		{Request: log.HTTP{Method: "GET", Route: "/api/v1/users/12"}},
+		{Request: log.HTTP{Method: "GET", Route: "/api/v1/users/12", Query: "fields=name"}},
+		{Request: log.HTTP{Method: "POST", Route: "/api/v1/orders/3"}},
+		{Request: log.HTTP{Method: "GET", Route: "/api/v1/status"}},
+		{Request: log.HTTP{Method: "GET", Route: "/api/v2/status"}},
+	}
+
+	hits := FindMostHitSections{}
+	if err := hits.Init(2); err != nil {
+		panic(err)
+	}
+	if err := hits.BeforeRun(); err != nil {
+		panic(err)
+	}
+
+	// Exercise stage
+	err := hits.Run(logs)
+
+	// Validation stage
+	assert.Nil(t, err)
+	res := hits.Result()
+	assert.Len(t, res, 2)
+	assert.Equal(t, "/api/v1", res[0].Section)
+	assert.Equal(t, uint64(4), res[0].Total)
+	assert.Equal(t, map[string]uint64{"GET": 3, "POST": 1}, res[0].Methods)
+	assert.Equal(t, "/api/v2", res[1].Section)
+
+	subsections := res[0].Subsections
+	assert.Len(t, subsections, 3)
+	assert.Equal(t, "/api/v1/users", subsections[0].Section)
+	assert.Equal(t, uint64(2), subsections[0].Total)
+	assert.Equal(t, "/api/v1/orders", subsections[1].Section)
+	assert.Equal(t, "/api/v1/status", subsections[2].Section)
+	assert.Nil(t, subsections[2].Subsections)
+
+	routes := subsections[0].Subsections
+	assert.Equal(t, []Hit{{Section: "/api/v1/users/12", Total: 2, Methods: map[string]uint64{"GET": 2}}}, routes)
+}
+
+func TestRunMakesShallowRoutesDirectChildrenOfTheirSection(t *testing.T) {
+	// Setup stage
+	logs := []log.Info{
+		{Request: log.HTTP{Method: "GET", Route: "/index.html"}},
+		{Request: log.HTTP{Method: "GET", Route: "/api/status"}},
+		{Request: log.HTTP{Method: "GET", Route: "/api/v1/status"}},
+	}
+
+	hits := FindMostHitSections{}
+	if err := hits.Init(2); err != nil {
+		panic(err)
+	}
+	if err := hits.BeforeRun(); err != nil {
+		panic(err)
+	}
+
+	// Exercise stage
+	err := hits.Run(logs)
+
+	// Validation stage
+	assert.Nil(t, err)
+	get := map[string]uint64{"GET": 1}
+	assert.Equal(t, []Hit{
+		{Section: "/", Total: 1, Methods: get, Subsections: []Hit{{Section: "/index.html", Total: 1, Methods: get}}},
+		{Section: "/api", Total: 1, Methods: get, Subsections: []Hit{{Section: "/api/status", Total: 1, Methods: get}}},
+		{Section: "/api/v1", Total: 1, Methods: get, Subsections: []Hit{{Section: "/api/v1/status", Total: 1, Methods: get}}},
+	}, hits.Result())
+}
+
+func TestInitReturnsAnErrorOnInvalidSectionDepths(t *testing.T) {
+	hits := FindMostHitSections{}
+
+	assert.NotNil(t, hits.Init(0))
+	assert.NotNil(t, hits.Init("2"))
+	assert.Nil(t, hits.Init())
+	assert.Equal(t, DefaultSectionDepth, hits.depth)
+}
@@ -0,0 +1,88 @@
+package task
+
+import "time"
+
+// DefaultRateWindows are the trailing windows the request-rate is measured on,
+// load-average style
+var DefaultRateWindows = []time.Duration{time.Second, 10 * time.Second, time.Minute, 5 * time.Minute, 15 * time.Minute}
+
+// RateCounter counts events per second in a ring buffer to measure their rate
+// over trailing windows. Its clock is the latest second events have been added
+// at (or it has been advanced to), events older than its capacity are dropped.
+type RateCounter struct {
+	counts []uint64 // counts[s % len(counts)] is the number of events of second s
+	head   int64    // latest second (unix), 0 if nothing has been counted yet
+}
+
+// NewRateCounter creates a counter able to measure rates over windows up to capacity
+// (rounded up to the second)
+func NewRateCounter(capacity time.Duration) *RateCounter {
+	size := int64((capacity + time.Second - 1) / time.Second)
+	if size < 1 {
+		size = 1
+	}
+	return &RateCounter{counts: make([]uint64, size)}
+}
+
+// Add counts n events at t, the counter's clock is advanced to t if it is later
+func (o *RateCounter) Add(t time.Time, n uint64) {
+	s := t.Unix()
+	o.Advance(t)
+	if s <= o.head-int64(len(o.counts)) {
+		return
+	}
+	o.counts[o.index(s)] += n
+}
+
+// Capacity returns the longest window the rate can be measured on
+func (o *RateCounter) Capacity() time.Duration {
+	return time.Duration(len(o.counts)) * time.Second
+}
+
+// Advance moves the counter's clock forward to t, the seconds in-between have no event.
+// It does nothing if t is before the counter's clock.
+func (o *RateCounter) Advance(t time.Time) {
+	s := t.Unix()
+	if o.head == 0 {
+		o.head = s
+		return
+	}
+	if s <= o.head {
+		return
+	}
+
+	size := int64(len(o.counts))
+	if s-o.head >= size {
+		for i := range o.counts {
+			o.counts[i] = 0
+		}
+	} else {
+		for sec := o.head + 1; sec <= s; sec++ {
+			o.counts[o.index(sec)] = 0
+		}
+	}
+	o.head = s
+}
+
+// Rate returns the average number of events per second over the window ending
+// at the counter's clock (included). The window is capped to the counter's capacity.
+func (o *RateCounter) Rate(window time.Duration) float64 {
+	n := int64(window / time.Second)
+	if n < 1 {
+		n = 1
+	}
+	if size := int64(len(o.counts)); n > size {
+		n = size
+	}
+
+	var total uint64
+	for sec := o.head - n + 1; sec <= o.head; sec++ {
+		total += o.counts[o.index(sec)]
+	}
+	return float64(total) / float64(n)
+}
+
+func (o *RateCounter) index(s int64) int {
+	size := int64(len(o.counts))
+	return int(((s % size) + size) % size)
+}
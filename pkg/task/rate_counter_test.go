@@ -0,0 +1,47 @@
+package task
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateCounterMeasuresTrailingWindows(t *testing.T) {
+	// Setup stage
+	start := time.Date(2020, 2, 9, 16, 27, 0, 0, time.UTC)
+	counter := NewRateCounter(time.Minute)
+
+	// Exercise stage - 9 requests over 10s, then a 1s spike
+	for i := 0; i < 9; i++ {
+		counter.Add(start.Add(time.Duration(i)*time.Second), 1)
+	}
+	counter.Add(start.Add(10*time.Second), 20)
+
+	// Validation stage
+	assert.Equal(t, 20.0, counter.Rate(time.Second))
+	assert.Equal(t, 2.8, counter.Rate(10*time.Second))
+	assert.InDelta(t, 29.0/60, counter.Rate(time.Minute), 1e-9)
+	assert.InDelta(t, 29.0/60, counter.Rate(time.Hour), 1e-9) // capped to the capacity
+}
+
+func TestRateCounterDropsEventsOutsideItsCapacity(t *testing.T) {
+	start := time.Date(2020, 2, 9, 16, 27, 0, 0, time.UTC)
+	counter := NewRateCounter(10 * time.Second)
+	assert.Equal(t, 10*time.Second, counter.Capacity())
+
+	counter.Add(start, 10)
+	counter.Add(start.Add(5*time.Second), 10)
+	counter.Add(start.Add(-20*time.Second), 10) // too old
+	assert.Equal(t, 2.0, counter.Rate(10*time.Second))
+
+	counter.Advance(start.Add(12 * time.Second))
+	assert.Equal(t, 1.0, counter.Rate(10*time.Second))
+	assert.Equal(t, 0.0, counter.Rate(time.Second))
+
+	counter.Advance(start.Add(time.Hour))
+	assert.Equal(t, 0.0, counter.Rate(10*time.Second))
+	counter.Advance(start) // the clock never goes back
+	counter.Add(start.Add(time.Hour), 5)
+	assert.Equal(t, 5.0, counter.Rate(time.Second))
+}
@@ -0,0 +1,149 @@
+package task
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// IDPlaceholder replaces numeric IDs in templated routes
+	IDPlaceholder string = ":id"
+	// UUIDPlaceholder replaces UUIDs in templated routes
+	UUIDPlaceholder string = ":uuid"
+	// HashPlaceholder replaces hexadecimal hashes in templated routes
+	HashPlaceholder string = ":hash"
+	// minHashLength is the minimum length of a hexadecimal segment to be considered a hash
+	minHashLength int = 16
+)
+
+// RouteNormalizer turns routes into templates so that requests to the same
+// resource aggregate together (/users/123 and /users/456 become /users/:id).
+// Routes matching a user-configured pattern (/api/v1/orders/:id/items) are
+// replaced by the pattern. Otherwise numeric IDs, UUIDs and hexadecimal hashes
+// are replaced by placeholders. Query strings and fragments are removed.
+type RouteNormalizer struct {
+	patterns [][]string // segments of the user-configured patterns
+}
+
+// NewRouteNormalizer creates a normalizer using the given patterns. In a pattern,
+// segments starting with ':' match any segment and a trailing '*' segment matches
+// one or more segments (/static/*).
+func NewRouteNormalizer(patterns ...string) (*RouteNormalizer, error) {
+	o := &RouteNormalizer{patterns: make([][]string, 0, len(patterns))}
+
+	for _, p := range patterns {
+		if !strings.HasPrefix(p, "/") {
+			return nil, fmt.Errorf("invalid route pattern %q - it must start with /", p)
+		}
+
+		segments := strings.Split(p[1:], "/")
+		for i, s := range segments {
+			if s == "*" && i != len(segments)-1 {
+				return nil, fmt.Errorf("invalid route pattern %q - * must be the last segment", p)
+			}
+		}
+		o.patterns = append(o.patterns, segments)
+	}
+
+	return o, nil
+}
+
+// Normalize returns route's template
+func (o *RouteNormalizer) Normalize(route string) string {
+	if i := strings.IndexAny(route, "?#"); i >= 0 {
+		route = route[:i]
+	}
+	if !strings.HasPrefix(route, "/") {
+		return route
+	}
+
+	segments := strings.Split(route[1:], "/")
+	for _, p := range o.patterns {
+		if matchPattern(p, segments) {
+			return "/" + strings.Join(p, "/")
+		}
+	}
+
+	for i, s := range segments {
+		segments[i] = templateSegment(s)
+	}
+	return "/" + strings.Join(segments, "/")
+}
+
+// matchPattern returns true if segments match the pattern's segments
+func matchPattern(pattern, segments []string) bool {
+	for i, p := range pattern {
+		if p == "*" {
+			return i < len(segments)
+		}
+		if i >= len(segments) || (!strings.HasPrefix(p, ":") && p != segments[i]) {
+			return false
+		}
+	}
+
+	return len(pattern) == len(segments)
+}
+
+// templateSegment replaces a route segment by a placeholder if it is an ID
+func templateSegment(s string) string {
+	switch {
+	case s == "":
+		return s
+	case isDigits(s):
+		return IDPlaceholder
+	case isUUID(s):
+		return UUIDPlaceholder
+	case len(s) >= minHashLength && isHex(s) && !isLetters(s):
+		return HashPlaceholder
+	}
+	return s
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func isLetters(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if c := s[i] | 0x20; c < 'a' || c > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+func isHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !('0' <= c && c <= '9') && !('a' <= c|0x20 && c|0x20 <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// isUUID returns true if s is formatted as 123e4567-e89b-12d3-a456-426614174000
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+
+	for i := 0; i < len(s); i++ {
+		switch i {
+		case 8, 13, 18, 23:
+			if s[i] != '-' {
+				return false
+			}
+		default:
+			if !isHex(s[i : i+1]) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,162 @@
+package task
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/Juli3nnicolas/http_log_monitor/pkg/log"
+)
+
+// DefaultTopClients is the number of clients each ranking holds by default
+const DefaultTopClients int = 5
+
+// TopClients reads the logs and ranks the clients (log.Info.Host) by number of
+// requests, bytes served and number of errors (4xx and 5xx codes)
+type TopClients struct {
+	max      int
+	rankings ClientRankings
+	done     bool
+}
+
+// ClientHit is a structure representing the requests made by a client
+type ClientHit struct {
+	Host     string // client's IP address (or hostname)
+	Requests uint64 // number of requests
+	Bytes    uint64 // bytes served to the client
+	Errors   uint64 // number of requests answered with an error (4xx and 5xx codes)
+}
+
+// ClientRankings are a frame's busiest clients, in decreasing order
+type ClientRankings struct {
+	ByRequests []ClientHit
+	ByBytes    []ClientHit // clients that have been served no content are left out
+	ByErrors   []ClientHit // clients that have got no error are left out
+}
+
+// Init sets the rankings' length, it accepts an optional parameter :
+// - max int : maximum number of clients per ranking (DefaultTopClients if not provided)
+func (o *TopClients) Init(args ...interface{}) error {
+	if len(args) > 1 {
+		return fmt.Errorf("wrong parameters - the only optional parameter is (max int)")
+	}
+
+	o.max = DefaultTopClients
+	if len(args) == 1 {
+		max, ok := args[0].(int)
+		if !ok {
+			return fmt.Errorf("type error - got %T instead of int", args[0])
+		}
+		if max < 1 {
+			return fmt.Errorf("wrong parameters - max must be greater than 0, got %d", max)
+		}
+		o.max = max
+	}
+
+	return nil
+}
+
+// BeforeRun sets the task as not done. IsDone is going to return to false.
+func (o *TopClients) BeforeRun(...interface{}) error {
+	o.done = false
+
+	return nil
+}
+
+// Run parses a []log.Info and ranks its clients.
+// IMPORTANT : this method expects a []log.Info input to function
+func (o *TopClients) Run(args ...interface{}) error {
+	if len(args) != 1 {
+		return fmt.Errorf("wrong parameters - only one parameter is supported, it must be a []log.Info")
+	}
+
+	logs, ok := args[0].([]log.Info)
+	if !ok {
+		return fmt.Errorf("type error - got %T instead of []log.Info", args[0])
+	}
+
+	if o.max == 0 {
+		o.max = DefaultTopClients
+	}
+
+	clientMap := make(map[string]int)
+	clients := make([]ClientHit, 0, 1)
+
+	for i := range logs {
+		host := logs[i].Host
+		j, found := clientMap[host]
+		if !found {
+			clients = append(clients, ClientHit{Host: host})
+			j = len(clients) - 1
+			clientMap[host] = j
+		}
+
+		c := &clients[j]
+		c.Requests++
+		if logs[i].Has(log.SizeField) {
+			c.Bytes += logs[i].Request.Size
+		}
+		if logs[i].Request.Code >= 400 {
+			c.Errors++
+		}
+	}
+
+	o.rankings = ClientRankings{
+		ByRequests: o.rank(clients, func(c *ClientHit) uint64 { return c.Requests }),
+		ByBytes:    o.rank(clients, func(c *ClientHit) uint64 { return c.Bytes }),
+		ByErrors:   o.rank(clients, func(c *ClientHit) uint64 { return c.Errors }),
+	}
+	o.done = true
+
+	return nil
+}
+
+// rank returns the o.max clients having the highest non-zero value, ties are
+// sorted by host
+func (o *TopClients) rank(clients []ClientHit, value func(*ClientHit) uint64) []ClientHit {
+	ranking := make([]ClientHit, 0, len(clients))
+	for i := range clients {
+		if value(&clients[i]) > 0 {
+			ranking = append(ranking, clients[i])
+		}
+	}
+
+	sort.Slice(ranking, func(i, j int) bool {
+		vi, vj := value(&ranking[i]), value(&ranking[j])
+		if vi != vj {
+			return vi > vj
+		}
+		return ranking[i].Host < ranking[j].Host
+	})
+
+	if len(ranking) > o.max {
+		ranking = ranking[:o.max]
+	}
+	return ranking
+}
+
+// Result returns the result of the work carried out by the task if the task is done.
+// Returns empty rankings otherwise.
+func (o *TopClients) Result() ClientRankings {
+	if o.IsDone() {
+		return o.rankings
+	}
+
+	return ClientRankings{}
+}
+
+// AfterRun does nothing, must be implemented to implement to task interface
+func (o *TopClients) AfterRun() error {
+	return nil
+}
+
+// IsDone is true when the task has completed
+func (o *TopClients) IsDone() bool {
+	return o.done
+}
+
+// Close closes the task. Call Init to use it again.
+func (o *TopClients) Close() error {
+	o.done = false
+	o.rankings = ClientRankings{}
+	return nil
+}
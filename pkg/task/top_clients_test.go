@@ -0,0 +1,63 @@
+package task
+
+import (
+	"testing"
+
+	"github.com/Juli3nnicolas/http_log_monitor/pkg/log"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunRanksClientsByRequestsBytesAndErrors(t *testing.T) {
+	// Setup stage
+	logs := []log.Info{
+		{Host: "10.0.0.1", Request: log.HTTP{Code: 200, Size: 100}},
+		{Host: "10.0.0.1", Request: log.HTTP{Code: 404, Size: 10}},
+		{Host: "10.0.0.1", Request: log.HTTP{Code: 200}, Missing: log.SizeField},
+		{Host: "2001:db8::1", Request: log.HTTP{Code: 200, Size: 5000}},
+		{Host: "2001:db8::1", Request: log.HTTP{Code: 503, Size: 20}},
+		{Host: "192.168.1.7", Request: log.HTTP{Code: 500}},
+		{Host: "192.168.1.8", Request: log.HTTP{Code: 304}},
+	}
+
+	clients := TopClients{}
+	if err := clients.Init(3); err != nil {
+		panic(err)
+	}
+	if err := clients.BeforeRun(); err != nil {
+		panic(err)
+	}
+
+	// Exercise stage
+	err := clients.Run(logs)
+
+	// Validation stage
+	assert.Nil(t, err)
+	res := clients.Result()
+	assert.Equal(t, []ClientHit{
+		{Host: "10.0.0.1", Requests: 3, Bytes: 110, Errors: 1},
+		{Host: "2001:db8::1", Requests: 2, Bytes: 5020, Errors: 1},
+		{Host: "192.168.1.7", Requests: 1, Errors: 1},
+	}, res.ByRequests)
+	assert.Equal(t, []ClientHit{
+		{Host: "2001:db8::1", Requests: 2, Bytes: 5020, Errors: 1},
+		{Host: "10.0.0.1", Requests: 3, Bytes: 110, Errors: 1},
+	}, res.ByBytes)
+	assert.Equal(t, []ClientHit{
+		{Host: "10.0.0.1", Requests: 3, Bytes: 110, Errors: 1},
+		{Host: "192.168.1.7", Requests: 1, Errors: 1},
+		{Host: "2001:db8::1", Requests: 2, Bytes: 5020, Errors: 1},
+	}, res.ByErrors)
+}
+
+func TestResultIsEmptyUntilTheTopClientsTaskIsDone(t *testing.T) {
+	clients := TopClients{}
+	assert.Nil(t, clients.Init())
+	assert.NotNil(t, clients.Init(0))
+	assert.NotNil(t, clients.Init("3"))
+
+	assert.Nil(t, clients.Run([]log.Info{{Host: "10.0.0.1"}}))
+	assert.Len(t, clients.Result().ByRequests, 1)
+	assert.Nil(t, clients.BeforeRun())
+	assert.Equal(t, ClientRankings{}, clients.Result())
+}